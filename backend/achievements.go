@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+)
+
+// Achievements are evaluated once a game has been persisted, from the final roster
+// and the player's updated lifetime stats. Every achievement unlocks once per player.
+
+// Achievement is a badge a player can unlock
+type Achievement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// UserAchievement is an achievement as listed for a player
+type UserAchievement struct {
+	Achievement
+	Unlocked   bool   `json:"unlocked"`
+	UnlockedAt int64  `json:"unlockedAt,omitempty"` // Unix seconds
+	GameID     string `json:"gameId,omitempty"`     // Game that unlocked it
+}
+
+// finishedGame is what achievements are decided by, from the view of one player
+type finishedGame struct {
+	Result     PlayerResult
+	Won        bool
+	Lead       int                   // Final points ahead of the best opponent
+	MaxDeficit int                   // Most points the player trailed by during the game
+	Stats      *db.CookiePlayerStats // Lifetime stats including this game; nil if unavailable
+}
+
+const comebackDeficit = 25 // Points a winner must have trailed by for a comeback
+
+// achievements lists every achievement in display order
+var achievements = []struct {
+	Achievement
+	unlocked func(g finishedGame) bool
+}{
+	{Achievement{"first_win", "First Bite", "Win your first game"}, func(g finishedGame) bool {
+		return g.Won
+	}},
+	{Achievement{"hundred_clicks", "Clicker", "Click 100 times in a single game"}, func(g finishedGame) bool {
+		return g.Result.Clicks >= 100
+	}},
+	{Achievement{"photo_finish", "Photo Finish", "Win a game by a single point"}, func(g finishedGame) bool {
+		return g.Won && g.Lead == 1
+	}},
+	{Achievement{"comeback", "Comeback Kid", "Win after trailing by 25 points or more"}, func(g finishedGame) bool {
+		return g.Won && g.MaxDeficit >= comebackDeficit
+	}},
+	{Achievement{"golden_touch", "Golden Touch", "Claim 3 golden cookies in a single game"}, func(g finishedGame) bool {
+		return g.Result.GoldenCookies >= 3
+	}},
+	{Achievement{"unstoppable", "Unstoppable", "Win 10 games in a row"}, func(g finishedGame) bool {
+		return g.Stats != nil && g.Stats.CurrentStreak >= 10
+	}},
+}
+
+// awardAchievements evaluates a finished game and stores the achievements its
+// players unlocked. Returns the newly unlocked achievements per user ID.
+func awardAchievements(gameID string, r *Roster, winnerID string) map[string][]Achievement {
+	now := time.Now().Unix()
+	unlocked := make(map[string][]Achievement)
+
+	for _, res := range r.Results() {
+		stats, err := db.GetPlayerStatsWithMock(res.UserID)
+		if err != nil {
+			log.Printf("Failed to load stats of %s for achievements: %v", res.UserID, err)
+		}
+		game := finishedGame{
+			Result:     res,
+			Won:        winnerID != "draw" && res.Placement == 1,
+			Lead:       r.Lead(res.UserID),
+			MaxDeficit: r.MaxDeficit[res.UserID],
+			Stats:      stats,
+		}
+
+		for _, a := range achievements {
+			if !a.unlocked(game) {
+				continue
+			}
+			created, err := db.UnlockAchievementWithMock(db.CookieAchievement{
+				UserID: res.UserID, AchievementID: a.ID, GameID: gameID, UnlockedAt: now,
+			})
+			if err != nil {
+				log.Printf("Failed to unlock achievement %s for %s: %v", a.ID, res.UserID, err)
+				continue
+			}
+			if created {
+				unlocked[res.UserID] = append(unlocked[res.UserID], a.Achievement)
+			}
+		}
+	}
+	return unlocked
+}
+
+// achievementsMessage announces the achievements a player just unlocked
+func achievementsMessage(unlocked []Achievement) GameMessage {
+	return GameMessage{
+		Type:    MsgTypeAchievementsUnlocked,
+		Payload: map[string]interface{}{"achievements": unlocked},
+	}
+}
+
+// handleUserAchievements lists all achievements and which of them a player has unlocked
+func handleUserAchievements(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := r.PathValue("userId")
+	stored, err := db.GetAchievementsWithMock(userID)
+	if err != nil {
+		log.Printf("[API] Error fetching achievements of %s: %v", userID, err)
+		http.Error(w, "Failed to fetch achievements", http.StatusInternalServerError)
+		return
+	}
+	byID := make(map[string]db.CookieAchievement, len(stored))
+	for _, a := range stored {
+		byID[a.AchievementID] = a
+	}
+
+	list := make([]UserAchievement, len(achievements))
+	for i, a := range achievements {
+		list[i] = UserAchievement{Achievement: a.Achievement}
+		if got, ok := byID[a.ID]; ok {
+			list[i].Unlocked = true
+			list[i].UnlockedAt = got.UnlockedAt
+			list[i].GameID = got.GameID
+		}
+	}
+	json.NewEncoder(w).Encode(list)
+}
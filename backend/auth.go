@@ -242,6 +242,8 @@ func handleGoogleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := db.SaveUserWithMock(user); err != nil {
 		log.Printf("[AUTH] WARNING: Failed to save user to DB: %v", err)
+	} else {
+		syncLeaderboardUser(user.UserID)
 	}
 
 	// Redirect to frontend with JWT token
@@ -326,6 +328,44 @@ func verifyJWT(tokenString string) (*JWTClaims, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
+// authenticatedUser returns the claims of the JWT sent in the Authorization header
+func authenticatedUser(r *http.Request) (*JWTClaims, error) {
+	tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if tokenString == "" {
+		return nil, fmt.Errorf("no token provided")
+	}
+	return verifyJWT(tokenString)
+}
+
+type claimsContextKey struct{}
+
+// requireAuth rejects requests without a valid JWT in the Authorization header. The
+// wrapped handler finds the caller's claims via requestClaims.
+func requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// CORS preflight requests carry no token
+		if r.Method == "OPTIONS" {
+			next(w, r)
+			return
+		}
+
+		claims, err := authenticatedUser(r)
+		if err != nil {
+			log.Printf("[AUTH] Rejected request to %s from IP: %s - %v", r.URL.Path, r.RemoteAddr, err)
+			enableCors(&w)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey{}, claims)))
+	}
+}
+
+// requestClaims returns the claims of a request that passed requireAuth
+func requestClaims(r *http.Request) *JWTClaims {
+	claims, _ := r.Context().Value(claimsContextKey{}).(*JWTClaims)
+	return claims
+}
+
 func handleVerifySession(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[AUTH] Session verification request from IP: %s", r.RemoteAddr)
 	w.Header().Set("Content-Type", "application/json")
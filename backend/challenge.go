@@ -0,0 +1,262 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+)
+
+// Challenge is a direct duel request from one player to another. It lives in Redis
+// until the target answers or it times out, so both players may be on any pod.
+type Challenge struct {
+	ID         string     `json:"id"`
+	Challenger QueueEntry `json:"challenger"`
+	TargetID   string     `json:"targetId"`
+	Rules      GameRules  `json:"rules"`
+	BestOf     int        `json:"bestOf,omitempty"` // Rounds of a series, 0 for a single game
+	CreatedAt  int64      `json:"createdAt"`
+}
+
+const (
+	challengeKeyPrefix = "overcookied:challenge:"
+	challengeTimeout   = 30 * time.Second
+	challengeTTL       = challengeTimeout + 10*time.Second // Outlives the timeout so the expiry can still be announced
+)
+
+var errChallengeNotFound = errors.New("challenge not found")
+
+// CreateChallenge stores a new challenge of challenger against targetID, for a
+// best-of-N series if bestOf is above 1
+func CreateChallenge(challenger QueueEntry, targetID string, rules GameRules, bestOf int) (*Challenge, error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	challenge := Challenge{
+		ID:         hex.EncodeToString(idBytes),
+		Challenger: challenger,
+		TargetID:   targetID,
+		Rules:      rules,
+		BestOf:     bestOf,
+		CreatedAt:  time.Now().Unix(),
+	}
+
+	challengeJSON, err := json.Marshal(challenge)
+	if err != nil {
+		return nil, err
+	}
+	if err := kvSet(challengeKeyPrefix+challenge.ID, string(challengeJSON), challengeTTL); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Challenge %s: %s challenged %s", challenge.ID, challenger.UserID, targetID)
+	return &challenge, nil
+}
+
+// TakeChallenge atomically removes a pending challenge and returns it, so that
+// accepting, declining and expiring can only happen once. Only the challenged
+// player may take it, unless targetID is empty.
+func TakeChallenge(challengeID, targetID string) (*Challenge, error) {
+	var challenge Challenge
+	err := kvUpdate(challengeKeyPrefix+challengeID, challengeTTL, func(current string, found bool) (string, error) {
+		if !found {
+			return "", errChallengeNotFound
+		}
+		if err := json.Unmarshal([]byte(current), &challenge); err != nil {
+			return "", err
+		}
+		if targetID != "" && challenge.TargetID != targetID {
+			return "", errChallengeNotFound
+		}
+		return "", nil // Delete
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// handleChallenge sends a duel challenge to another online player. The challenger
+// may pick a rules preset, the matchmaking preset is used otherwise, and ask for a
+// best-of-N series.
+func (gm *GameManager) handleChallenge(client *Client, targetID, preset string, bestOf int) {
+	if !IsRedisAvailable() {
+		sendError(client, "unavailable", "Challenges are not available right now")
+		return
+	}
+	if targetID == "" || targetID == client.userID {
+		sendError(client, "invalid_request", "userId must be another player")
+		return
+	}
+
+	rules := matchmakingRules()
+	if preset != "" {
+		var ok bool
+		if rules, ok = rulesForPreset(preset); !ok {
+			sendError(client, "invalid_preset", "Unknown game rules preset")
+			return
+		}
+	}
+	if !validBestOf(bestOf) {
+		sendError(client, "invalid_best_of", "A series is best of 3 or 5")
+		return
+	}
+
+	presence := gm.presenceOf([]string{client.userID, targetID})
+	if presence[client.userID] != PresenceOnline {
+		sendError(client, "already_busy", "Leave the queue or finish your game first")
+		return
+	}
+	switch presence[targetID] {
+	case PresenceOnline:
+	case PresenceOffline:
+		sendError(client, "player_offline", "This player is not online")
+		return
+	default:
+		sendError(client, "player_busy", "This player is already queued or in a game")
+		return
+	}
+
+	challenger := queueEntryFor(client)
+	challenge, err := CreateChallenge(challenger, targetID, rules, bestOf)
+	if err != nil {
+		log.Printf("Failed to create challenge for %s: %v", client.userID, err)
+		sendError(client, "challenge_error", "Could not send the challenge")
+		return
+	}
+
+	gm.sendToUser(targetID, GameMessage{
+		Type: MsgTypeChallengeReceived,
+		Payload: map[string]interface{}{
+			"challengeId": challenge.ID,
+			"from":        challenger.gamePlayer(),
+			"rules":       challenge.Rules,
+			"bestOf":      challenge.BestOf,
+			"expiresIn":   challengeTimeout.Seconds(),
+		},
+	})
+	sendToClient(client, GameMessage{
+		Type: MsgTypeChallengeSent,
+		Payload: map[string]interface{}{
+			"challengeId": challenge.ID,
+			"userId":      targetID,
+			"expiresIn":   challengeTimeout.Seconds(),
+		},
+	})
+
+	// The challenger's pod announces the timeout to both players
+	time.AfterFunc(challengeTimeout, func() { gm.expireChallenge(challenge.ID) })
+}
+
+// expireChallenge withdraws a challenge nobody answered in time
+func (gm *GameManager) expireChallenge(challengeID string) {
+	challenge, err := TakeChallenge(challengeID, "")
+	if err != nil {
+		if !errors.Is(err, errChallengeNotFound) {
+			log.Printf("Failed to expire challenge %s: %v", challengeID, err)
+		}
+		return
+	}
+
+	log.Printf("Challenge %s expired", challengeID)
+	msg := GameMessage{Type: MsgTypeChallengeExpired, Payload: map[string]string{"challengeId": challengeID}}
+	gm.sendToUser(challenge.Challenger.UserID, msg)
+	gm.sendToUser(challenge.TargetID, msg)
+}
+
+// handleChallengeAccept accepts a challenge and starts the duel through the regular
+// distributed match flow, bypassing the matchmaking queue
+func (gm *GameManager) handleChallengeAccept(client *Client, challengeID string) {
+	if !IsRedisAvailable() {
+		sendError(client, "unavailable", "Challenges are not available right now")
+		return
+	}
+	if challengeID == "" {
+		sendError(client, "invalid_request", "challengeId is required")
+		return
+	}
+
+	if gm.inActiveGame(client) {
+		sendError(client, "already_in_game", "You are already in a game")
+		return
+	}
+
+	challenge, err := TakeChallenge(challengeID, client.userID)
+	if errors.Is(err, errChallengeNotFound) {
+		sendError(client, "challenge_not_found", "This challenge has expired")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to accept challenge %s: %v", challengeID, err)
+		sendError(client, "challenge_error", "Could not accept the challenge")
+		return
+	}
+
+	challengerID := challenge.Challenger.UserID
+	if gm.presenceOf([]string{challengerID})[challengerID] != PresenceOnline {
+		sendError(client, "player_busy", "The challenger is not available anymore")
+		return
+	}
+
+	// Neither player may be matched into a second game. The challenger stops
+	// spectating on their pod once the match notification arrives.
+	gm.stopSpectating(client)
+	if !gm.leaveOwnLobby(client) {
+		return
+	}
+	RemoveFromQueue(client.userID)
+	RemoveFromQueue(challengerID)
+	if _, err := CancelLobby(challengerID); errors.Is(err, errLobbyFull) {
+		sendError(client, "player_busy", "The challenger is not available anymore")
+		return
+	}
+
+	entries := []QueueEntry{challenge.Challenger, queueEntryFor(client)}
+	if challenge.BestOf > 1 {
+		if err := gm.startSeries(entries, challenge.Rules, challenge.BestOf); err != nil {
+			log.Printf("Failed to start series for challenge %s: %v", challengeID, err)
+			sendError(client, "challenge_error", "Could not start the series")
+		}
+		return
+	}
+
+	userIDs := []string{challengerID, client.userID}
+	roomID := newRoomID(ModeDuel, userIDs)
+	if err := CreateDistributedGame(roomID, ModeDuel, entries, challenge.Rules); err != nil {
+		log.Printf("Failed to create distributed game for challenge %s: %v", challengeID, err)
+		sendError(client, "challenge_error", "Could not start the game")
+		return
+	}
+
+	log.Printf("Challenge %s accepted, starting room %s", challengeID, roomID)
+	if err := PublishMatchNotification(newMatchNotification(roomID, ModeDuel, userIDs)); err != nil {
+		log.Printf("Failed to publish match notification for challenge %s: %v", challengeID, err)
+	}
+}
+
+// handleChallengeDecline turns down a challenge and tells the challenger
+func (gm *GameManager) handleChallengeDecline(client *Client, challengeID string) {
+	if !IsRedisAvailable() {
+		sendError(client, "unavailable", "Challenges are not available right now")
+		return
+	}
+
+	challenge, err := TakeChallenge(challengeID, client.userID)
+	if errors.Is(err, errChallengeNotFound) {
+		sendError(client, "challenge_not_found", "This challenge has expired")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to decline challenge %s: %v", challengeID, err)
+		sendError(client, "challenge_error", "Could not decline the challenge")
+		return
+	}
+
+	gm.sendToUser(challenge.Challenger.UserID, GameMessage{
+		Type:    MsgTypeChallengeDeclined,
+		Payload: map[string]string{"challengeId": challengeID, "userId": client.userID},
+	})
+}
@@ -33,6 +33,15 @@ func main() {
 
 	recreateTableUsers(svc)
 	recreateTableGames(svc)
+	recreateTableReplays(svc)
+	recreateTableSeasons(svc)
+	recreateTableSeasonStats(svc)
+	recreateTablePlayerStats(svc)
+	recreateTableHeadToHead(svc)
+	recreateTableAchievements(svc)
+	recreateTableFriends(svc)
+	recreateTableSeries(svc)
+	recreateTableTournaments(svc)
 	log.Println("Database setup complete!")
 }
 
@@ -147,3 +156,296 @@ func recreateTableGames(svc *dynamodb.Client) {
 		log.Printf("Table %s created successfully", tableName)
 	}
 }
+
+func recreateTableReplays(svc *dynamodb.Client) {
+	tableName := "CookieReplays"
+	deleteTableIfExists(svc, tableName)
+	log.Printf("Creating table %s...", tableName)
+
+	_, err := svc.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("GameID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String("Chunk"),
+				AttributeType: types.ScalarAttributeTypeN,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("GameID"),
+				KeyType:       types.KeyTypeHash,
+			},
+			{
+				AttributeName: aws.String("Chunk"),
+				KeyType:       types.KeyTypeRange,
+			},
+		},
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+	})
+
+	if err != nil {
+		log.Printf("Could not create table %s: %v", tableName, err)
+	} else {
+		log.Printf("Table %s created successfully", tableName)
+	}
+}
+
+func recreateTableSeasons(svc *dynamodb.Client) {
+	tableName := "CookieSeasons"
+	deleteTableIfExists(svc, tableName)
+	log.Printf("Creating table %s...", tableName)
+
+	_, err := svc.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("SeasonID"),
+				AttributeType: types.ScalarAttributeTypeN,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("SeasonID"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+	})
+
+	if err != nil {
+		log.Printf("Could not create table %s: %v", tableName, err)
+	} else {
+		log.Printf("Table %s created successfully", tableName)
+	}
+}
+
+func recreateTableSeasonStats(svc *dynamodb.Client) {
+	tableName := "CookieSeasonStats"
+	deleteTableIfExists(svc, tableName)
+	log.Printf("Creating table %s...", tableName)
+
+	_, err := svc.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("SeasonID"),
+				AttributeType: types.ScalarAttributeTypeN,
+			},
+			{
+				AttributeName: aws.String("UserID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("SeasonID"),
+				KeyType:       types.KeyTypeHash,
+			},
+			{
+				AttributeName: aws.String("UserID"),
+				KeyType:       types.KeyTypeRange,
+			},
+		},
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+	})
+
+	if err != nil {
+		log.Printf("Could not create table %s: %v", tableName, err)
+	} else {
+		log.Printf("Table %s created successfully", tableName)
+	}
+}
+
+func recreateTablePlayerStats(svc *dynamodb.Client) {
+	tableName := "CookiePlayerStats"
+	deleteTableIfExists(svc, tableName)
+	log.Printf("Creating table %s...", tableName)
+
+	_, err := svc.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("UserID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("UserID"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+	})
+
+	if err != nil {
+		log.Printf("Could not create table %s: %v", tableName, err)
+	} else {
+		log.Printf("Table %s created successfully", tableName)
+	}
+}
+
+func recreateTableHeadToHead(svc *dynamodb.Client) {
+	tableName := "CookieHeadToHead"
+	deleteTableIfExists(svc, tableName)
+	log.Printf("Creating table %s...", tableName)
+
+	_, err := svc.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("PairID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("PairID"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+	})
+
+	if err != nil {
+		log.Printf("Could not create table %s: %v", tableName, err)
+	} else {
+		log.Printf("Table %s created successfully", tableName)
+	}
+}
+
+func recreateTableAchievements(svc *dynamodb.Client) {
+	tableName := "CookieAchievements"
+	deleteTableIfExists(svc, tableName)
+	log.Printf("Creating table %s...", tableName)
+
+	_, err := svc.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("UserID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String("AchievementID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("UserID"),
+				KeyType:       types.KeyTypeHash,
+			},
+			{
+				AttributeName: aws.String("AchievementID"),
+				KeyType:       types.KeyTypeRange,
+			},
+		},
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+	})
+
+	if err != nil {
+		log.Printf("Could not create table %s: %v", tableName, err)
+	} else {
+		log.Printf("Table %s created successfully", tableName)
+	}
+}
+
+func recreateTableFriends(svc *dynamodb.Client) {
+	tableName := "CookieFriends"
+	deleteTableIfExists(svc, tableName)
+	log.Printf("Creating table %s...", tableName)
+
+	_, err := svc.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("UserID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+			{
+				AttributeName: aws.String("FriendID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("UserID"),
+				KeyType:       types.KeyTypeHash,
+			},
+			{
+				AttributeName: aws.String("FriendID"),
+				KeyType:       types.KeyTypeRange,
+			},
+		},
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+	})
+
+	if err != nil {
+		log.Printf("Could not create table %s: %v", tableName, err)
+	} else {
+		log.Printf("Table %s created successfully", tableName)
+	}
+}
+
+func recreateTableSeries(svc *dynamodb.Client) {
+	tableName := "CookieSeries"
+	deleteTableIfExists(svc, tableName)
+	log.Printf("Creating table %s...", tableName)
+
+	_, err := svc.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("SeriesID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("SeriesID"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+	})
+
+	if err != nil {
+		log.Printf("Could not create table %s: %v", tableName, err)
+	} else {
+		log.Printf("Table %s created successfully", tableName)
+	}
+}
+
+func recreateTableTournaments(svc *dynamodb.Client) {
+	tableName := "CookieTournaments"
+	deleteTableIfExists(svc, tableName)
+	log.Printf("Creating table %s...", tableName)
+
+	_, err := svc.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
+		AttributeDefinitions: []types.AttributeDefinition{
+			{
+				AttributeName: aws.String("TournamentID"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{
+				AttributeName: aws.String("TournamentID"),
+				KeyType:       types.KeyTypeHash,
+			},
+		},
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModePayPerRequest,
+	})
+
+	if err != nil {
+		log.Printf("Could not create table %s: %v", tableName, err)
+	} else {
+		log.Printf("Table %s created successfully", tableName)
+	}
+}
@@ -2,6 +2,9 @@ package db
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"log"
 	"os"
 	"sort"
@@ -53,7 +56,20 @@ type CookieUser struct {
 	Email   string `json:"email" dynamodbav:"Email"`
 	Name    string `json:"name" dynamodbav:"Name"`
 	Picture string `json:"picture" dynamodbav:"Picture"`
-	Score   int    `json:"score" dynamodbav:"Score"` // Total Score
+	Score   int    `json:"score" dynamodbav:"Score"`   // Total Score
+	Rating  int    `json:"rating" dynamodbav:"Rating"` // Elo skill rating
+}
+
+// DefaultRating is the Elo rating every new player starts with
+const DefaultRating = 1000
+
+// EffectiveRating returns the player's rating, treating users created before
+// ratings existed as having the default rating
+func (u CookieUser) EffectiveRating() int {
+	if u.Rating == 0 {
+		return DefaultRating
+	}
+	return u.Rating
 }
 
 // Model: CookieGame
@@ -71,10 +87,186 @@ type CookieGame struct {
 	PlayerPicture   string `json:"playerPicture" dynamodbav:"PlayerPicture"`
 	OpponentName    string `json:"opponentName" dynamodbav:"OpponentName"`
 	OpponentPicture string `json:"opponentPicture" dynamodbav:"OpponentPicture"`
+	Suspicious      bool   `json:"suspicious" dynamodbav:"Suspicious"`   // Flagged by click anti-cheat
+	Mode            string `json:"mode" dynamodbav:"Mode"`               // "duel" or "ffa"; empty for older 1v1 records
+	Placement       int    `json:"placement" dynamodbav:"Placement"`     // 1 = best; tied scores share a placement
+	PlayerCount     int    `json:"playerCount" dynamodbav:"PlayerCount"` // Players in the match
+	Team            string `json:"team,omitempty" dynamodbav:"Team"`     // Team matches: the player's team
+	TeamScore       int    `json:"teamScore,omitempty" dynamodbav:"TeamScore"`
+	RematchOf       string `json:"rematchOf,omitempty" dynamodbav:"RematchOf,omitempty"` // GameID of the game this one is a rematch of
+	Practice        bool   `json:"practice,omitempty" dynamodbav:"Practice,omitempty"`   // Against a bot, not counted for the leaderboards
+}
+
+// Model: CookieReplay
+// A replay is stored as several items of the same GameID: this header with Chunk 0
+// and the event timeline split into Chunks parts (Chunk 1..Chunks), so that long
+// games stay below the 400KB DynamoDB item size limit.
+type CookieReplay struct {
+	GameID    string `json:"gameId" dynamodbav:"GameID"`
+	Chunk     int    `json:"-" dynamodbav:"Chunk"` // Always 0
+	Player1ID string `json:"player1Id" dynamodbav:"Player1ID"`
+	Player2ID string `json:"player2Id" dynamodbav:"Player2ID"`
+	P1Name    string `json:"p1Name" dynamodbav:"P1Name"`
+	P2Name    string `json:"p2Name" dynamodbav:"P2Name"`
+	P1Picture string `json:"p1Picture" dynamodbav:"P1Picture"`
+	P2Picture string `json:"p2Picture" dynamodbav:"P2Picture"`
+	StartedAt int64  `json:"startedAt" dynamodbav:"StartedAt"` // Unix ms
+	Players   string `json:"players" dynamodbav:"Players"`     // JSON-encoded seat list, "p1".."pN"
+	Chunks    int    `json:"chunks" dynamodbav:"Chunks"`       // Number of timeline parts
+}
+
+// Model: CookieReplayChunk
+// A consecutive part of a replay's event timeline as a JSON-encoded array
+type CookieReplayChunk struct {
+	GameID string `json:"gameId" dynamodbav:"GameID"`
+	Chunk  int    `json:"chunk" dynamodbav:"Chunk"` // 1..Chunks
+	Events string `json:"events" dynamodbav:"Events"`
+}
+
+// Model: CookieSeason
+// Seasons are numbered consecutively; a season is archived once its final
+// standings have been written to the season stats.
+type CookieSeason struct {
+	SeasonID int    `json:"seasonId" dynamodbav:"SeasonID"`
+	Name     string `json:"name" dynamodbav:"Name"`
+	StartsAt int64  `json:"startsAt" dynamodbav:"StartsAt"` // Unix seconds
+	EndsAt   int64  `json:"endsAt" dynamodbav:"EndsAt"`     // Unix seconds, exclusive
+	Archived bool   `json:"archived" dynamodbav:"Archived"`
+}
+
+// Model: CookieSeasonStats
+// One record per player and season (SeasonID partition key, UserID sort key)
+type CookieSeasonStats struct {
+	SeasonID  int    `json:"seasonId" dynamodbav:"SeasonID"`
+	UserID    string `json:"userId" dynamodbav:"UserID"`
+	Name      string `json:"name" dynamodbav:"Name"`
+	Picture   string `json:"picture" dynamodbav:"Picture"`
+	Score     int    `json:"score" dynamodbav:"Score"`         // Points scored this season
+	Rating    int    `json:"rating" dynamodbav:"Rating"`       // Rating after the player's last game of the season
+	Games     int    `json:"games" dynamodbav:"Games"`         // Games played this season
+	Wins      int    `json:"wins" dynamodbav:"Wins"`           // Games won this season
+	FinalRank int    `json:"finalRank" dynamodbav:"FinalRank"` // Set when the season is archived
+}
+
+// Model: CookiePlayerStats
+// Lifetime statistics of a player, updated incrementally after every game
+type CookiePlayerStats struct {
+	UserID        string `json:"userId" dynamodbav:"UserID"`
+	Games         int    `json:"games" dynamodbav:"Games"`
+	Wins          int    `json:"wins" dynamodbav:"Wins"`
+	Losses        int    `json:"losses" dynamodbav:"Losses"`
+	Draws         int    `json:"draws" dynamodbav:"Draws"`
+	TotalScore    int    `json:"totalScore" dynamodbav:"TotalScore"`
+	BestScore     int    `json:"bestScore" dynamodbav:"BestScore"`
+	TotalClicks   int    `json:"totalClicks" dynamodbav:"TotalClicks"`
+	TotalSeconds  int    `json:"totalSeconds" dynamodbav:"TotalSeconds"`   // Seconds played, for clicks per second
+	CurrentStreak int    `json:"currentStreak" dynamodbav:"CurrentStreak"` // Wins in a row up to the last game
+	LongestStreak int    `json:"longestStreak" dynamodbav:"LongestStreak"`
+	GoldenCookies int    `json:"goldenCookies" dynamodbav:"GoldenCookies"` // Golden cookies claimed
+}
+
+// PlayerGame is a finished game of a player as counted into CookiePlayerStats
+type PlayerGame struct {
+	Score         int
+	Clicks        int
+	Seconds       int
+	GoldenCookies int
+	Won           bool
+	Draw          bool
+}
+
+// Model: CookieHeadToHead
+// One aggregate record per pair of players who met in a duel. The pair is stored
+// in a fixed order (PlayerA < PlayerB), see HeadToHeadID.
+type CookieHeadToHead struct {
+	PairID          string `json:"pairId" dynamodbav:"PairID"`
+	PlayerA         string `json:"playerA" dynamodbav:"PlayerA"`
+	PlayerB         string `json:"playerB" dynamodbav:"PlayerB"`
+	Games           int    `json:"games" dynamodbav:"Games"`
+	WinsA           int    `json:"winsA" dynamodbav:"WinsA"`
+	WinsB           int    `json:"winsB" dynamodbav:"WinsB"`
+	Draws           int    `json:"draws" dynamodbav:"Draws"`
+	LastPlayed      int64  `json:"lastPlayed" dynamodbav:"LastPlayed"`           // Unix seconds
+	BiggestMargin   int    `json:"biggestMargin" dynamodbav:"BiggestMargin"`     // Largest winning score difference
+	BiggestMarginBy string `json:"biggestMarginBy" dynamodbav:"BiggestMarginBy"` // Winner of that game
+}
+
+// HeadToHeadID returns the key of the head-to-head record of two players and the
+// players in stored order
+func HeadToHeadID(userA, userB string) (string, string, string) {
+	if userB < userA {
+		userA, userB = userB, userA
+	}
+	return userA + "#" + userB, userA, userB
+}
+
+// Model: CookieAchievement
+// One record per unlocked achievement (UserID partition key, AchievementID sort key)
+type CookieAchievement struct {
+	UserID        string `json:"userId" dynamodbav:"UserID"`
+	AchievementID string `json:"achievementId" dynamodbav:"AchievementID"`
+	GameID        string `json:"gameId" dynamodbav:"GameID"`         // Game that unlocked it
+	UnlockedAt    int64  `json:"unlockedAt" dynamodbav:"UnlockedAt"` // Unix seconds
+}
+
+// Model: CookieFriend
+// A friendship is stored as two records, one per side (UserID partition key,
+// FriendID sort key), so each player's list is a single query.
+type CookieFriend struct {
+	UserID   string `json:"userId" dynamodbav:"UserID"`
+	FriendID string `json:"friendId" dynamodbav:"FriendID"`
+	Status   string `json:"status" dynamodbav:"Status"` // FriendOutgoing, FriendIncoming or FriendAccepted
+	Since    int64  `json:"since" dynamodbav:"Since"`   // Unix seconds of the request, then of the acceptance
+}
+
+// Friendship states, seen from the record's UserID
+const (
+	FriendOutgoing = "outgoing" // UserID sent a request to FriendID
+	FriendIncoming = "incoming" // FriendID sent a request to UserID
+	FriendAccepted = "accepted"
+)
+
+// Model: CookieSeries
+// The parent record of a best-of-N series; the rounds are regular CookieGames
+// listed by GameIDs in the order they were played.
+type CookieSeries struct {
+	SeriesID  string         `json:"seriesId" dynamodbav:"SeriesID"`
+	BestOf    int            `json:"bestOf" dynamodbav:"BestOf"`
+	Mode      string         `json:"mode" dynamodbav:"Mode"`
+	PlayerIDs []string       `json:"playerIds" dynamodbav:"PlayerIDs"` // Seat order
+	Names     []string       `json:"names" dynamodbav:"Names"`         // Same order as PlayerIDs
+	Wins      map[string]int `json:"wins" dynamodbav:"Wins"`           // UserID -> rounds won
+	Draws     int            `json:"draws" dynamodbav:"Draws"`
+	GameIDs   []string       `json:"gameIds" dynamodbav:"GameIDs"`
+	WinnerID  string         `json:"winnerId" dynamodbav:"WinnerID"`   // "draw" if the series ended level
+	Reason    string         `json:"reason" dynamodbav:"Reason"`       // "normal" or "forfeit"
+	StartedAt int64          `json:"startedAt" dynamodbav:"StartedAt"` // Unix seconds
+	EndedAt   int64          `json:"endedAt" dynamodbav:"EndedAt"`     // Unix seconds
+}
+
+// Model: CookieTournament
+// The bracket changes as a whole with every finished match, so it is stored as a
+// single JSON-encoded attribute. Version guards concurrent updates from several pods.
+type CookieTournament struct {
+	TournamentID string `json:"tournamentId" dynamodbav:"TournamentID"`
+	Name         string `json:"name" dynamodbav:"Name"`
+	Status       string `json:"status" dynamodbav:"Status"`
+	CreatedAt    int64  `json:"createdAt" dynamodbav:"CreatedAt"` // Unix seconds
+	Version      int    `json:"version" dynamodbav:"Version"`     // 1 on creation, +1 per save
+	Bracket      string `json:"bracket" dynamodbav:"Bracket"`
 }
 
 const TableUsers = "CookieUsers"
 const TableGames = "CookieGames"
+const TableReplays = "CookieReplays"
+const TableSeasons = "CookieSeasons"
+const TableSeasonStats = "CookieSeasonStats"
+const TablePlayerStats = "CookiePlayerStats"
+const TableHeadToHead = "CookieHeadToHead"
+const TableAchievements = "CookieAchievements"
+const TableFriends = "CookieFriends"
+const TableSeries = "CookieSeries"
+const TableTournaments = "CookieTournaments"
 
 // --- User Operations ---
 
@@ -110,6 +302,9 @@ func SaveUser(user CookieUser) error {
 	}
 
 	// New User
+	if user.Rating == 0 {
+		user.Rating = DefaultRating
+	}
 	av, err := attributevalue.MarshalMap(user)
 	if err != nil {
 		return err
@@ -163,6 +358,50 @@ func UpdateUserStats(userID string, score int) error {
 	return err
 }
 
+// UpdateUserRating stores a player's new Elo rating
+func UpdateUserRating(userID string, rating int) error {
+	_, err := svc.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: aws.String(TableUsers),
+		Key: map[string]types.AttributeValue{
+			"UserID": &types.AttributeValueMemberS{Value: userID},
+		},
+		UpdateExpression: aws.String("set Rating = :r"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":r": &types.AttributeValueMemberN{Value: strconv.Itoa(rating)},
+		},
+	})
+	if err == nil {
+		log.Printf("[DB] Updated rating for user %s: %d", userID, rating)
+	}
+	return err
+}
+
+// GetLeaderboardByRating returns the top players ranked by Elo rating
+func GetLeaderboardByRating(limit int) ([]CookieUser, error) {
+	// Full Scan + Sort, same trade-off as GetLeaderboard
+	out, err := svc.Scan(context.TODO(), &dynamodb.ScanInput{
+		TableName: aws.String(TableUsers),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var users []CookieUser
+	err = attributevalue.UnmarshalListOfMaps(out.Items, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].EffectiveRating() > users[j].EffectiveRating()
+	})
+
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
 func GetLeaderboard(limit int) ([]CookieUser, error) {
 	// Full Scan + Sort (Okay for < 10k users)
 	out, err := svc.Scan(context.TODO(), &dynamodb.ScanInput{
@@ -189,6 +428,27 @@ func GetLeaderboard(limit int) ([]CookieUser, error) {
 	return users, nil
 }
 
+// ListUsers returns all users, e.g. to rebuild the Redis leaderboard
+func ListUsers() ([]CookieUser, error) {
+	paginator := dynamodb.NewScanPaginator(svc, &dynamodb.ScanInput{
+		TableName: aws.String(TableUsers),
+	})
+
+	var users []CookieUser
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		var page []CookieUser
+		if err := attributevalue.UnmarshalListOfMaps(out.Items, &page); err != nil {
+			return nil, err
+		}
+		users = append(users, page...)
+	}
+	return users, nil
+}
+
 // --- Game History Operations ---
 
 func SaveGame(game CookieGame) error {
@@ -208,8 +468,37 @@ func SaveGame(game CookieGame) error {
 	return err
 }
 
-func GetGameHistory(userID string, limit int32) ([]CookieGame, error) {
-	out, err := svc.Query(context.TODO(), &dynamodb.QueryInput{
+// ErrInvalidCursor is returned for a history cursor that wasn't issued for the player
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// historyCursor is the position after the last game of a history page, i.e. the
+// LastEvaluatedKey of PlayerHistoryIndex (table key plus index key)
+type historyCursor struct {
+	GameID    string `json:"g" dynamodbav:"GameID"`
+	PlayerID  string `json:"p" dynamodbav:"PlayerID"`
+	Timestamp int64  `json:"t" dynamodbav:"Timestamp"`
+}
+
+func encodeHistoryCursor(c historyCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeHistoryCursor parses an opaque history cursor of the given player
+func decodeHistoryCursor(userID, cursor string) (historyCursor, error) {
+	var c historyCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || json.Unmarshal(data, &c) != nil || c.PlayerID != userID || c.GameID == "" {
+		return historyCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// GetGameHistory returns a page of up to limit games of a player, newest first,
+// starting at the cursor of the previous page ("" for the first page). The returned
+// cursor is empty once the history is exhausted.
+func GetGameHistory(userID string, limit int32, cursor string) ([]CookieGame, string, error) {
+	input := &dynamodb.QueryInput{
 		TableName:              aws.String(TableGames),
 		IndexName:              aws.String("PlayerHistoryIndex"),
 		KeyConditionExpression: aws.String("PlayerID = :pid"),
@@ -218,19 +507,39 @@ func GetGameHistory(userID string, limit int32) ([]CookieGame, error) {
 		},
 		ScanIndexForward: aws.Bool(false), // Descending timestamp
 		Limit:            aws.Int32(limit),
-	})
+	}
+	if cursor != "" {
+		start, err := decodeHistoryCursor(userID, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if input.ExclusiveStartKey, err = attributevalue.MarshalMap(start); err != nil {
+			return nil, "", err
+		}
+	}
+
+	out, err := svc.Query(context.TODO(), input)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var games []CookieGame
 	err = attributevalue.UnmarshalListOfMaps(out.Items, &games)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	var next string
+	if len(out.LastEvaluatedKey) > 0 {
+		var last historyCursor
+		if err := attributevalue.UnmarshalMap(out.LastEvaluatedKey, &last); err != nil {
+			return nil, "", err
+		}
+		next = encodeHistoryCursor(last)
 	}
 
 	if len(games) == 0 {
-		return games, nil
+		return games, next, nil
 	}
 
 	// 2. Enrich with User Data (BatchGetItem)
@@ -253,10 +562,10 @@ func GetGameHistory(userID string, limit int32) ([]CookieGame, error) {
 	}
 
 	if len(keys) == 0 {
-		return games, nil
+		return games, next, nil
 	}
 
-	// DynamoDB BatchGetItem (limit 100, we retrieve max 20 games * 2 users = 40 keys, so safe)
+	// DynamoDB BatchGetItem (limit 100 keys; callers request at most 50 games, i.e. the player plus 50 opponents)
 	batchOut, err := svc.BatchGetItem(context.TODO(), &dynamodb.BatchGetItemInput{
 		RequestItems: map[string]types.KeysAndAttributes{
 			TableUsers: {
@@ -267,7 +576,7 @@ func GetGameHistory(userID string, limit int32) ([]CookieGame, error) {
 	if err != nil {
 		log.Printf("[DB] Error batch fetching users for history: %v", err)
 		// Return games anyway, just without enriched info (or with snapshot info)
-		return games, nil
+		return games, next, nil
 	}
 
 	// Unmarshal Users
@@ -275,7 +584,7 @@ func GetGameHistory(userID string, limit int32) ([]CookieGame, error) {
 	err = attributevalue.UnmarshalListOfMaps(batchOut.Responses[TableUsers], &users)
 	if err != nil {
 		log.Printf("[DB] Error unmarshaling batch users: %v", err)
-		return games, nil
+		return games, next, nil
 	}
 
 	// Create Map for lookup
@@ -296,6 +605,33 @@ func GetGameHistory(userID string, limit int32) ([]CookieGame, error) {
 		}
 	}
 
+	return games, next, nil
+}
+
+// ListGamesSince returns the game records of all players since the given Unix time,
+// e.g. to rank the points scored in a leaderboard time window
+func ListGamesSince(since int64) ([]CookieGame, error) {
+	paginator := dynamodb.NewScanPaginator(svc, &dynamodb.ScanInput{
+		TableName:                aws.String(TableGames),
+		FilterExpression:         aws.String("#ts >= :since"),
+		ExpressionAttributeNames: map[string]string{"#ts": "Timestamp"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":since": &types.AttributeValueMemberN{Value: strconv.FormatInt(since, 10)},
+		},
+	})
+
+	var games []CookieGame
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		var page []CookieGame
+		if err := attributevalue.UnmarshalListOfMaps(out.Items, &page); err != nil {
+			return nil, err
+		}
+		games = append(games, page...)
+	}
 	return games, nil
 }
 
@@ -315,3 +651,696 @@ func CountGamesByPlayer(userID string) (int, error) {
 	}
 	return int(out.Count), nil
 }
+
+// --- Replay Operations ---
+
+// SaveReplay stores the timeline chunks of a replay and then its header, so a
+// replay is only found once it is complete
+func SaveReplay(replay CookieReplay, chunks []CookieReplayChunk) error {
+	replay.Chunk = 0
+	replay.Chunks = len(chunks)
+
+	items := make([]interface{}, 0, len(chunks)+1)
+	for _, chunk := range chunks {
+		items = append(items, chunk)
+	}
+	items = append(items, replay)
+
+	for _, item := range items {
+		av, err := attributevalue.MarshalMap(item)
+		if err != nil {
+			return err
+		}
+		_, err = svc.PutItem(context.TODO(), &dynamodb.PutItemInput{
+			TableName: aws.String(TableReplays),
+			Item:      av,
+		})
+		if err != nil {
+			log.Printf("[DB] Error saving replay: %v", err)
+			return err
+		}
+	}
+	log.Printf("[DB] Saved replay for game %s (%d chunks)", replay.GameID, len(chunks))
+	return nil
+}
+
+// GetReplay returns the header and timeline chunks of a replay in order, nil if
+// the game has no replay
+func GetReplay(gameID string) (*CookieReplay, []CookieReplayChunk, error) {
+	out, err := svc.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(TableReplays),
+		Key: map[string]types.AttributeValue{
+			"GameID": &types.AttributeValueMemberS{Value: gameID},
+			"Chunk":  &types.AttributeValueMemberN{Value: "0"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if out.Item == nil {
+		return nil, nil, nil // Not found
+	}
+
+	var replay CookieReplay
+	if err := attributevalue.UnmarshalMap(out.Item, &replay); err != nil {
+		return nil, nil, err
+	}
+
+	paginator := dynamodb.NewQueryPaginator(svc, &dynamodb.QueryInput{
+		TableName:              aws.String(TableReplays),
+		KeyConditionExpression: aws.String("GameID = :gid AND Chunk > :header"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":gid":    &types.AttributeValueMemberS{Value: gameID},
+			":header": &types.AttributeValueMemberN{Value: "0"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+
+	chunks := make([]CookieReplayChunk, 0, replay.Chunks)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, nil, err
+		}
+		var items []CookieReplayChunk
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, nil, err
+		}
+		chunks = append(chunks, items...)
+	}
+	return &replay, chunks, nil
+}
+
+// --- Season Operations ---
+
+// CreateSeason stores a new season. Returns false if a season with the same ID
+// already exists, e.g. because another pod rolled over first.
+func CreateSeason(season CookieSeason) (bool, error) {
+	av, err := attributevalue.MarshalMap(season)
+	if err != nil {
+		return false, err
+	}
+	_, err = svc.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           aws.String(TableSeasons),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(SeasonID)"),
+	})
+	var exists *types.ConditionalCheckFailedException
+	if errors.As(err, &exists) {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("[DB] Error creating season: %v", err)
+		return false, err
+	}
+	log.Printf("[DB] Created season %d", season.SeasonID)
+	return true, nil
+}
+
+// ListSeasons returns all seasons, oldest first
+func ListSeasons() ([]CookieSeason, error) {
+	// Full Scan, there is only a handful of seasons per year
+	out, err := svc.Scan(context.TODO(), &dynamodb.ScanInput{
+		TableName: aws.String(TableSeasons),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var seasons []CookieSeason
+	err = attributevalue.UnmarshalListOfMaps(out.Items, &seasons)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].SeasonID < seasons[j].SeasonID
+	})
+	return seasons, nil
+}
+
+// UpdateSeasonStats adds the result of one game to a player's season record
+func UpdateSeasonStats(seasonID int, userID, name, picture string, score int, won bool, rating int) error {
+	wins := 0
+	if won {
+		wins = 1
+	}
+	_, err := svc.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: aws.String(TableSeasonStats),
+		Key: map[string]types.AttributeValue{
+			"SeasonID": &types.AttributeValueMemberN{Value: strconv.Itoa(seasonID)},
+			"UserID":   &types.AttributeValueMemberS{Value: userID},
+		},
+		UpdateExpression: aws.String("set #N = :n, Picture = :p, Rating = :r ADD Score :s, Games :one, Wins :w"),
+		ExpressionAttributeNames: map[string]string{
+			"#N": "Name", // Name is reserved
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":n":   &types.AttributeValueMemberS{Value: name},
+			":p":   &types.AttributeValueMemberS{Value: picture},
+			":r":   &types.AttributeValueMemberN{Value: strconv.Itoa(rating)},
+			":s":   &types.AttributeValueMemberN{Value: strconv.Itoa(score)},
+			":one": &types.AttributeValueMemberN{Value: "1"},
+			":w":   &types.AttributeValueMemberN{Value: strconv.Itoa(wins)},
+		},
+	})
+	if err != nil {
+		log.Printf("[DB] Error updating season %d stats for user %s: %v", seasonID, userID, err)
+	}
+	return err
+}
+
+// GetSeasonStats returns the records of all players of a season, ordered by score
+func GetSeasonStats(seasonID int) ([]CookieSeasonStats, error) {
+	paginator := dynamodb.NewQueryPaginator(svc, &dynamodb.QueryInput{
+		TableName:              aws.String(TableSeasonStats),
+		KeyConditionExpression: aws.String("SeasonID = :sid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":sid": &types.AttributeValueMemberN{Value: strconv.Itoa(seasonID)},
+		},
+	})
+
+	var stats []CookieSeasonStats
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		var page []CookieSeasonStats
+		if err := attributevalue.UnmarshalListOfMaps(out.Items, &page); err != nil {
+			return nil, err
+		}
+		stats = append(stats, page...)
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].Score > stats[j].Score
+	})
+	return stats, nil
+}
+
+// ArchiveSeason stores the final rank of every player of a season and marks the
+// season as archived
+func ArchiveSeason(seasonID int, ranks map[string]int) error {
+	for userID, rank := range ranks {
+		_, err := svc.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+			TableName: aws.String(TableSeasonStats),
+			Key: map[string]types.AttributeValue{
+				"SeasonID": &types.AttributeValueMemberN{Value: strconv.Itoa(seasonID)},
+				"UserID":   &types.AttributeValueMemberS{Value: userID},
+			},
+			UpdateExpression: aws.String("set FinalRank = :rank"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":rank": &types.AttributeValueMemberN{Value: strconv.Itoa(rank)},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := svc.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: aws.String(TableSeasons),
+		Key: map[string]types.AttributeValue{
+			"SeasonID": &types.AttributeValueMemberN{Value: strconv.Itoa(seasonID)},
+		},
+		UpdateExpression: aws.String("set Archived = :a"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":a": &types.AttributeValueMemberBOOL{Value: true},
+		},
+	})
+	if err == nil {
+		log.Printf("[DB] Archived season %d with %d players", seasonID, len(ranks))
+	}
+	return err
+}
+
+// --- Player Stats Operations ---
+
+// UpdatePlayerStats adds a finished game to a player's lifetime statistics
+func UpdatePlayerStats(userID string, game PlayerGame) error {
+	wins, losses, draws := 0, 0, 0
+	streak := ":zero" // Anything but a win ends the streak
+	switch {
+	case game.Won:
+		wins = 1
+		streak = "if_not_exists(CurrentStreak, :zero) + :one"
+	case game.Draw:
+		draws = 1
+	default:
+		losses = 1
+	}
+
+	out, err := svc.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: aws.String(TablePlayerStats),
+		Key: map[string]types.AttributeValue{
+			"UserID": &types.AttributeValueMemberS{Value: userID},
+		},
+		UpdateExpression: aws.String("set CurrentStreak = " + streak +
+			" ADD Games :one, Wins :w, Losses :l, Draws :d, TotalScore :s, TotalClicks :c, TotalSeconds :t, GoldenCookies :g"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":zero": &types.AttributeValueMemberN{Value: "0"},
+			":one":  &types.AttributeValueMemberN{Value: "1"},
+			":w":    &types.AttributeValueMemberN{Value: strconv.Itoa(wins)},
+			":l":    &types.AttributeValueMemberN{Value: strconv.Itoa(losses)},
+			":d":    &types.AttributeValueMemberN{Value: strconv.Itoa(draws)},
+			":s":    &types.AttributeValueMemberN{Value: strconv.Itoa(game.Score)},
+			":c":    &types.AttributeValueMemberN{Value: strconv.Itoa(game.Clicks)},
+			":t":    &types.AttributeValueMemberN{Value: strconv.Itoa(game.Seconds)},
+			":g":    &types.AttributeValueMemberN{Value: strconv.Itoa(game.GoldenCookies)},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	if err != nil {
+		log.Printf("[DB] Error updating stats for user %s: %v", userID, err)
+		return err
+	}
+
+	var stats CookiePlayerStats
+	if err := attributevalue.UnmarshalMap(out.Attributes, &stats); err != nil {
+		return err
+	}
+	if stats.CurrentStreak <= stats.LongestStreak && game.Score <= stats.BestScore {
+		return nil
+	}
+
+	// Records only ever go up; the condition keeps a concurrent update from lowering them
+	best, longest := max(stats.BestScore, game.Score), max(stats.LongestStreak, stats.CurrentStreak)
+	_, err = svc.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName: aws.String(TablePlayerStats),
+		Key: map[string]types.AttributeValue{
+			"UserID": &types.AttributeValueMemberS{Value: userID},
+		},
+		UpdateExpression: aws.String("set BestScore = :b, LongestStreak = :ls"),
+		ConditionExpression: aws.String("(attribute_not_exists(BestScore) OR BestScore <= :b) AND " +
+			"(attribute_not_exists(LongestStreak) OR LongestStreak <= :ls)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":b":  &types.AttributeValueMemberN{Value: strconv.Itoa(best)},
+			":ls": &types.AttributeValueMemberN{Value: strconv.Itoa(longest)},
+		},
+	})
+	var lowered *types.ConditionalCheckFailedException
+	if errors.As(err, &lowered) {
+		return nil
+	}
+	return err
+}
+
+// GetPlayerStats returns a player's lifetime statistics, nil if they never played
+func GetPlayerStats(userID string) (*CookiePlayerStats, error) {
+	out, err := svc.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(TablePlayerStats),
+		Key: map[string]types.AttributeValue{
+			"UserID": &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Item == nil {
+		return nil, nil
+	}
+
+	var stats CookiePlayerStats
+	if err := attributevalue.UnmarshalMap(out.Item, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// --- Head-to-Head Operations ---
+
+// UpdateHeadToHead adds a finished duel between two players to their head-to-head
+// record. winnerID is one of the players, anything else counts as a draw.
+func UpdateHeadToHead(userA, userB string, scoreA, scoreB int, winnerID string, timestamp int64) error {
+	pairID, first, _ := HeadToHeadID(userA, userB)
+	if first != userA {
+		userA, userB = userB, userA
+		scoreA, scoreB = scoreB, scoreA
+	}
+	winsA, winsB, draws := 0, 0, 0
+	margin := 0 // Winner's lead, may be negative after a forfeit
+	switch winnerID {
+	case userA:
+		winsA = 1
+		margin = scoreA - scoreB
+	case userB:
+		winsB = 1
+		margin = scoreB - scoreA
+	default:
+		draws = 1
+	}
+
+	key := map[string]types.AttributeValue{
+		"PairID": &types.AttributeValueMemberS{Value: pairID},
+	}
+	_, err := svc.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:        aws.String(TableHeadToHead),
+		Key:              key,
+		UpdateExpression: aws.String("set PlayerA = :a, PlayerB = :b, LastPlayed = :ts ADD Games :one, WinsA :wa, WinsB :wb, Draws :d"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":a":   &types.AttributeValueMemberS{Value: userA},
+			":b":   &types.AttributeValueMemberS{Value: userB},
+			":ts":  &types.AttributeValueMemberN{Value: strconv.FormatInt(timestamp, 10)},
+			":one": &types.AttributeValueMemberN{Value: "1"},
+			":wa":  &types.AttributeValueMemberN{Value: strconv.Itoa(winsA)},
+			":wb":  &types.AttributeValueMemberN{Value: strconv.Itoa(winsB)},
+			":d":   &types.AttributeValueMemberN{Value: strconv.Itoa(draws)},
+		},
+	})
+	if err != nil {
+		log.Printf("[DB] Error updating head-to-head %s: %v", pairID, err)
+		return err
+	}
+	if margin <= 0 {
+		return nil
+	}
+
+	// Only a new record margin is written; the condition fails otherwise
+	_, err = svc.UpdateItem(context.TODO(), &dynamodb.UpdateItemInput{
+		TableName:           aws.String(TableHeadToHead),
+		Key:                 key,
+		UpdateExpression:    aws.String("set BiggestMargin = :m, BiggestMarginBy = :w"),
+		ConditionExpression: aws.String("attribute_not_exists(BiggestMargin) OR BiggestMargin < :m"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":m": &types.AttributeValueMemberN{Value: strconv.Itoa(margin)},
+			":w": &types.AttributeValueMemberS{Value: winnerID},
+		},
+	})
+	var smaller *types.ConditionalCheckFailedException
+	if errors.As(err, &smaller) {
+		return nil
+	}
+	return err
+}
+
+// GetHeadToHead returns the head-to-head record of two players, nil if they never met
+func GetHeadToHead(userA, userB string) (*CookieHeadToHead, error) {
+	pairID, _, _ := HeadToHeadID(userA, userB)
+	out, err := svc.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(TableHeadToHead),
+		Key: map[string]types.AttributeValue{
+			"PairID": &types.AttributeValueMemberS{Value: pairID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Item == nil {
+		return nil, nil
+	}
+
+	var record CookieHeadToHead
+	if err := attributevalue.UnmarshalMap(out.Item, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// --- Achievement Operations ---
+
+// UnlockAchievement stores an unlocked achievement. Returns false if the player
+// had already unlocked it.
+func UnlockAchievement(achievement CookieAchievement) (bool, error) {
+	av, err := attributevalue.MarshalMap(achievement)
+	if err != nil {
+		return false, err
+	}
+	_, err = svc.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           aws.String(TableAchievements),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(AchievementID)"),
+	})
+	var exists *types.ConditionalCheckFailedException
+	if errors.As(err, &exists) {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("[DB] Error unlocking achievement %s for user %s: %v", achievement.AchievementID, achievement.UserID, err)
+		return false, err
+	}
+	log.Printf("[DB] User %s unlocked achievement %s", achievement.UserID, achievement.AchievementID)
+	return true, nil
+}
+
+// GetAchievements returns all achievements a player has unlocked
+func GetAchievements(userID string) ([]CookieAchievement, error) {
+	paginator := dynamodb.NewQueryPaginator(svc, &dynamodb.QueryInput{
+		TableName:              aws.String(TableAchievements),
+		KeyConditionExpression: aws.String("UserID = :uid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":uid": &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+
+	var achievements []CookieAchievement
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		var page []CookieAchievement
+		if err := attributevalue.UnmarshalListOfMaps(out.Items, &page); err != nil {
+			return nil, err
+		}
+		achievements = append(achievements, page...)
+	}
+	return achievements, nil
+}
+
+// --- Friend Operations ---
+
+// friendKey is the key of one side of a friendship
+func friendKey(userID, friendID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"UserID":   &types.AttributeValueMemberS{Value: userID},
+		"FriendID": &types.AttributeValueMemberS{Value: friendID},
+	}
+}
+
+// AddFriendRequest stores a friend request from one player to another. Returns
+// false if the two players are already friends or a request between them exists.
+func AddFriendRequest(fromID, toID string, timestamp int64) (bool, error) {
+	puts := make([]types.TransactWriteItem, 0, 2)
+	for _, f := range []CookieFriend{
+		{UserID: fromID, FriendID: toID, Status: FriendOutgoing, Since: timestamp},
+		{UserID: toID, FriendID: fromID, Status: FriendIncoming, Since: timestamp},
+	} {
+		av, err := attributevalue.MarshalMap(f)
+		if err != nil {
+			return false, err
+		}
+		puts = append(puts, types.TransactWriteItem{Put: &types.Put{
+			TableName:           aws.String(TableFriends),
+			Item:                av,
+			ConditionExpression: aws.String("attribute_not_exists(FriendID)"),
+		}})
+	}
+
+	_, err := svc.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{TransactItems: puts})
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("[DB] Error storing friend request %s -> %s: %v", fromID, toID, err)
+		return false, err
+	}
+	log.Printf("[DB] Friend request %s -> %s", fromID, toID)
+	return true, nil
+}
+
+// AcceptFriendRequest turns a pending request from fromID to userID into a
+// friendship. Returns false if there is no such request.
+func AcceptFriendRequest(userID, fromID string, timestamp int64) (bool, error) {
+	update := func(key map[string]types.AttributeValue, expected string) types.TransactWriteItem {
+		return types.TransactWriteItem{Update: &types.Update{
+			TableName:           aws.String(TableFriends),
+			Key:                 key,
+			UpdateExpression:    aws.String("set #S = :accepted, Since = :ts"),
+			ConditionExpression: aws.String("#S = :expected"),
+			ExpressionAttributeNames: map[string]string{
+				"#S": "Status", // Status is reserved
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":accepted": &types.AttributeValueMemberS{Value: FriendAccepted},
+				":expected": &types.AttributeValueMemberS{Value: expected},
+				":ts":       &types.AttributeValueMemberN{Value: strconv.FormatInt(timestamp, 10)},
+			},
+		}}
+	}
+
+	_, err := svc.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			update(friendKey(userID, fromID), FriendIncoming),
+			update(friendKey(fromID, userID), FriendOutgoing),
+		},
+	})
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("[DB] Error accepting friend request %s -> %s: %v", fromID, userID, err)
+		return false, err
+	}
+	log.Printf("[DB] %s and %s are now friends", userID, fromID)
+	return true, nil
+}
+
+// RemoveFriend deletes a friendship or a pending request between two players, from both sides
+func RemoveFriend(userID, friendID string) error {
+	_, err := svc.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Delete: &types.Delete{TableName: aws.String(TableFriends), Key: friendKey(userID, friendID)}},
+			{Delete: &types.Delete{TableName: aws.String(TableFriends), Key: friendKey(friendID, userID)}},
+		},
+	})
+	if err != nil {
+		log.Printf("[DB] Error removing friend %s of %s: %v", friendID, userID, err)
+	}
+	return err
+}
+
+// ListFriends returns all friendships and pending requests of a player
+func ListFriends(userID string) ([]CookieFriend, error) {
+	paginator := dynamodb.NewQueryPaginator(svc, &dynamodb.QueryInput{
+		TableName:              aws.String(TableFriends),
+		KeyConditionExpression: aws.String("UserID = :uid"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":uid": &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+
+	var friends []CookieFriend
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		var page []CookieFriend
+		if err := attributevalue.UnmarshalListOfMaps(out.Items, &page); err != nil {
+			return nil, err
+		}
+		friends = append(friends, page...)
+	}
+	return friends, nil
+}
+
+// --- Series Operations ---
+
+func SaveSeries(series CookieSeries) error {
+	av, err := attributevalue.MarshalMap(series)
+	if err != nil {
+		return err
+	}
+	_, err = svc.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(TableSeries),
+		Item:      av,
+	})
+	if err == nil {
+		log.Printf("[DB] Saved series %s (Winner: %s)", series.SeriesID, series.WinnerID)
+	} else {
+		log.Printf("[DB] Error saving series: %v", err)
+	}
+	return err
+}
+
+func GetSeries(seriesID string) (*CookieSeries, error) {
+	out, err := svc.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(TableSeries),
+		Key: map[string]types.AttributeValue{
+			"SeriesID": &types.AttributeValueMemberS{Value: seriesID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Item == nil {
+		return nil, nil // Not found
+	}
+
+	var series CookieSeries
+	err = attributevalue.UnmarshalMap(out.Item, &series)
+	return &series, err
+}
+
+// --- Tournament Operations ---
+
+// SaveTournament stores a tournament whose Version was incremented by one. Returns
+// false if the stored version is not the one it was read at, i.e. another pod
+// updated the tournament in between.
+func SaveTournament(tournament CookieTournament) (bool, error) {
+	av, err := attributevalue.MarshalMap(tournament)
+	if err != nil {
+		return false, err
+	}
+	input := &dynamodb.PutItemInput{
+		TableName:           aws.String(TableTournaments),
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(TournamentID)"),
+	}
+	if tournament.Version > 1 {
+		input.ConditionExpression = aws.String("Version = :v")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":v": &types.AttributeValueMemberN{Value: strconv.Itoa(tournament.Version - 1)},
+		}
+	}
+	_, err = svc.PutItem(context.TODO(), input)
+	var conflict *types.ConditionalCheckFailedException
+	if errors.As(err, &conflict) {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("[DB] Error saving tournament %s: %v", tournament.TournamentID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+func GetTournament(tournamentID string) (*CookieTournament, error) {
+	out, err := svc.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: aws.String(TableTournaments),
+		Key: map[string]types.AttributeValue{
+			"TournamentID": &types.AttributeValueMemberS{Value: tournamentID},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Item == nil {
+		return nil, nil // Not found
+	}
+
+	var tournament CookieTournament
+	err = attributevalue.UnmarshalMap(out.Item, &tournament)
+	return &tournament, err
+}
+
+// ListTournaments returns all tournaments, newest first
+func ListTournaments() ([]CookieTournament, error) {
+	// Full Scan, tournaments are run by hand a few times a month
+	paginator := dynamodb.NewScanPaginator(svc, &dynamodb.ScanInput{
+		TableName: aws.String(TableTournaments),
+	})
+
+	var tournaments []CookieTournament
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		var page []CookieTournament
+		if err := attributevalue.UnmarshalListOfMaps(out.Items, &page); err != nil {
+			return nil, err
+		}
+		tournaments = append(tournaments, page...)
+	}
+
+	sort.Slice(tournaments, func(i, j int) bool {
+		return tournaments[i].CreatedAt > tournaments[j].CreatedAt
+	})
+	return tournaments, nil
+}
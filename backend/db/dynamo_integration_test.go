@@ -88,7 +88,7 @@ func TestDynamoDBIntegration_GetGameHistory(t *testing.T) {
 	Init()
 
 	// Try to get game history for a test user
-	games, err := GetGameHistory("integration-test-user", 5)
+	games, _, err := GetGameHistory("integration-test-user", 5, "")
 
 	if err != nil {
 		t.Logf("DynamoDB GetGameHistory error (may be expected if table doesn't exist): %v", err)
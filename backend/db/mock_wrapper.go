@@ -32,6 +32,7 @@ func SaveUserWithMock(user CookieUser) error {
 			Name:    user.Name,
 			Picture: user.Picture,
 			Score:   user.Score,
+			Rating:  user.Rating,
 		}
 		return mocks.GetMockDynamoDB().SaveUser(mockUser)
 	}
@@ -51,6 +52,7 @@ func GetUserWithMock(userID string) (*CookieUser, error) {
 			Name:    mockUser.Name,
 			Picture: mockUser.Picture,
 			Score:   mockUser.Score,
+			Rating:  mockUser.Rating,
 		}, nil
 	}
 	return GetUser(userID)
@@ -71,6 +73,7 @@ func GetLeaderboardWithMock(limit int) ([]CookieUser, error) {
 				Name:    mu.Name,
 				Picture: mu.Picture,
 				Score:   mu.Score,
+				Rating:  mu.Rating,
 			}
 		}
 		return users, nil
@@ -78,6 +81,60 @@ func GetLeaderboardWithMock(limit int) ([]CookieUser, error) {
 	return GetLeaderboard(limit)
 }
 
+// GetLeaderboardByRatingWithMock retrieves the leaderboard ranked by rating (mock or real)
+func GetLeaderboardByRatingWithMock(limit int) ([]CookieUser, error) {
+	if useMocks {
+		mockUsers, err := mocks.GetMockDynamoDB().GetTopUsersByRating(limit)
+		if err != nil {
+			return nil, err
+		}
+		users := make([]CookieUser, len(mockUsers))
+		for i, mu := range mockUsers {
+			users[i] = CookieUser{
+				UserID:  mu.UserID,
+				Email:   mu.Email,
+				Name:    mu.Name,
+				Picture: mu.Picture,
+				Score:   mu.Score,
+				Rating:  mu.Rating,
+			}
+		}
+		return users, nil
+	}
+	return GetLeaderboardByRating(limit)
+}
+
+// ListUsersWithMock returns all users (mock or real)
+func ListUsersWithMock() ([]CookieUser, error) {
+	if useMocks {
+		mockUsers, err := mocks.GetMockDynamoDB().ListUsers()
+		if err != nil {
+			return nil, err
+		}
+		users := make([]CookieUser, len(mockUsers))
+		for i, mu := range mockUsers {
+			users[i] = CookieUser{
+				UserID:  mu.UserID,
+				Email:   mu.Email,
+				Name:    mu.Name,
+				Picture: mu.Picture,
+				Score:   mu.Score,
+				Rating:  mu.Rating,
+			}
+		}
+		return users, nil
+	}
+	return ListUsers()
+}
+
+// UpdateUserRatingWithMock stores a user's new rating (mock or real)
+func UpdateUserRatingWithMock(userID string, rating int) error {
+	if useMocks {
+		return mocks.GetMockDynamoDB().SetUserRating(userID, rating)
+	}
+	return UpdateUserRating(userID, rating)
+}
+
 // UpdateUserStatsWithMock updates user stats (mock or real)
 func UpdateUserStatsWithMock(userID string, score int) error {
 	if useMocks {
@@ -103,40 +160,64 @@ func SaveGameWithMock(game CookieGame) error {
 			PlayerPicture:   game.PlayerPicture,
 			OpponentName:    game.OpponentName,
 			OpponentPicture: game.OpponentPicture,
+			Suspicious:      game.Suspicious,
+			Mode:            game.Mode,
+			Placement:       game.Placement,
+			PlayerCount:     game.PlayerCount,
+			Team:            game.Team,
+			TeamScore:       game.TeamScore,
+			RematchOf:       game.RematchOf,
+			Practice:        game.Practice,
 		}
 		return mocks.GetMockDynamoDB().SaveGame(mockGame)
 	}
 	return SaveGame(game)
 }
 
-// GetGameHistoryWithMock retrieves game history (mock or real)
-func GetGameHistoryWithMock(userID string, limit int32) ([]CookieGame, error) {
+// GetGameHistoryWithMock retrieves a page of a player's game history (mock or real)
+func GetGameHistoryWithMock(userID string, limit int32, cursor string) ([]CookieGame, string, error) {
+	if useMocks {
+		var after historyCursor
+		if cursor != "" {
+			var err error
+			if after, err = decodeHistoryCursor(userID, cursor); err != nil {
+				return nil, "", err
+			}
+		}
+		// Fetch one extra game to know whether there is a next page
+		mockGames, err := mocks.GetMockDynamoDB().GetGamesByPlayerAfter(userID, int(limit)+1, after.Timestamp, after.GameID)
+		if err != nil {
+			return nil, "", err
+		}
+		var next string
+		if len(mockGames) > int(limit) {
+			mockGames = mockGames[:limit]
+			last := mockGames[len(mockGames)-1]
+			next = encodeHistoryCursor(historyCursor{GameID: last.GameID, PlayerID: last.PlayerID, Timestamp: last.Timestamp})
+		}
+		games := make([]CookieGame, len(mockGames))
+		for i, mg := range mockGames {
+			games[i] = CookieGame(mg)
+		}
+		return games, next, nil
+	}
+	return GetGameHistory(userID, limit, cursor)
+}
+
+// ListGamesSinceWithMock returns the games of all players since the given Unix time (mock or real)
+func ListGamesSinceWithMock(since int64) ([]CookieGame, error) {
 	if useMocks {
-		mockGames, err := mocks.GetMockDynamoDB().GetGamesByPlayer(userID, int(limit))
+		mockGames, err := mocks.GetMockDynamoDB().ListGamesSince(since)
 		if err != nil {
 			return nil, err
 		}
 		games := make([]CookieGame, len(mockGames))
 		for i, mg := range mockGames {
-			games[i] = CookieGame{
-				GameID:          mg.GameID,
-				PlayerID:        mg.PlayerID,
-				Timestamp:       mg.Timestamp,
-				Score:           mg.Score,
-				OpponentScore:   mg.OpponentScore,
-				Reason:          mg.Reason,
-				Won:             mg.Won,
-				WinnerID:        mg.WinnerID,
-				Opponent:        mg.Opponent,
-				PlayerName:      mg.PlayerName,
-				PlayerPicture:   mg.PlayerPicture,
-				OpponentName:    mg.OpponentName,
-				OpponentPicture: mg.OpponentPicture,
-			}
+			games[i] = CookieGame(mg)
 		}
 		return games, nil
 	}
-	return GetGameHistory(userID, limit)
+	return ListGamesSince(since)
 }
 
 // CountGamesByPlayerWithMock returns the total number of games for a player (mock or real)
@@ -147,6 +228,276 @@ func CountGamesByPlayerWithMock(userID string) (int, error) {
 	return CountGamesByPlayer(userID)
 }
 
+// SaveReplayWithMock saves a game replay with its timeline chunks (mock or real)
+func SaveReplayWithMock(replay CookieReplay, chunks []CookieReplayChunk) error {
+	if useMocks {
+		mockChunks := make([]mocks.CookieReplayChunk, len(chunks))
+		for i, c := range chunks {
+			mockChunks[i] = mocks.CookieReplayChunk(c)
+		}
+		return mocks.GetMockDynamoDB().SaveReplay(mocks.CookieReplay{
+			GameID:    replay.GameID,
+			Player1ID: replay.Player1ID,
+			Player2ID: replay.Player2ID,
+			P1Name:    replay.P1Name,
+			P2Name:    replay.P2Name,
+			P1Picture: replay.P1Picture,
+			P2Picture: replay.P2Picture,
+			StartedAt: replay.StartedAt,
+			Players:   replay.Players,
+		}, mockChunks)
+	}
+	return SaveReplay(replay, chunks)
+}
+
+// GetReplayWithMock retrieves a game replay and its timeline chunks in order (mock
+// or real), nil if none was recorded
+func GetReplayWithMock(gameID string) (*CookieReplay, []CookieReplayChunk, error) {
+	if useMocks {
+		mockReplay, mockChunks, err := mocks.GetMockDynamoDB().GetReplay(gameID)
+		if err != nil || mockReplay == nil {
+			return nil, nil, err
+		}
+		chunks := make([]CookieReplayChunk, len(mockChunks))
+		for i, c := range mockChunks {
+			chunks[i] = CookieReplayChunk(c)
+		}
+		return &CookieReplay{
+			GameID:    mockReplay.GameID,
+			Player1ID: mockReplay.Player1ID,
+			Player2ID: mockReplay.Player2ID,
+			P1Name:    mockReplay.P1Name,
+			P2Name:    mockReplay.P2Name,
+			P1Picture: mockReplay.P1Picture,
+			P2Picture: mockReplay.P2Picture,
+			StartedAt: mockReplay.StartedAt,
+			Players:   mockReplay.Players,
+			Chunks:    mockReplay.Chunks,
+		}, chunks, nil
+	}
+	return GetReplay(gameID)
+}
+
+// CreateSeasonWithMock stores a new season (mock or real); false if it already exists
+func CreateSeasonWithMock(season CookieSeason) (bool, error) {
+	if useMocks {
+		return mocks.GetMockDynamoDB().CreateSeason(mocks.CookieSeason(season))
+	}
+	return CreateSeason(season)
+}
+
+// ListSeasonsWithMock returns all seasons, oldest first (mock or real)
+func ListSeasonsWithMock() ([]CookieSeason, error) {
+	if useMocks {
+		mockSeasons, err := mocks.GetMockDynamoDB().ListSeasons()
+		if err != nil {
+			return nil, err
+		}
+		seasons := make([]CookieSeason, len(mockSeasons))
+		for i, ms := range mockSeasons {
+			seasons[i] = CookieSeason(ms)
+		}
+		return seasons, nil
+	}
+	return ListSeasons()
+}
+
+// UpdateSeasonStatsWithMock adds a game result to a player's season record (mock or real)
+func UpdateSeasonStatsWithMock(seasonID int, userID, name, picture string, score int, won bool, rating int) error {
+	if useMocks {
+		return mocks.GetMockDynamoDB().UpdateSeasonStats(seasonID, userID, name, picture, score, won, rating)
+	}
+	return UpdateSeasonStats(seasonID, userID, name, picture, score, won, rating)
+}
+
+// GetSeasonStatsWithMock returns all player records of a season by score (mock or real)
+func GetSeasonStatsWithMock(seasonID int) ([]CookieSeasonStats, error) {
+	if useMocks {
+		mockStats, err := mocks.GetMockDynamoDB().GetSeasonStats(seasonID)
+		if err != nil {
+			return nil, err
+		}
+		stats := make([]CookieSeasonStats, len(mockStats))
+		for i, ms := range mockStats {
+			stats[i] = CookieSeasonStats(ms)
+		}
+		return stats, nil
+	}
+	return GetSeasonStats(seasonID)
+}
+
+// ArchiveSeasonWithMock stores the final standings of a season (mock or real)
+func ArchiveSeasonWithMock(seasonID int, ranks map[string]int) error {
+	if useMocks {
+		return mocks.GetMockDynamoDB().ArchiveSeason(seasonID, ranks)
+	}
+	return ArchiveSeason(seasonID, ranks)
+}
+
+// UpdatePlayerStatsWithMock adds a finished game to a player's lifetime statistics (mock or real)
+func UpdatePlayerStatsWithMock(userID string, game PlayerGame) error {
+	if useMocks {
+		return mocks.GetMockDynamoDB().UpdatePlayerStats(userID, mocks.PlayerGame(game))
+	}
+	return UpdatePlayerStats(userID, game)
+}
+
+// GetPlayerStatsWithMock returns a player's lifetime statistics (mock or real)
+func GetPlayerStatsWithMock(userID string) (*CookiePlayerStats, error) {
+	if useMocks {
+		mockStats, err := mocks.GetMockDynamoDB().GetPlayerStats(userID)
+		if err != nil || mockStats == nil {
+			return nil, err
+		}
+		stats := CookiePlayerStats(*mockStats)
+		return &stats, nil
+	}
+	return GetPlayerStats(userID)
+}
+
+// UpdateHeadToHeadWithMock adds a finished duel to the head-to-head record of two players (mock or real)
+func UpdateHeadToHeadWithMock(userA, userB string, scoreA, scoreB int, winnerID string, timestamp int64) error {
+	if useMocks {
+		return mocks.GetMockDynamoDB().UpdateHeadToHead(userA, userB, scoreA, scoreB, winnerID, timestamp)
+	}
+	return UpdateHeadToHead(userA, userB, scoreA, scoreB, winnerID, timestamp)
+}
+
+// GetHeadToHeadWithMock returns the head-to-head record of two players (mock or real)
+func GetHeadToHeadWithMock(userA, userB string) (*CookieHeadToHead, error) {
+	if useMocks {
+		mockRecord, err := mocks.GetMockDynamoDB().GetHeadToHead(userA, userB)
+		if err != nil || mockRecord == nil {
+			return nil, err
+		}
+		record := CookieHeadToHead(*mockRecord)
+		return &record, nil
+	}
+	return GetHeadToHead(userA, userB)
+}
+
+// UnlockAchievementWithMock stores an unlocked achievement, false if it already was (mock or real)
+func UnlockAchievementWithMock(achievement CookieAchievement) (bool, error) {
+	if useMocks {
+		return mocks.GetMockDynamoDB().UnlockAchievement(mocks.CookieAchievement(achievement))
+	}
+	return UnlockAchievement(achievement)
+}
+
+// GetAchievementsWithMock returns all achievements a player has unlocked (mock or real)
+func GetAchievementsWithMock(userID string) ([]CookieAchievement, error) {
+	if useMocks {
+		mockAchievements, err := mocks.GetMockDynamoDB().GetAchievements(userID)
+		if err != nil {
+			return nil, err
+		}
+		achievements := make([]CookieAchievement, len(mockAchievements))
+		for i, a := range mockAchievements {
+			achievements[i] = CookieAchievement(a)
+		}
+		return achievements, nil
+	}
+	return GetAchievements(userID)
+}
+
+// AddFriendRequestWithMock stores a friend request, false if the players are already linked (mock or real)
+func AddFriendRequestWithMock(fromID, toID string, timestamp int64) (bool, error) {
+	if useMocks {
+		return mocks.GetMockDynamoDB().AddFriendRequest(fromID, toID, timestamp)
+	}
+	return AddFriendRequest(fromID, toID, timestamp)
+}
+
+// AcceptFriendRequestWithMock accepts a pending friend request, false if there is none (mock or real)
+func AcceptFriendRequestWithMock(userID, fromID string, timestamp int64) (bool, error) {
+	if useMocks {
+		return mocks.GetMockDynamoDB().AcceptFriendRequest(userID, fromID, timestamp)
+	}
+	return AcceptFriendRequest(userID, fromID, timestamp)
+}
+
+// RemoveFriendWithMock deletes a friendship or pending request (mock or real)
+func RemoveFriendWithMock(userID, friendID string) error {
+	if useMocks {
+		return mocks.GetMockDynamoDB().RemoveFriend(userID, friendID)
+	}
+	return RemoveFriend(userID, friendID)
+}
+
+// ListFriendsWithMock returns all friendships and pending requests of a player (mock or real)
+func ListFriendsWithMock(userID string) ([]CookieFriend, error) {
+	if useMocks {
+		mockFriends, err := mocks.GetMockDynamoDB().ListFriends(userID)
+		if err != nil {
+			return nil, err
+		}
+		friends := make([]CookieFriend, len(mockFriends))
+		for i, f := range mockFriends {
+			friends[i] = CookieFriend(f)
+		}
+		return friends, nil
+	}
+	return ListFriends(userID)
+}
+
+// SaveSeriesWithMock stores the record of a finished series (mock or real)
+func SaveSeriesWithMock(series CookieSeries) error {
+	if useMocks {
+		return mocks.GetMockDynamoDB().SaveSeries(mocks.CookieSeries(series))
+	}
+	return SaveSeries(series)
+}
+
+// GetSeriesWithMock retrieves a series record (mock or real), nil if none exists
+func GetSeriesWithMock(seriesID string) (*CookieSeries, error) {
+	if useMocks {
+		mockSeries, err := mocks.GetMockDynamoDB().GetSeries(seriesID)
+		if err != nil || mockSeries == nil {
+			return nil, err
+		}
+		series := CookieSeries(*mockSeries)
+		return &series, nil
+	}
+	return GetSeries(seriesID)
+}
+
+// SaveTournamentWithMock stores a tournament (mock or real); false on a version conflict
+func SaveTournamentWithMock(tournament CookieTournament) (bool, error) {
+	if useMocks {
+		return mocks.GetMockDynamoDB().SaveTournament(mocks.CookieTournament(tournament))
+	}
+	return SaveTournament(tournament)
+}
+
+// GetTournamentWithMock retrieves a tournament (mock or real), nil if none exists
+func GetTournamentWithMock(tournamentID string) (*CookieTournament, error) {
+	if useMocks {
+		mockTournament, err := mocks.GetMockDynamoDB().GetTournament(tournamentID)
+		if err != nil || mockTournament == nil {
+			return nil, err
+		}
+		tournament := CookieTournament(*mockTournament)
+		return &tournament, nil
+	}
+	return GetTournament(tournamentID)
+}
+
+// ListTournamentsWithMock returns all tournaments, newest first (mock or real)
+func ListTournamentsWithMock() ([]CookieTournament, error) {
+	if useMocks {
+		mockTournaments, err := mocks.GetMockDynamoDB().ListTournaments()
+		if err != nil {
+			return nil, err
+		}
+		tournaments := make([]CookieTournament, len(mockTournaments))
+		for i, mt := range mockTournaments {
+			tournaments[i] = CookieTournament(mt)
+		}
+		return tournaments, nil
+	}
+	return ListTournaments()
+}
+
 // IsMockMode returns whether mock mode is enabled
 func IsMockMode() bool {
 	return useMocks
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	ffaMinPlayers = 3
+	ffaMaxPlayers = 8
+)
+
+// Free-for-all matchmaking configuration (overridable via environment variables)
+var (
+	ffaRoomSize    = 6                // FFA_ROOM_SIZE: players a room is filled up to
+	ffaFillTimeout = 15 * time.Second // FFA_FILL_TIMEOUT: seconds to wait before starting with fewer players, must stay below queueTTL
+)
+
+// initFFAConfig loads the free-for-all matchmaking configuration from the environment
+func initFFAConfig() {
+	if v, err := strconv.Atoi(os.Getenv("FFA_ROOM_SIZE")); err == nil {
+		ffaRoomSize = min(max(v, ffaMinPlayers), ffaMaxPlayers)
+	}
+	if v, err := strconv.Atoi(os.Getenv("FFA_FILL_TIMEOUT")); err == nil && v > 0 {
+		ffaFillTimeout = min(time.Duration(v)*time.Second, queueTTL-5*time.Second)
+	}
+	log.Printf("[FFA] Rooms of %d players, starting with at least %d after %s", ffaRoomSize, ffaMinPlayers, ffaFillTimeout)
+}
+
+// selectFFAGroup picks the queue entries (players or parties) for a free-for-all room.
+// Entries must be ordered by JoinedAt (oldest first). A room starts as soon as it is
+// full, or with everyone who fits once the oldest player has waited for ffaFillTimeout.
+func selectFFAGroup(entries []QueueEntry, now int64) []int {
+	// A player may only take one seat
+	var picked []int
+	players := 0
+	seen := make(map[string]bool)
+	for i, entry := range entries {
+		if seen[entry.UserID] || players+entry.unitSize() > ffaRoomSize {
+			continue
+		}
+		seen[entry.UserID] = true
+		picked = append(picked, i)
+		players += entry.unitSize()
+		if players == ffaRoomSize {
+			return picked
+		}
+	}
+
+	if players < ffaMinPlayers {
+		return nil
+	}
+	if now-entries[picked[0]].JoinedAt < int64(ffaFillTimeout.Seconds()) {
+		return nil
+	}
+	return picked
+}
+
+// TryFFAMatchmaking attempts to fill a free-for-all room from the queue.
+// Returns the seated players if a room can start, nil otherwise
+func TryFFAMatchmaking() ([]QueueEntry, error) {
+	units, err := matchFromQueue(ModeFFA, selectFFAGroup)
+	if err != nil || units == nil {
+		return nil, err
+	}
+
+	groups, err := expandUnits(units)
+	if err != nil {
+		return nil, err
+	}
+	matched := flattenUnits(groups)
+
+	log.Printf("Matched %d players for a free-for-all room", len(matched))
+	return matched, nil
+}
+
+// handleLocalFFAJoin adds a client to the in-memory free-for-all queue (single-pod
+// mode) and starts a room once it is full or the fill timeout has passed.
+// Must be called with gm.mutex held.
+func (gm *GameManager) handleLocalFFAJoin(client *Client) {
+	for _, waiting := range gm.ffaWaiting {
+		if waiting == client {
+			return
+		}
+	}
+	gm.ffaWaiting = append(gm.ffaWaiting, client)
+
+	if len(gm.ffaWaiting) >= ffaRoomSize {
+		gm.startLocalFFA()
+		return
+	}
+	gm.scheduleLocalFFAStart()
+}
+
+// scheduleLocalFFAStart arms the fill timeout of the in-memory free-for-all queue
+// unless it is running already. Once it expires the waiting players start if there
+// are enough of them; otherwise it is armed again while anyone is still waiting.
+// Must be called with gm.mutex held.
+func (gm *GameManager) scheduleLocalFFAStart() {
+	if gm.ffaTimer != nil {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(ffaFillTimeout, func() {
+		gm.mutex.Lock()
+		defer gm.mutex.Unlock()
+		if gm.ffaTimer != timer {
+			return // Stopped in favour of a newer timer
+		}
+		gm.ffaTimer = nil
+
+		if len(gm.ffaWaiting) >= ffaMinPlayers {
+			gm.startLocalFFA()
+		} else if len(gm.ffaWaiting) > 0 {
+			gm.scheduleLocalFFAStart()
+		}
+	})
+	gm.ffaTimer = timer
+}
+
+// startLocalFFA starts an in-memory room with the waiting free-for-all players.
+// Must be called with gm.mutex held.
+func (gm *GameManager) startLocalFFA() {
+	// The players left behind get a fill timeout of their own
+	if gm.ffaTimer != nil {
+		gm.ffaTimer.Stop()
+		gm.ffaTimer = nil
+	}
+
+	players := gm.ffaWaiting
+	if len(players) > ffaRoomSize {
+		players = players[:ffaRoomSize]
+	}
+	gm.ffaWaiting = append([]*Client(nil), gm.ffaWaiting[len(players):]...)
+	gm.StartGame(ModeFFA, players...)
+
+	if len(gm.ffaWaiting) > 0 {
+		gm.scheduleLocalFFAStart()
+	}
+}
+
+// eliminateDistributedPlayer removes a player from a distributed free-for-all. The
+// timer pod ends the match once fewer than two players are left.
+func (gm *GameManager) eliminateDistributedPlayer(roomID, userID, reason string) {
+	state, err := UpdateGameState(roomID, func(state *DistributedGameState) error {
+		if _, out := state.Eliminated[userID]; out || state.GameEnded {
+			return errGameNotFound
+		}
+		state.Eliminate(userID, reason)
+		delete(state.DisconnectedAt, userID)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	log.Printf("Player %s left free-for-all room %s (%s)", userID, roomID, reason)
+	announceElimination(state, userID, reason)
+}
+
+// announceElimination releases an eliminated player and tells all pods about it
+func announceElimination(state *DistributedGameState, userID, reason string) {
+	ClearPlayerRoom(userID, state.RoomID)
+
+	PublishGameEvent(GameEvent{
+		RoomID:    state.RoomID,
+		EventType: EventPlayerEliminated,
+		PlayerID:  userID,
+		Data: map[string]interface{}{
+			"reason":    reason,
+			"remaining": len(state.Active()),
+			"scores":    state.ScoresCopy(),
+		},
+	})
+}
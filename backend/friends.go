@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+)
+
+// Friend is a friendship or pending friend request of the caller
+type Friend struct {
+	GamePlayer
+	Since  int64  `json:"since"`            // Unix seconds of the request, or of the acceptance
+	Status string `json:"status,omitempty"` // Presence, accepted friends only
+}
+
+// FriendList is the response of GET /api/friends
+type FriendList struct {
+	Friends  []Friend `json:"friends"`
+	Incoming []Friend `json:"incoming"` // Requests waiting for the caller's answer
+	Outgoing []Friend `json:"outgoing"` // Requests the caller sent
+}
+
+// friendPlayer returns the public identity of a user, just the ID if they cannot be loaded
+func friendPlayer(userID string) GamePlayer {
+	user, err := db.GetUserWithMock(userID)
+	if err != nil || user == nil {
+		return GamePlayer{UserID: userID}
+	}
+	return GamePlayer{UserID: user.UserID, Name: user.Name, Picture: user.Picture}
+}
+
+// findFriend returns the caller's side of their link with another user, nil if there is none
+func findFriend(userID, otherID string) (*db.CookieFriend, error) {
+	friends, err := db.ListFriendsWithMock(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range friends {
+		if f.FriendID == otherID {
+			return &f, nil
+		}
+	}
+	return nil, nil
+}
+
+// handleFriends lists the caller's friends with their presence and pending requests
+func (gm *GameManager) handleFriends(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := requestClaims(r).UserID
+	stored, err := db.ListFriendsWithMock(userID)
+	if err != nil {
+		log.Printf("[API] Error fetching friends of %s: %v", userID, err)
+		http.Error(w, "Failed to fetch friends", http.StatusInternalServerError)
+		return
+	}
+
+	var accepted []string
+	for _, f := range stored {
+		if f.Status == db.FriendAccepted {
+			accepted = append(accepted, f.FriendID)
+		}
+	}
+	presence := gm.presenceOf(accepted)
+
+	list := FriendList{Friends: []Friend{}, Incoming: []Friend{}, Outgoing: []Friend{}}
+	for _, f := range stored {
+		friend := Friend{GamePlayer: friendPlayer(f.FriendID), Since: f.Since}
+		switch f.Status {
+		case db.FriendAccepted:
+			friend.Status = presence[f.FriendID]
+			list.Friends = append(list.Friends, friend)
+		case db.FriendIncoming:
+			list.Incoming = append(list.Incoming, friend)
+		case db.FriendOutgoing:
+			list.Outgoing = append(list.Outgoing, friend)
+		}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleFriendRequest sends a friend request to a user (POST). A request to a user
+// who already asked the caller accepts theirs.
+func (gm *GameManager) handleFriendRequest(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, friendID := requestClaims(r).UserID, r.PathValue("userId")
+	if friendID == userID {
+		http.Error(w, "Cannot befriend yourself", http.StatusBadRequest)
+		return
+	}
+	target, err := db.GetUserWithMock(friendID)
+	if err != nil {
+		log.Printf("[API] Error fetching user %s: %v", friendID, err)
+		http.Error(w, "Failed to send friend request", http.StatusInternalServerError)
+		return
+	}
+	if target == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	existing, err := findFriend(userID, friendID)
+	if err != nil {
+		log.Printf("[API] Error fetching friends of %s: %v", userID, err)
+		http.Error(w, "Failed to send friend request", http.StatusInternalServerError)
+		return
+	}
+	if existing != nil && existing.Status == db.FriendIncoming {
+		gm.acceptFriend(w, userID, friendID)
+		return
+	}
+
+	created, err := db.AddFriendRequestWithMock(userID, friendID, time.Now().Unix())
+	if err != nil {
+		http.Error(w, "Failed to send friend request", http.StatusInternalServerError)
+		return
+	}
+	if !created {
+		http.Error(w, "Already friends or request pending", http.StatusConflict)
+		return
+	}
+
+	gm.sendToUser(friendID, GameMessage{Type: MsgTypeFriendRequest, Payload: friendPlayer(userID)})
+	json.NewEncoder(w).Encode(map[string]string{"status": db.FriendOutgoing})
+}
+
+// handleFriendAccept accepts a pending friend request from a user (POST)
+func (gm *GameManager) handleFriendAccept(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	gm.acceptFriend(w, requestClaims(r).UserID, r.PathValue("userId"))
+}
+
+// acceptFriend accepts the friend request of fromID and tells both players about
+// each other's presence
+func (gm *GameManager) acceptFriend(w http.ResponseWriter, userID, fromID string) {
+	accepted, err := db.AcceptFriendRequestWithMock(userID, fromID, time.Now().Unix())
+	if err != nil {
+		http.Error(w, "Failed to accept friend request", http.StatusInternalServerError)
+		return
+	}
+	if !accepted {
+		http.Error(w, "No pending friend request", http.StatusNotFound)
+		return
+	}
+
+	presence := gm.presenceOf([]string{userID, fromID})
+	gm.sendToUser(fromID, GameMessage{
+		Type:    MsgTypeFriendAccepted,
+		Payload: Friend{GamePlayer: friendPlayer(userID), Status: presence[userID]},
+	})
+	json.NewEncoder(w).Encode(Friend{GamePlayer: friendPlayer(fromID), Status: presence[fromID]})
+}
+
+// handleFriendRemove removes a friend, or declines or withdraws a friend request (DELETE)
+func (gm *GameManager) handleFriendRemove(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, friendID := requestClaims(r).UserID, r.PathValue("userId")
+	existing, err := findFriend(userID, friendID)
+	if err != nil {
+		log.Printf("[API] Error fetching friends of %s: %v", userID, err)
+		http.Error(w, "Failed to remove friend", http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "Not friends", http.StatusNotFound)
+		return
+	}
+	if err := db.RemoveFriendWithMock(userID, friendID); err != nil {
+		http.Error(w, "Failed to remove friend", http.StatusInternalServerError)
+		return
+	}
+
+	gm.sendToUser(friendID, GameMessage{Type: MsgTypeFriendRemoved, Payload: map[string]string{"userId": userID}})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
@@ -40,9 +43,74 @@ func toFloat64(v interface{}) float64 {
 	}
 }
 
+// toScores converts a scoreboard from event data, which is a map[string]int when
+// passed in-memory and a map[string]interface{} after a JSON round trip
+func toScores(v interface{}) map[string]int {
+	switch val := v.(type) {
+	case map[string]int:
+		return val
+	case map[string]interface{}:
+		scores := make(map[string]int, len(val))
+		for id, score := range val {
+			scores[id] = toInt(score)
+		}
+		return scores
+	default:
+		return nil
+	}
+}
+
+// toStrings converts a string list from event data, which is a []string when
+// passed in-memory and a []interface{} after a JSON round trip
+func toStrings(v interface{}) []string {
+	switch val := v.(type) {
+	case []string:
+		return val
+	case []interface{}:
+		strs := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		return strs
+	default:
+		return nil
+	}
+}
+
+// claimDistributedPowerUp atomically claims the active power-up of a room and
+// applies its effect. The returned effect is nil if nothing was claimable.
+func claimDistributedPowerUp(roomID, playerID string) (*DistributedGameState, *PowerUpEffect, error) {
+	var effect *PowerUpEffect
+	state, err := UpdateGameState(roomID, func(state *DistributedGameState) error {
+		effect = nil
+		if !state.GoldenCookieActive || state.GameEnded {
+			return nil
+		}
+		state.GoldenCookieActive = false
+
+		applied := state.Effects.Apply(state.PowerUpType, playerID, state.Allies(playerID), state.Targets(playerID), state.Scores, state.MatchRules(), time.Now())
+		state.CountGoldenCookie(playerID)
+		state.syncLegacyFields()
+		effect = &applied
+		return nil
+	})
+	return state, effect, err
+}
+
+// withTeamScores adds the team totals of a team match to a payload
+func withTeamScores(payload map[string]interface{}, teamScores map[string]int) map[string]interface{} {
+	if teamScores != nil {
+		payload["teamScores"] = teamScores
+	}
+	return payload
+}
+
 // Message Types
 const (
 	MsgTypeJoinQueue     = "JOIN_QUEUE"
+	MsgTypeJoinPractice  = "JOIN_PRACTICE" // Play against a server-side bot right away
 	MsgTypeGameStart     = "GAME_START"
 	MsgTypeClick         = "CLICK"
 	MsgTypeUpdate        = "UPDATE"
@@ -51,6 +119,56 @@ const (
 	MsgTypeOpponentClick = "OPPONENT_CLICK" // New message type for red +1
 	MsgTypeGameOver      = "GAME_OVER"
 	MsgTypeQuit          = "QUIT_GAME"
+	MsgTypeClickRejected = "CLICK_REJECTED" // Click dropped by the server (rate limit)
+
+	MsgTypeAchievementsUnlocked = "ACHIEVEMENTS_UNLOCKED" // Sent to a player right after GAME_OVER
+
+	MsgTypeFriendRequest  = "FRIEND_REQUEST"  // Someone sent the user a friend request
+	MsgTypeFriendAccepted = "FRIEND_ACCEPTED" // A friend request of the user was accepted
+	MsgTypeFriendRemoved  = "FRIEND_REMOVED"
+	MsgTypeFriendPresence = "FRIEND_PRESENCE" // A friend came online, queued, started or finished a match or went offline
+
+	MsgTypeOpponentDisconnected = "OPPONENT_DISCONNECTED" // Opponent dropped, reconnect window running
+	MsgTypeOpponentReconnected  = "OPPONENT_RECONNECTED"
+	MsgTypePlayerLeft           = "PLAYER_LEFT" // FFA player quit or dropped out, the match goes on
+
+	MsgTypeCreateLobby    = "CREATE_LOBBY" // Open a private lobby with an invite code
+	MsgTypeJoinLobby      = "JOIN_LOBBY"
+	MsgTypeCancelLobby    = "CANCEL_LOBBY"
+	MsgTypeLobbyCreated   = "LOBBY_CREATED"
+	MsgTypeLobbyCancelled = "LOBBY_CANCELLED"
+
+	MsgTypePartyInvite     = "PARTY_INVITE" // Leader invites a user, creating the party if needed
+	MsgTypePartyAccept     = "PARTY_ACCEPT"
+	MsgTypePartyDecline    = "PARTY_DECLINE"
+	MsgTypePartyLeave      = "PARTY_LEAVE"
+	MsgTypePartyInvitation = "PARTY_INVITATION" // Sent to the invited user
+	MsgTypePartyUpdate     = "PARTY_UPDATE"     // Party of the user, null once they are in none
+	MsgTypePartyQueued     = "PARTY_QUEUED"     // Sent to the members when the leader queues the party
+
+	MsgTypeChallenge         = "CHALLENGE" // Challenge an online player to a duel
+	MsgTypeChallengeAccept   = "CHALLENGE_ACCEPT"
+	MsgTypeChallengeDecline  = "CHALLENGE_DECLINE"
+	MsgTypeChallengeSent     = "CHALLENGE_SENT"     // Sent to the challenger
+	MsgTypeChallengeReceived = "CHALLENGE_RECEIVED" // Sent to the challenged user
+	MsgTypeChallengeDeclined = "CHALLENGE_DECLINED"
+	MsgTypeChallengeExpired  = "CHALLENGE_EXPIRED" // Sent to both when nobody answered in time
+
+	MsgTypeRequestRematch  = "REQUEST_REMATCH" // Vote for a rematch after GAME_OVER
+	MsgTypeDeclineRematch  = "DECLINE_REMATCH"
+	MsgTypeRematchVote     = "REMATCH_VOTE"     // A player voted for the rematch
+	MsgTypeRematchDeclined = "REMATCH_DECLINED" // Vote closed, a player declined or is gone
+	MsgTypeRematchExpired  = "REMATCH_EXPIRED"
+
+	MsgTypeSeriesUpdate = "SERIES_UPDATE" // A round of a series finished, the next one follows
+	MsgTypeSeriesOver   = "SERIES_OVER"
+
+	MsgTypeTournamentMatch = "TOURNAMENT_MATCH" // The player's next bracket match is ready
+	MsgTypeTournamentOver  = "TOURNAMENT_OVER"
+
+	MsgTypeSpectate      = "SPECTATE"       // Join a live room read-only
+	MsgTypeSpectateStart = "SPECTATE_START" // Snapshot sent to a new spectator
+	MsgTypeError         = "ERROR"
 )
 
 type GameMessage struct {
@@ -63,28 +181,48 @@ type ClickPayload struct {
 }
 
 type GameState struct {
-	TimeRemaining int    `json:"timeRemaining"`
-	P1Score       int    `json:"p1Score"`
-	P2Score       int    `json:"p2Score"`
-	P1Name        string `json:"p1Name"`
-	P2Name        string `json:"p2Name"`
-	P1Picture     string `json:"p1Picture"`
-	P2Picture     string `json:"p2Picture"`
+	TimeRemaining int            `json:"timeRemaining"`
+	P1Score       int            `json:"p1Score"`
+	P2Score       int            `json:"p2Score"`
+	P1Name        string         `json:"p1Name"`
+	P2Name        string         `json:"p2Name"`
+	P1Picture     string         `json:"p1Picture"`
+	P2Picture     string         `json:"p2Picture"`
+	Scores        map[string]int `json:"scores,omitempty"`     // UserID -> score, for all players
+	TeamScores    map[string]int `json:"teamScores,omitempty"` // Team ID -> team total, team matches only
+}
+
+// newGameState builds the UPDATE payload of a roster; p1/p2 mirror the first two seats
+func newGameState(r *Roster, timeRemaining int) GameState {
+	state := GameState{TimeRemaining: timeRemaining, Scores: r.ScoresCopy(), TeamScores: r.TeamScores()}
+	if len(r.Players) >= 2 {
+		p1, p2 := r.Players[0], r.Players[1]
+		state.P1Score, state.P2Score = r.Scores[p1.UserID], r.Scores[p2.UserID]
+		state.P1Name, state.P2Name = p1.Name, p2.Name
+		state.P1Picture, state.P2Picture = p1.Picture, p2.Picture
+	}
+	return state
 }
 
 type GameRoom struct {
-	ID        string
-	Player1   *Client
-	Player2   *Client
-	State     GameState
-	Broadcast chan []byte
-	Close     chan bool
+	Roster
+	ID            string
+	Clients       []*Client // Seat order; nil for the placeholder rooms of distributed games
+	Rules         GameRules
+	RematchOf     string // ID of the room this game is a rematch of
+	TimeRemaining int
+	Broadcast     chan []byte
+	Close         chan bool
+	ended         bool         // Results sent (or game aborted), nothing may change anymore
+	rematch       *RematchVote // Open rematch vote once the game has finished
 
 	// Game Logic
 	GoldenCookieActive bool
 	GoldenCookieX      float64
 	GoldenCookieY      float64
-	DoubleClickActive  map[string]time.Time // UserID -> Expiry
+	PowerUpType        string          // Type of the spawned golden cookie
+	Effects            PowerUpEffects  // Active power-up effects
+	Suspicious         map[string]bool // UserID -> flagged by anti-cheat
 	mutex              sync.Mutex
 }
 
@@ -94,8 +232,14 @@ type GameManager struct {
 	broadcast   chan []byte
 	register    chan *Client
 	unregister  chan *Client
-	waiting     *Client // Simple queue for 1v1 (in-memory fallback)
+	waiting     *Client     // Simple queue for 1v1 (in-memory fallback)
+	ffaWaiting  []*Client   // Free-for-all queue (in-memory fallback)
+	ffaTimer    *time.Timer // Fill timeout of the in-memory free-for-all queue
+	teamWaiting []*Client   // Team queue (in-memory fallback)
 	clientRooms map[*Client]*GameRoom
+	spectators  map[*Client]string   // Spectator -> RoomID
+	presence    map[string]string    // UserID -> presence last published for a local user
+	queuedAt    map[string]time.Time // UserID -> when a local user joined the Redis queue or the in-memory 1v1 queue
 	mutex       sync.Mutex
 }
 
@@ -107,6 +251,9 @@ func NewGameManager() *GameManager {
 		clients:     make(map[*Client]bool),
 		clientsByID: make(map[string]*Client),
 		clientRooms: make(map[*Client]*GameRoom),
+		spectators:  make(map[*Client]string),
+		presence:    make(map[string]string),
+		queuedAt:    make(map[string]time.Time),
 		waiting:     nil,
 	}
 }
@@ -120,55 +267,74 @@ func (gm *GameManager) Run() {
 			gm.clientsByID[client.userID] = client
 			gm.mutex.Unlock()
 			log.Printf("New client connected: %s", client.userID)
+			go gm.refreshPresence(client.userID)
+
+			// Re-attach the player to a distributed game still in progress
+			go gm.tryResumeGame(client)
 		case client := <-gm.unregister:
 			gm.mutex.Lock()
 			if _, ok := gm.clients[client]; ok {
+				// Only the latest connection of a user counts; an older socket closing
+				// after a reconnect must not affect the game
+				latest := gm.clientsByID[client.userID] == client
+
 				// Remove from Redis queue if using distributed matchmaking
-				if IsRedisAvailable() {
+				if latest && IsRedisAvailable() {
 					RemoveFromQueue(client.userID)
+					CancelLobby(client.userID)
+					// A queued party waits for everyone; the membership survives a reconnect
+					go dequeueParty(client.userID)
 				}
 
 				// Handle game disconnect if needs be
 				if room, ok := gm.clientRooms[client]; ok {
-					// Notify Valid Opponent
-					var opponent *Client
-					if room.Player1 == client {
-						opponent = room.Player2
+					if room.Clients == nil {
+						// Distributed game: keep the match alive for the reconnect window
+						delete(gm.clientRooms, client)
+						if latest {
+							go gm.handlePlayerDisconnect(client, room.ID)
+						}
+					} else if room.Mode == ModeFFA {
+						// The others keep playing
+						delete(gm.clientRooms, client)
+						room.Leave(client, "disconnected")
 					} else {
-						opponent = room.Player1
-					}
+						// Notify Valid Opponents
+						msg := GameMessage{
+							Type:    MsgTypeGameOver,
+							Payload: map[string]string{"winner": room.ForfeitWinner(client.userID), "reason": "opponent_disconnected"},
+						}
 
-					// Send Game Over (Opponent Disconnected)
-					msg := GameMessage{
-						Type:    MsgTypeGameOver,
-						Payload: map[string]string{"winner": opponent.userID, "reason": "opponent_disconnected"},
-					}
-					bytes, _ := json.Marshal(msg)
+						// Send Game Over (Opponent Disconnected); opponents might be blocked or dc'ed too
+						for _, opponent := range room.others(client) {
+							sendToClient(opponent, msg)
+						}
 
-					// Try to send to opponent
-					select {
-					case opponent.send <- bytes:
-					default:
-						// Opponent might be blocked or dc'ed too
-					}
+						// Close Room non-blocking
+						go func() {
+							select {
+							case room.Close <- true:
+							default:
+							}
+						}()
 
-					// Close Room non-blocking
-					go func() {
-						select {
-						case room.Close <- true:
-						default:
+						for _, player := range room.Clients {
+							delete(gm.clientRooms, player)
 						}
-					}()
-
-					delete(gm.clientRooms, room.Player1)
-					delete(gm.clientRooms, room.Player2)
+					}
 				}
+				delete(gm.spectators, client)
 				delete(gm.clients, client)
-				delete(gm.clientsByID, client.userID)
+				if latest {
+					delete(gm.clientsByID, client.userID)
+					go gm.refreshPresence(client.userID) // Offline, unless they reconnected already
+				}
 				close(client.send)
 				if gm.waiting == client {
 					gm.waiting = nil
 				}
+				gm.ffaWaiting = removeClient(gm.ffaWaiting, client)
+				gm.teamWaiting = removeClient(gm.teamWaiting, client)
 				log.Printf("Client disconnected: %s", client.userID)
 			}
 			gm.mutex.Unlock()
@@ -187,6 +353,110 @@ func (gm *GameManager) Run() {
 	}
 }
 
+// handlePlayerDisconnect starts the reconnect grace window for a player who dropped
+// out of a distributed game. The timer pod forfeits the game if the window expires.
+// A connection the player already replaced on another pod changes nothing.
+func (gm *GameManager) handlePlayerDisconnect(client *Client, roomID string) {
+	userID := client.userID
+	_, err := UpdateGameState(roomID, func(state *DistributedGameState) error {
+		if state.GameEnded {
+			return errGameNotFound
+		}
+		if owner, ok := state.Connections[userID]; ok && owner != client.connID {
+			return errStaleConnection
+		}
+		if state.DisconnectedAt == nil {
+			state.DisconnectedAt = make(map[string]int64)
+		}
+		state.DisconnectedAt[userID] = time.Now().UnixMilli()
+		return nil
+	})
+	if errors.Is(err, errStaleConnection) {
+		log.Printf("Old connection of %s to room %s closed, the player reconnected elsewhere", userID, roomID)
+	}
+	if err != nil {
+		return
+	}
+
+	log.Printf("Player %s disconnected from room %s, waiting %s for reconnect", userID, roomID, reconnectGrace)
+	PublishGameEvent(GameEvent{
+		RoomID:    roomID,
+		EventType: EventPlayerDisconnected,
+		PlayerID:  userID,
+		Data:      map[string]interface{}{"graceSeconds": reconnectGrace.Seconds()},
+	})
+}
+
+// ownConnection records the client's connection as the one playing its seat
+func (s *DistributedGameState) ownConnection(client *Client) {
+	if s.Connections == nil {
+		s.Connections = make(map[string]string)
+	}
+	s.Connections[client.userID] = client.connID
+}
+
+// claimSeat records the connection a player starts a distributed game with
+func claimSeat(client *Client, roomID string) {
+	_, err := UpdateGameState(roomID, func(state *DistributedGameState) error {
+		if state.GameEnded {
+			return errGameNotFound
+		}
+		if _, taken := state.Connections[client.userID]; taken {
+			return nil // Resumed from another connection already
+		}
+		state.ownConnection(client)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errGameNotFound) {
+		log.Printf("Failed to record the connection of %s in room %s: %v", client.userID, roomID, err)
+	}
+}
+
+// tryResumeGame re-attaches a freshly connected client to the distributed game it
+// was playing before its connection dropped and sends it a full state snapshot
+func (gm *GameManager) tryResumeGame(client *Client) {
+	if !IsRedisAvailable() {
+		return
+	}
+
+	roomID, err := GetPlayerRoom(client.userID)
+	if err != nil || roomID == "" {
+		return
+	}
+
+	_, err = UpdateGameState(roomID, func(state *DistributedGameState) error {
+		if _, out := state.Eliminated[client.userID]; out || state.GameEnded {
+			return errGameNotFound
+		}
+		delete(state.DisconnectedAt, client.userID)
+		state.ownConnection(client)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	gm.mutex.Lock()
+	if _, ok := gm.clients[client]; !ok {
+		// Connection already gone again
+		gm.mutex.Unlock()
+		return
+	}
+	gm.clientRooms[client] = &GameRoom{ID: roomID}
+	gm.mutex.Unlock()
+	go gm.refreshPresence(client.userID)
+
+	log.Printf("Player %s resumed game in room %s", client.userID, roomID)
+	gm.sendGameStart(client, roomID, true)
+
+	PublishGameEvent(GameEvent{
+		RoomID:    roomID,
+		EventType: EventPlayerReconnected,
+		PlayerID:  client.userID,
+		Data:      map[string]interface{}{},
+	})
+}
+
 func (gm *GameManager) handleMessage(client *Client, msg []byte) {
 	var genericMsg GameMessage
 	if err := json.Unmarshal(msg, &genericMsg); err != nil {
@@ -194,17 +464,146 @@ func (gm *GameManager) handleMessage(client *Client, msg []byte) {
 		return
 	}
 
+	gm.mutex.Lock()
+	_, spectating := gm.spectators[client]
+	gm.mutex.Unlock()
+
 	switch genericMsg.Type {
 	case MsgTypeJoinQueue:
-		gm.handleJoinQueue(client)
+		gm.handleJoinQueue(client, payloadString(genericMsg.Payload, "mode"))
+	case MsgTypeJoinPractice:
+		gm.handleJoinPractice(client, payloadString(genericMsg.Payload, "difficulty"))
+	case MsgTypeSpectate:
+		gm.handleSpectate(client, payloadString(genericMsg.Payload, "roomId"))
+	case MsgTypeCreateLobby:
+		gm.handleCreateLobby(client, payloadString(genericMsg.Payload, "preset"), payloadInt(genericMsg.Payload, "bestOf"))
+	case MsgTypeJoinLobby:
+		gm.handleJoinLobby(client, payloadString(genericMsg.Payload, "code"))
+	case MsgTypeCancelLobby:
+		gm.handleCancelLobby(client)
+	case MsgTypePartyInvite:
+		gm.handlePartyInvite(client, payloadString(genericMsg.Payload, "userId"))
+	case MsgTypePartyAccept:
+		gm.handlePartyAccept(client, payloadString(genericMsg.Payload, "partyId"))
+	case MsgTypePartyDecline:
+		gm.handlePartyDecline(client, payloadString(genericMsg.Payload, "partyId"))
+	case MsgTypePartyLeave:
+		gm.handlePartyLeave(client)
+	case MsgTypeChallenge:
+		gm.handleChallenge(client, payloadString(genericMsg.Payload, "userId"), payloadString(genericMsg.Payload, "preset"), payloadInt(genericMsg.Payload, "bestOf"))
+	case MsgTypeChallengeAccept:
+		gm.handleChallengeAccept(client, payloadString(genericMsg.Payload, "challengeId"))
+	case MsgTypeChallengeDecline:
+		gm.handleChallengeDecline(client, payloadString(genericMsg.Payload, "challengeId"))
+	case MsgTypeRequestRematch, MsgTypeDeclineRematch:
+		gm.handleRematchVote(client, genericMsg.Type == MsgTypeRequestRematch)
 	case MsgTypeClick, MsgTypeCookieClick, MsgTypeQuit:
-		// Use distributed game handling if Redis is available
-		if IsRedisAvailable() {
-			gm.handleDistributedGameMessage(client, genericMsg)
-		} else if room, ok := gm.clientRooms[client]; ok {
+		if spectating {
+			// Spectators are read-only; QUIT_GAME just leaves the room
+			if genericMsg.Type == MsgTypeQuit {
+				gm.stopSpectating(client)
+			}
+			return
+		}
+
+		if genericMsg.Type == MsgTypeClick && !gm.allowClick(client) {
+			return
+		}
+
+		// In-memory rooms (practice games, or any game in single-pod mode) are played
+		// on this pod; everything else is a distributed game if Redis is available
+		gm.mutex.Lock()
+		room := gm.clientRooms[client]
+		gm.mutex.Unlock()
+		if room != nil && room.Clients != nil {
 			room.HandleGameMessage(client, genericMsg)
+		} else if IsRedisAvailable() {
+			gm.handleDistributedGameMessage(client, genericMsg)
+		}
+	}
+}
+
+// allowClick enforces the per-client click rate limit. Rejected clicks are reported
+// back to the client, and sustained superhuman rates flag the player for the current game.
+func (gm *GameManager) allowClick(client *Client) bool {
+	allowed, retryAfter, flagged := client.limiter.Allow()
+	if flagged {
+		gm.flagSuspicious(client)
+	}
+
+	if !allowed {
+		sendToClient(client, GameMessage{
+			Type: MsgTypeClickRejected,
+			Payload: map[string]interface{}{
+				"reason":       "rate_limited",
+				"retryAfterMs": retryAfter.Milliseconds(),
+			},
+		})
+	}
+	return allowed
+}
+
+// flagSuspicious marks the client as suspicious in its current game so the flag
+// is persisted with the game record
+func (gm *GameManager) flagSuspicious(client *Client) {
+	gm.mutex.Lock()
+	room, ok := gm.clientRooms[client]
+	gm.mutex.Unlock()
+
+	if !ok || room == nil {
+		return
+	}
+
+	log.Printf("[ANTICHEAT] Flagging %s as suspicious in room %s (sustained >= %d CPS)", client.userID, room.ID, suspiciousCPS)
+
+	// Distributed games keep the flag in their Redis state
+	if room.Clients == nil {
+		_, err := UpdateGameState(room.ID, func(state *DistributedGameState) error {
+			if state.SuspiciousPlayers == nil {
+				state.SuspiciousPlayers = make(map[string]bool)
+			}
+			state.SuspiciousPlayers[client.userID] = true
+			return nil
+		})
+		if err != nil {
+			log.Printf("[ANTICHEAT] Failed to flag %s in room %s: %v", client.userID, room.ID, err)
+		}
+		return
+	}
+
+	room.mutex.Lock()
+	room.Suspicious[client.userID] = true
+	room.mutex.Unlock()
+}
+
+// rejectFrozenClick tells a player that their click was ignored because they are frozen
+func rejectFrozenClick(client *Client, remaining time.Duration) {
+	sendToClient(client, GameMessage{
+		Type: MsgTypeClickRejected,
+		Payload: map[string]interface{}{
+			"reason":       "frozen",
+			"retryAfterMs": remaining.Milliseconds(),
+		},
+	})
+}
+
+// sendToClient marshals a message and pushes it to the client without blocking
+func sendToClient(client *Client, msg GameMessage) {
+	bytes, _ := json.Marshal(msg)
+	select {
+	case client.send <- bytes:
+	default:
+	}
+}
+
+// removeClient drops a client from an in-memory queue
+func removeClient(queue []*Client, client *Client) []*Client {
+	for i, waiting := range queue {
+		if waiting == client {
+			return append(queue[:i], queue[i+1:]...)
 		}
 	}
+	return queue
 }
 
 // handleDistributedGameMessage handles game messages via Redis
@@ -222,16 +621,17 @@ func (gm *GameManager) handleDistributedGameMessage(client *Client, msg GameMess
 
 	switch msg.Type {
 	case MsgTypeClick:
-		// Get current state to check for double-click powerup
+		// Get current state to check for power-up effects
 		state, err := GetGameState(roomID)
 		if err != nil {
 			log.Printf("Failed to get game state: %v", err)
 			return
 		}
 
-		points := 1
-		if expiry, ok := state.DoubleClickExpiry[client.userID]; ok && time.Now().Unix() < expiry {
-			points = 2
+		points := state.Effects.ClickPoints(client.userID, time.Now())
+		if points == 0 {
+			rejectFrozenClick(client, state.Effects.FrozenFor(client.userID, time.Now()))
+			return
 		}
 
 		// Atomically update score in Redis
@@ -246,33 +646,34 @@ func (gm *GameManager) handleDistributedGameMessage(client *Client, msg GameMess
 			RoomID:    roomID,
 			EventType: EventClick,
 			PlayerID:  client.userID,
-			Data: map[string]interface{}{
+			Data: withTeamScores(map[string]interface{}{
 				"points":  float64(points),
 				"p1Score": float64(updatedState.P1Score),
 				"p2Score": float64(updatedState.P2Score),
-			},
+				"scores":  updatedState.ScoresCopy(),
+			}, updatedState.TeamScores()),
 		}
 		PublishGameEvent(event)
 
 	case MsgTypeCookieClick:
-		// Try to atomically claim the golden cookie
-		claimed, err := AtomicClaimGoldenCookie(roomID, client.userID)
+		// Try to atomically claim the golden cookie and apply its power-up
+		state, effect, err := claimDistributedPowerUp(roomID, client.userID)
 		if err != nil {
 			log.Printf("Failed to claim golden cookie: %v", err)
 			return
 		}
 
-		if claimed {
-			state, _ := GetGameState(roomID)
+		if effect != nil {
+			data := effect.eventData()
+			data["p1Score"] = float64(state.P1Score)
+			data["p2Score"] = float64(state.P2Score)
+			data["scores"] = state.ScoresCopy()
+			withTeamScores(data, state.TeamScores())
 			event := GameEvent{
 				RoomID:    roomID,
 				EventType: EventGoldenClaim,
 				PlayerID:  client.userID,
-				Data: map[string]interface{}{
-					"claimedBy": client.userID,
-					"p1Score":   float64(state.P1Score),
-					"p2Score":   float64(state.P2Score),
-				},
+				Data:      data,
 			}
 			PublishGameEvent(event)
 		}
@@ -285,15 +686,25 @@ func (gm *GameManager) handleDistributedGameMessage(client *Client, msg GameMess
 			return
 		}
 
-		// Determine winner (the other player)
-		winnerID := state.Player1ID
-		if client.userID == state.Player1ID {
-			winnerID = state.Player2ID
+		if state.Mode == ModeFFA {
+			// Leaving a free-for-all only eliminates the player
+			gm.eliminateDistributedPlayer(roomID, client.userID, "quit")
+			return
 		}
 
-		state.GameEnded = true
-		state.WinnerID = winnerID
-		SaveGameState(state)
+		// The other player, or the other team, wins unless the game is over already
+		state, err = UpdateGameState(roomID, func(state *DistributedGameState) error {
+			if state.GameEnded {
+				return errGameNotFound
+			}
+			state.GameEnded = true
+			state.WinnerID = state.ForfeitWinner(client.userID)
+			return nil
+		})
+		if err != nil {
+			return
+		}
+		winnerID := state.WinnerID
 
 		// Publish quit event
 		event := GameEvent{
@@ -303,8 +714,21 @@ func (gm *GameManager) handleDistributedGameMessage(client *Client, msg GameMess
 			Data:      map[string]interface{}{"winner": winnerID, "reason": "quit"},
 		}
 		PublishGameEvent(event)
+		go updateRatings(forfeitResults(&state.Roster, client.userID)) // The quitter loses, whatever the score
+		go saveReplay(state)
+
+		// Quitting a round gives up the whole series, quitting a bracket match the match
+		if state.SeriesID != "" {
+			go gm.forfeitSeries(state.SeriesID, client.userID)
+		}
+		if state.TournamentID != "" {
+			go gm.finishTournamentMatch(state.TournamentID, state.TournamentMatch, roomID, winnerID, "forfeit")
+		}
 
 		// Clean up
+		for _, userID := range state.IDs() {
+			ClearPlayerRoom(userID, roomID)
+		}
 		go func() {
 			time.Sleep(5 * time.Second)
 			DeleteGameState(roomID)
@@ -312,30 +736,76 @@ func (gm *GameManager) handleDistributedGameMessage(client *Client, msg GameMess
 	}
 }
 
-func (gm *GameManager) handleJoinQueue(client *Client) {
-	log.Printf("Client %s joined queue", client.userID)
+func (gm *GameManager) handleJoinQueue(client *Client, mode string) {
+	if mode != ModeFFA && mode != ModeTeam {
+		mode = ModeDuel
+	}
+	log.Printf("Client %s joined %s queue", client.userID, mode)
+
+	// A spectator looking for their own game stops watching
+	gm.stopSpectating(client)
 
 	// Use Redis for distributed matchmaking if available
 	if IsRedisAvailable() {
-		err := AddToQueue(client)
+		// A host queueing for a public match gives up their lobby
+		if !gm.leaveOwnLobby(client) {
+			return
+		}
+
+		entry := queueEntryFor(client)
+		entry.Rating = lookupRating(client.userID)
+		entry.Mode = mode
+
+		// A party queues as one entry of its leader
+		party, err := GetPartyOf(client.userID)
 		if err != nil {
-			log.Printf("Failed to add to Redis queue: %v, using in-memory fallback", err)
-			// Fall through to in-memory matchmaking
-		} else {
+			log.Printf("Failed to look up party of %s: %v", client.userID, err)
+		}
+		if party != nil && len(party.Members) > 1 && !gm.preparePartyEntry(client, party, &entry) {
+			return
+		}
+
+		err = AddToQueue(entry)
+		if err == nil {
+			if entry.PartyID != "" {
+				gm.notifyPartyQueued(party, mode)
+			}
+			queuedAt := time.Now()
+			gm.mutex.Lock()
+			gm.queuedAt[client.userID] = queuedAt
+			gm.mutex.Unlock()
+			gm.refreshPresence(client.userID)
+			if mode == ModeDuel && entry.PartyID == "" {
+				gm.schedulePracticeFallback(client, queuedAt)
+			}
 			return // Redis will handle matchmaking via RunMatchmakingLoop
 		}
+		if entry.PartyID != "" {
+			log.Printf("Failed to add party %s to Redis queue: %v", party.ID, err)
+			sendError(client, "queue_error", "Could not join the queue")
+			return
+		}
+		log.Printf("Failed to add to Redis queue: %v, using in-memory fallback", err)
+		// Fall through to in-memory matchmaking
 	}
 
 	// In-memory fallback for single-pod mode
+	defer gm.refreshPresence(client.userID) // Runs once the lock below is released
 	gm.mutex.Lock()
 	defer gm.mutex.Unlock()
-	if gm.waiting != nil && gm.waiting != client {
+	if mode == ModeFFA {
+		gm.handleLocalFFAJoin(client)
+	} else if mode == ModeTeam {
+		gm.handleLocalTeamJoin(client)
+	} else if gm.waiting != nil && gm.waiting != client {
 		// Found a match!
 		opponent := gm.waiting
 		gm.waiting = nil
-		gm.StartGame(opponent, client)
+		gm.StartGame(ModeDuel, opponent, client)
 	} else {
 		gm.waiting = client
+		gm.queuedAt[client.userID] = time.Now()
+		gm.schedulePracticeFallback(client, gm.queuedAt[client.userID])
 	}
 }
 
@@ -345,34 +815,55 @@ func (gm *GameManager) RunMatchmakingLoop() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		player1, player2, err := TryMatchmaking()
+		players, err := TryMatchmaking()
 		if err != nil {
 			log.Printf("Matchmaking error: %v", err)
-			continue
+		} else if players != nil {
+			// Found a match! Create room and notify both pods
+			gm.hostMatch(ModeDuel, players)
 		}
 
-		if player1 != nil && player2 != nil {
-			// Found a match! Create room and notify both pods
-			roomID := fmt.Sprintf("%s_%s_%d", player1.UserID, player2.UserID, time.Now().Unix())
+		group, err := TryFFAMatchmaking()
+		if err != nil {
+			log.Printf("FFA matchmaking error: %v", err)
+		} else if group != nil {
+			gm.hostMatch(ModeFFA, group)
+		}
 
-			// Create distributed game state in Redis
-			if err := CreateDistributedGame(roomID, player1, player2); err != nil {
-				log.Printf("Failed to create distributed game: %v", err)
-				continue
-			}
+		teams, err := TryTeamMatchmaking()
+		if err != nil {
+			log.Printf("Team matchmaking error: %v", err)
+		} else if teams != nil {
+			gm.hostMatch(ModeTeam, teams)
+		}
+	}
+}
 
-			match := MatchNotification{
-				Player1ID: player1.UserID,
-				Player2ID: player2.UserID,
-				RoomID:    roomID,
-				HostPodID: GetPodID(),
-			}
+// newRoomID derives a room ID from the seated players
+func newRoomID(mode string, userIDs []string) string {
+	if mode == ModeDuel {
+		return fmt.Sprintf("%s_%s_%d", userIDs[0], userIDs[1], time.Now().Unix())
+	}
+	return fmt.Sprintf("%s_%s_%d", mode, userIDs[0], time.Now().UnixNano())
+}
 
-			// Publish match notification to all pods
-			if err := PublishMatchNotification(match); err != nil {
-				log.Printf("Failed to publish match notification: %v", err)
-			}
-		}
+// hostMatch creates a distributed game for matched queue entries and notifies all pods
+func (gm *GameManager) hostMatch(mode string, entries []QueueEntry) {
+	userIDs := make([]string, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+	}
+	roomID := newRoomID(mode, userIDs)
+
+	// Create distributed game state in Redis
+	if err := CreateDistributedGame(roomID, mode, entries, matchmakingRules()); err != nil {
+		log.Printf("Failed to create distributed game: %v", err)
+		return
+	}
+
+	// Publish match notification to all pods
+	if err := PublishMatchNotification(newMatchNotification(roomID, mode, userIDs)); err != nil {
+		log.Printf("Failed to publish match notification: %v", err)
 	}
 }
 
@@ -385,34 +876,29 @@ func (gm *GameManager) SubscribeToMatchNotifications() {
 
 // handleMatchNotification handles a match found by any pod
 func (gm *GameManager) handleMatchNotification(match MatchNotification) {
-	log.Printf("Received match notification: %s vs %s (host: %s)", match.Player1ID, match.Player2ID, match.HostPodID)
-
-	gm.mutex.Lock()
-	p1, hasP1 := gm.clientsByID[match.Player1ID]
-	p2, hasP2 := gm.clientsByID[match.Player2ID]
-	gm.mutex.Unlock()
-
-	// With distributed games, we don't need both players on the same pod
-	// Each pod notifies its local player about the game start
-
-	if hasP1 {
-		log.Printf("Notifying local player %s about game start", match.Player1ID)
-		gm.sendGameStart(p1, match.Player2ID, "p1", match.RoomID)
+	playerIDs := match.Players()
+	log.Printf("Received match notification: %s (host: %s)", strings.Join(playerIDs, " vs "), match.HostPodID)
 
-		// Track which room this client is in
+	// With distributed games, we don't need all players on the same pod
+	// Each pod notifies its local players about the game start
+	for _, userID := range playerIDs {
 		gm.mutex.Lock()
-		gm.clientRooms[p1] = &GameRoom{ID: match.RoomID}
+		client, ok := gm.clientsByID[userID]
 		gm.mutex.Unlock()
-	}
+		if !ok {
+			continue
+		}
 
-	if hasP2 {
-		log.Printf("Notifying local player %s about game start", match.Player2ID)
-		gm.sendGameStart(p2, match.Player1ID, "p2", match.RoomID)
+		log.Printf("Notifying local player %s about game start", userID)
+		gm.sendGameStart(client, match.RoomID, false)
 
-		// Track which room this client is in
+		// Track which room this client is in; a player no longer watches other games
 		gm.mutex.Lock()
-		gm.clientRooms[p2] = &GameRoom{ID: match.RoomID}
+		gm.clientRooms[client] = &GameRoom{ID: match.RoomID}
+		delete(gm.spectators, client)
 		gm.mutex.Unlock()
+		go gm.refreshPresence(userID)
+		go claimSeat(client, match.RoomID)
 	}
 
 	// Only the timer pod runs the game loop
@@ -422,8 +908,30 @@ func (gm *GameManager) handleMatchNotification(match MatchNotification) {
 	}
 }
 
-// sendGameStart sends the game start message to a player
-func (gm *GameManager) sendGameStart(client *Client, opponentID, role, roomID string) {
+// gameStartPayload describes the seating of a match from the view of one of its players
+func gameStartPayload(r *Roster, userID string) map[string]interface{} {
+	payload := map[string]interface{}{
+		"role":    r.Role(userID),
+		"mode":    r.Mode,
+		"players": r.Players,
+	}
+	switch r.Mode {
+	case ModeDuel:
+		opponent := r.LeadingOpponent(userID)
+		payload["opponent"] = opponent
+		if opponent != "" {
+			payload["headToHead"] = lookupHeadToHead(userID, opponent)
+		}
+	case ModeTeam:
+		payload["team"] = r.Team(userID)
+		payload["teams"] = map[string][]string{Team1: r.TeamMembers(Team1), Team2: r.TeamMembers(Team2)}
+	}
+	return payload
+}
+
+// sendGameStart sends the game start message to a player. When resumed is set the
+// message is a snapshot for a player rejoining a game already in progress.
+func (gm *GameManager) sendGameStart(client *Client, roomID string, resumed bool) {
 	// Get initial game state from Redis
 	state, err := GetGameState(roomID)
 	if err != nil {
@@ -431,73 +939,139 @@ func (gm *GameManager) sendGameStart(client *Client, opponentID, role, roomID st
 		return
 	}
 
-	startMsg := GameMessage{
-		Type: MsgTypeGameStart,
-		Payload: map[string]interface{}{
-			"opponent":      opponentID,
-			"role":          role,
-			"roomId":        roomID,
-			"timeRemaining": state.TimeRemaining,
-			"p1Score":       state.P1Score,
-			"p2Score":       state.P2Score,
-			"p1Name":        state.Player1Name,
-			"p2Name":        state.Player2Name,
-			"p1Picture":     state.Player1Picture,
-			"p2Picture":     state.Player2Picture,
-		},
+	payload := gameStartPayload(&state.Roster, client.userID)
+	payload["roomId"] = roomID
+	payload["timeRemaining"] = state.TimeRemaining
+	payload["p1Score"] = state.P1Score
+	payload["p2Score"] = state.P2Score
+	payload["p1Name"] = state.Player1Name
+	payload["p2Name"] = state.Player2Name
+	payload["p1Picture"] = state.Player1Picture
+	payload["p2Picture"] = state.Player2Picture
+	payload["scores"] = state.ScoresCopy()
+	withTeamScores(payload, state.TeamScores())
+	payload["started"] = state.GameStarted
+	payload["resumed"] = resumed
+	payload["rules"] = state.MatchRules()
+	if state.RematchOf != "" {
+		payload["rematchOf"] = state.RematchOf
 	}
-	bytes, _ := json.Marshal(startMsg)
-	select {
-	case client.send <- bytes:
-	default:
+	if series := seriesPayload(state.SeriesID); series != nil {
+		payload["series"] = series
+	}
+	if state.TournamentID != "" {
+		payload["tournament"] = map[string]string{"id": state.TournamentID, "matchId": state.TournamentMatch}
 	}
+
+	client.limiter.Reset()
+	sendToClient(client, GameMessage{Type: MsgTypeGameStart, Payload: payload})
 }
 
-// runDistributedGameLoop runs the game timer and broadcasts state updates via Redis
+// runDistributedGameLoop runs the game timer and broadcasts state updates via Redis.
+// Every tick renews this pod's timer lease; the loop stops as soon as another pod
+// has taken over the lease. It can also be started mid-game to resume a room.
 func (gm *GameManager) runDistributedGameLoop(roomID string) {
-	// Broadcast initial state immediately so clients have game info during countdown
-	gm.broadcastGameState(roomID)
-
-	// Wait for countdown (5 seconds)
-	time.Sleep(5 * time.Second)
-
-	// Mark game as started
-	state, err := GetGameState(roomID)
-	if err != nil {
-		log.Printf("Failed to get game state for %s: %v", roomID, err)
-		return
-	}
-	state.GameStarted = true
-	SaveGameState(state)
-
-	// Broadcast initial state
+	// Broadcast current state immediately so clients have game info during countdown
 	gm.broadcastGameState(roomID)
 
 	ticker := time.NewTicker(1 * time.Second)
-	gcTimer := time.NewTimer(time.Duration(5+rand.Intn(6)) * time.Second)
+	// Golden cookie timer only starts once the countdown is over
+	var gcTimer *time.Timer
+	var gcC <-chan time.Time
+	var rules GameRules
 
 	defer func() {
 		ticker.Stop()
-		gcTimer.Stop()
+		if gcTimer != nil {
+			gcTimer.Stop()
+		}
 	}()
 
 	for {
 		select {
 		case <-ticker.C:
-			state, err := GetGameState(roomID)
-			if err != nil {
+			wasStarted := false
+			forfeitedBy := ""
+			eliminated := ""
+			state, err := UpdateGameState(roomID, func(state *DistributedGameState) error {
+				if state.TimerPodID != GetPodID() {
+					return errTimerLeaseHeld
+				}
+				state.TimerHeartbeat = time.Now().UnixMilli()
+				if state.GameEnded {
+					return nil
+				}
+
+				// Forfeit players whose reconnect window ran out; in a free-for-all
+				// they are only eliminated
+				if loser := state.ExpiredDisconnect(time.Now()); loser != "" && state.Mode == ModeFFA {
+					state.Eliminate(loser, "disconnected")
+					delete(state.DisconnectedAt, loser)
+					eliminated = loser
+				} else if loser != "" {
+					state.GameEnded = true
+					state.WinnerID = state.ForfeitWinner(loser)
+					forfeitedBy = loser
+					return nil
+				}
+
+				wasStarted = state.GameStarted
+				if !state.GameStarted {
+					// Still counting down
+					if time.Now().UnixMilli() >= state.StartsAt {
+						state.GameStarted = true
+					}
+					return nil
+				}
+
+				state.TimeRemaining--
+				return nil
+			})
+			if errors.Is(err, errTimerLeaseHeld) {
+				log.Printf("Pod %s lost timer lease for room %s, stopping loop", GetPodID(), roomID)
+				return
+			}
+			if errors.Is(err, errGameNotFound) {
 				log.Printf("Game %s state not found, stopping loop", roomID)
 				return
 			}
+			if err != nil {
+				// Contention or a Redis hiccup, try again on the next tick
+				log.Printf("Failed to update game %s, skipping tick: %v", roomID, err)
+				continue
+			}
+
+			if forfeitedBy != "" {
+				gm.forfeitDistributedGame(state, forfeitedBy)
+				return
+			}
 
 			if state.GameEnded {
 				return
 			}
 
-			state.TimeRemaining--
-			SaveGameState(state)
+			if eliminated != "" {
+				log.Printf("Player %s did not reconnect to room %s in time and was eliminated", eliminated, roomID)
+				announceElimination(state, eliminated, "disconnected")
+			}
+
+			// A free-for-all ends early once a single player is left
+			if len(state.Active()) < 2 {
+				gm.endDistributedGame(roomID)
+				return
+			}
+
+			if !state.GameStarted {
+				continue
+			}
+
+			rules = state.MatchRules()
+			if gcTimer == nil {
+				gcTimer = time.NewTimer(rules.NextGoldenCookie())
+				gcC = gcTimer.C
+			}
 
-			if state.TimeRemaining <= 0 {
+			if wasStarted && state.TimeRemaining <= 0 {
 				gm.endDistributedGame(roomID)
 				return
 			}
@@ -505,9 +1079,72 @@ func (gm *GameManager) runDistributedGameLoop(roomID string) {
 			// Broadcast state update via Redis
 			gm.broadcastGameState(roomID)
 
-		case <-gcTimer.C:
+		case <-gcC:
 			gm.spawnDistributedGoldenCookie(roomID)
-			gcTimer.Reset(time.Duration(5+rand.Intn(6)) * time.Second)
+			gcTimer.Reset(rules.NextGoldenCookie())
+		}
+	}
+}
+
+// forfeitDistributedGame announces the end of a game whose player did not reconnect in
+// time. Like a quit, an aborted game only keeps its replay.
+func (gm *GameManager) forfeitDistributedGame(state *DistributedGameState, loserID string) {
+	log.Printf("Player %s did not reconnect to room %s in time, %s wins by forfeit", loserID, state.RoomID, state.WinnerID)
+
+	PublishGameEvent(GameEvent{
+		RoomID:    state.RoomID,
+		EventType: EventPlayerQuit,
+		PlayerID:  loserID,
+		Data:      map[string]interface{}{"winner": state.WinnerID, "reason": "opponent_disconnected"},
+	})
+	go updateRatings(forfeitResults(&state.Roster, loserID))
+	go saveReplay(state)
+	if state.SeriesID != "" {
+		go gm.forfeitSeries(state.SeriesID, loserID)
+	}
+	if state.TournamentID != "" {
+		go gm.finishTournamentMatch(state.TournamentID, state.TournamentMatch, state.RoomID, state.WinnerID, "forfeit")
+	}
+
+	for _, userID := range state.IDs() {
+		ClearPlayerRoom(userID, state.RoomID)
+	}
+
+	go func() {
+		time.Sleep(5 * time.Second)
+		DeleteGameState(state.RoomID)
+	}()
+}
+
+// RunTimerFailoverLoop watches all active games and resumes the game loop of any
+// room whose timer pod has stopped renewing its lease (e.g. pod killed during scale-down)
+func (gm *GameManager) RunTimerFailoverLoop() {
+	ticker := time.NewTicker(timerLeaseTimeout / 2)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		roomIDs, err := ListActiveGames()
+		if err != nil {
+			log.Printf("Timer failover: failed to list active games: %v", err)
+			continue
+		}
+
+		for _, roomID := range roomIDs {
+			claimed, err := ClaimTimerLease(roomID)
+			if errors.Is(err, errGameNotFound) {
+				// State expired without cleanup, forget about the room
+				RemoveActiveGame(roomID)
+				continue
+			}
+			if err != nil {
+				log.Printf("Timer failover: failed to claim lease for %s: %v", roomID, err)
+				continue
+			}
+
+			if claimed {
+				log.Printf("Pod %s resuming timer for room %s", GetPodID(), roomID)
+				go gm.runDistributedGameLoop(roomID)
+			}
 		}
 	}
 }
@@ -522,7 +1159,7 @@ func (gm *GameManager) broadcastGameState(roomID string) {
 	event := GameEvent{
 		RoomID:    roomID,
 		EventType: EventStateUpdate,
-		Data: map[string]interface{}{
+		Data: withTeamScores(map[string]interface{}{
 			"timeRemaining": state.TimeRemaining,
 			"p1Score":       state.P1Score,
 			"p2Score":       state.P2Score,
@@ -530,29 +1167,35 @@ func (gm *GameManager) broadcastGameState(roomID string) {
 			"p2Name":        state.Player2Name,
 			"p1Picture":     state.Player1Picture,
 			"p2Picture":     state.Player2Picture,
-		},
+			"scores":        state.ScoresCopy(),
+		}, state.TeamScores()),
 	}
 	PublishGameEvent(event)
 }
 
 // spawnDistributedGoldenCookie spawns a golden cookie and notifies all pods
 func (gm *GameManager) spawnDistributedGoldenCookie(roomID string) {
-	state, err := GetGameState(roomID)
+	state, err := UpdateGameState(roomID, func(state *DistributedGameState) error {
+		if state.GameEnded {
+			return errGameNotFound
+		}
+		state.GoldenCookieActive = true
+		state.GoldenCookieX = rand.Float64()*90 + 5
+		state.GoldenCookieY = rand.Float64()*90 + 5
+		state.PowerUpType = rollPowerUp()
+		return nil
+	})
 	if err != nil {
 		return
 	}
 
-	state.GoldenCookieActive = true
-	state.GoldenCookieX = rand.Float64()*90 + 5
-	state.GoldenCookieY = rand.Float64()*90 + 5
-	SaveGameState(state)
-
 	event := GameEvent{
 		RoomID:    roomID,
 		EventType: EventGoldenSpawn,
 		Data: map[string]interface{}{
-			"x": state.GoldenCookieX,
-			"y": state.GoldenCookieY,
+			"x":    state.GoldenCookieX,
+			"y":    state.GoldenCookieY,
+			"type": state.PowerUpType,
 		},
 	}
 	PublishGameEvent(event)
@@ -560,38 +1203,55 @@ func (gm *GameManager) spawnDistributedGoldenCookie(roomID string) {
 
 // endDistributedGame ends the game and notifies all pods
 func (gm *GameManager) endDistributedGame(roomID string) {
-	state, err := GetGameState(roomID)
+	state, err := UpdateGameState(roomID, func(state *DistributedGameState) error {
+		if state.GameEnded {
+			return errGameNotFound // Already ended elsewhere
+		}
+		state.GameEnded = true
+		state.WinnerID = state.Winner()
+		return nil
+	})
 	if err != nil {
 		return
 	}
 
-	state.GameEnded = true
-
-	// Determine winner
-	if state.P1Score > state.P2Score {
-		state.WinnerID = state.Player1ID
-	} else if state.P2Score > state.P1Score {
-		state.WinnerID = state.Player2ID
-	} else {
-		state.WinnerID = "draw"
-	}
-
-	SaveGameState(state)
-
 	event := GameEvent{
 		RoomID:    roomID,
 		EventType: EventGameEnd,
-		Data: map[string]interface{}{
+		Data: withTeamScores(map[string]interface{}{
 			"winner":  state.WinnerID,
 			"p1Score": state.P1Score,
 			"p2Score": state.P2Score,
-		},
+			"scores":  state.ScoresCopy(),
+			"results": state.Results(),
+		}, state.TeamScores()),
+	}
+
+	// The players may vote for a rematch for a short while; a series plays its
+	// next round and a tournament advances its bracket instead
+	if state.SeriesID != "" {
+		go gm.finishSeriesRound(state)
+	} else if state.TournamentID != "" {
+		go gm.finishTournamentMatch(state.TournamentID, state.TournamentMatch, roomID, state.WinnerID, "normal")
+	} else if err := OpenRematch(newRematchVote(roomID, &state.Roster, state.MatchRules())); err != nil {
+		log.Printf("Failed to open rematch vote for room %s: %v", roomID, err)
+	} else {
+		event.Data["rematchWindow"] = rematchWindow.Seconds()
+		time.AfterFunc(rematchWindow, func() {
+			if vote, err := TakeRematch(roomID); err == nil {
+				gm.expireRematch(vote)
+			}
+		})
 	}
 	PublishGameEvent(event)
 
 	// Persist game stats (only timer pod does this)
 	go gm.persistGameStats(state)
 
+	for _, userID := range state.Active() {
+		ClearPlayerRoom(userID, roomID)
+	}
+
 	// Clean up game state after a delay
 	go func() {
 		time.Sleep(30 * time.Second)
@@ -601,28 +1261,58 @@ func (gm *GameManager) endDistributedGame(roomID string) {
 
 // persistGameStats saves game results to database (DynamoDB or mock)
 func (gm *GameManager) persistGameStats(state *DistributedGameState) {
+	seconds := state.MatchRules().DurationSeconds - max(state.TimeRemaining, 0)
+	saveGameResults(state.RoomID, state.RematchOf, state.Mode, state.WinnerID, seconds, state.Results(), state.SuspiciousPlayers)
+	go saveReplay(state)
+
+	for userID, unlocked := range awardAchievements(state.RoomID, &state.Roster, state.WinnerID) {
+		gm.sendToUser(userID, achievementsMessage(unlocked))
+	}
+}
+
+// saveGameResults stores one CookieGame record per player and updates the players'
+// stats and ratings. The opponent of a record is the best placed other player (of
+// the other team in a team match).
+// seconds is how long the match was played, for the average clicks per second, and
+// rematchOf the GameID of the game this one is a rematch of, if any.
+func saveGameResults(gameID, rematchOf, mode, winnerID string, seconds int, results []PlayerResult, suspicious map[string]bool) {
 	timestamp := time.Now().Unix()
-	p1Won := state.P1Score > state.P2Score
-
-	// P1
-	db.SaveGameWithMock(db.CookieGame{
-		GameID: state.RoomID, PlayerID: state.Player1ID, Timestamp: timestamp,
-		Score: state.P1Score, OpponentScore: state.P2Score,
-		Reason: "normal", Won: p1Won, WinnerID: state.WinnerID, Opponent: state.Player2ID,
-		PlayerName: state.Player1Name, PlayerPicture: state.Player1Picture,
-		OpponentName: state.Player2Name, OpponentPicture: state.Player2Picture,
-	})
-	db.UpdateUserStatsWithMock(state.Player1ID, state.P1Score)
-
-	// P2
-	db.SaveGameWithMock(db.CookieGame{
-		GameID: state.RoomID, PlayerID: state.Player2ID, Timestamp: timestamp,
-		Score: state.P2Score, OpponentScore: state.P1Score,
-		Reason: "normal", Won: !p1Won, WinnerID: state.WinnerID, Opponent: state.Player1ID,
-		PlayerName: state.Player2Name, PlayerPicture: state.Player2Picture,
-		OpponentName: state.Player1Name, OpponentPicture: state.Player1Picture,
-	})
-	db.UpdateUserStatsWithMock(state.Player2ID, state.P2Score)
+
+	for _, res := range results {
+		opponent := res
+		for _, other := range results {
+			if other.UserID != res.UserID && (res.Team == "" || other.Team != res.Team) {
+				opponent = other
+				break
+			}
+		}
+		opponentScore := opponent.Score
+		if res.Team != "" {
+			opponentScore = opponent.TeamScore
+		}
+
+		db.SaveGameWithMock(db.CookieGame{
+			GameID: gameID, PlayerID: res.UserID, Timestamp: timestamp,
+			Score: res.Score, OpponentScore: opponentScore,
+			Reason: res.Reason, Won: winnerID != "draw" && res.Placement == 1, WinnerID: winnerID, Opponent: opponent.UserID,
+			PlayerName: res.Name, PlayerPicture: res.Picture,
+			OpponentName: opponent.Name, OpponentPicture: opponent.Picture,
+			Suspicious: suspicious[res.UserID],
+			Mode:       mode, Placement: res.Placement, PlayerCount: len(results),
+			Team: res.Team, TeamScore: res.TeamScore, RematchOf: rematchOf,
+		})
+		db.UpdateUserStatsWithMock(res.UserID, res.Score)
+		db.UpdatePlayerStatsWithMock(res.UserID, db.PlayerGame{
+			Score: res.Score, Clicks: res.Clicks, Seconds: seconds, GoldenCookies: res.GoldenCookies,
+			Won: winnerID != "draw" && res.Placement == 1, Draw: winnerID == "draw" && res.Placement == 1,
+		})
+		recordLeaderboardScore(res)
+	}
+
+	recordHeadToHead(mode, winnerID, results, timestamp)
+
+	ratings := updateRatings(results)
+	recordSeasonResults(winnerID, results, ratings)
 }
 
 // SubscribeToGameEvents listens for game events from all pods
@@ -645,8 +1335,16 @@ func (gm *GameManager) handleGameEvent(event GameEvent) {
 		}
 	}
 
-	if len(localClients) == 0 {
-		return // No local players for this game
+	// Spectators receive the same stream minus player-specific notifications
+	var localSpectators []*Client
+	for client, roomID := range gm.spectators {
+		if roomID == event.RoomID {
+			localSpectators = append(localSpectators, client)
+		}
+	}
+
+	if len(localClients) == 0 && len(localSpectators) == 0 {
+		return // No local players or spectators for this game
 	}
 
 	var msg GameMessage
@@ -671,23 +1369,39 @@ func (gm *GameManager) handleGameEvent(event GameEvent) {
 				P2Name:        event.Data["p2Name"].(string),
 				P1Picture:     p1Picture,
 				P2Picture:     p2Picture,
+				Scores:        toScores(event.Data["scores"]),
+				TeamScores:    toScores(event.Data["teamScores"]),
 			},
 		}
 
 	case EventGoldenSpawn:
 		msg = GameMessage{
-			Type:    MsgTypeCookieSpawn,
-			Payload: map[string]float64{"x": toFloat64(event.Data["x"]), "y": toFloat64(event.Data["y"])},
+			Type: MsgTypeCookieSpawn,
+			Payload: map[string]interface{}{
+				"x":    toFloat64(event.Data["x"]),
+				"y":    toFloat64(event.Data["y"]),
+				"type": event.Data["type"],
+			},
 		}
 
 	case EventGoldenClaim:
 		msg = GameMessage{
 			Type: MsgTypeUpdate,
-			Payload: map[string]interface{}{
+			Payload: withTeamScores(map[string]interface{}{
 				"goldenCookieClaimedBy": event.Data["claimedBy"],
 				"p1Score":               event.Data["p1Score"],
 				"p2Score":               event.Data["p2Score"],
-			},
+				"scores":                toScores(event.Data["scores"]),
+				"powerUp": PowerUpEffect{
+					Type:       payloadString(event.Data, "powerUp"),
+					ClaimedBy:  payloadString(event.Data, "claimedBy"),
+					Target:     payloadString(event.Data, "target"),
+					Affected:   toStrings(event.Data["affected"]),
+					DurationMs: int64(toFloat64(event.Data["durationMs"])),
+					Points:     toInt(event.Data["points"]),
+					Blocked:    event.Data["blocked"] == true,
+				},
+			}, toScores(event.Data["teamScores"])),
 		}
 
 	case EventClick:
@@ -714,13 +1428,14 @@ func (gm *GameManager) handleGameEvent(event GameEvent) {
 		p2Score := toInt(event.Data["p2Score"])
 		scoreMsg := GameMessage{
 			Type: MsgTypeUpdate,
-			Payload: map[string]interface{}{
+			Payload: withTeamScores(map[string]interface{}{
 				"p1Score": p1Score,
 				"p2Score": p2Score,
-			},
+				"scores":  toScores(event.Data["scores"]),
+			}, toScores(event.Data["teamScores"])),
 		}
 		scoreBytes, _ := json.Marshal(scoreMsg)
-		for _, client := range localClients {
+		for _, client := range append(localClients, localSpectators...) {
 			select {
 			case client.send <- scoreBytes:
 			default:
@@ -729,27 +1444,82 @@ func (gm *GameManager) handleGameEvent(event GameEvent) {
 		return // Don't send the generic message
 
 	case EventGameEnd:
-		msg = GameMessage{
-			Type:    MsgTypeGameOver,
-			Payload: map[string]interface{}{"winner": event.Data["winner"]},
+		payload := withTeamScores(map[string]interface{}{
+			"winner":  event.Data["winner"],
+			"scores":  toScores(event.Data["scores"]),
+			"results": event.Data["results"],
+		}, toScores(event.Data["teamScores"]))
+		if window, ok := event.Data["rematchWindow"]; ok {
+			payload["rematchWindow"] = window
 		}
+		msg = GameMessage{Type: MsgTypeGameOver, Payload: payload}
 
 		// Clean up client rooms
 		for _, client := range localClients {
 			delete(gm.clientRooms, client)
+			go gm.refreshPresence(client.userID)
+		}
+		for _, client := range localSpectators {
+			delete(gm.spectators, client)
 		}
 
 	case EventPlayerQuit:
 		winnerID := event.Data["winner"].(string)
+		reason := "quit"
+		if r, ok := event.Data["reason"].(string); ok {
+			reason = r
+		}
 		msg = GameMessage{
 			Type:    MsgTypeGameOver,
-			Payload: map[string]string{"winner": winnerID, "reason": "quit"},
+			Payload: map[string]string{"winner": winnerID, "reason": reason},
 		}
 
 		// Clean up client rooms
 		for _, client := range localClients {
 			delete(gm.clientRooms, client)
+			go gm.refreshPresence(client.userID)
+		}
+		for _, client := range localSpectators {
+			delete(gm.spectators, client)
+		}
+
+	case EventPlayerEliminated:
+		reason := payloadString(event.Data, "reason")
+		left := GameMessage{
+			Type: MsgTypePlayerLeft,
+			Payload: map[string]interface{}{
+				"userId":    event.PlayerID,
+				"reason":    reason,
+				"remaining": toInt(event.Data["remaining"]),
+				"scores":    toScores(event.Data["scores"]),
+			},
+		}
+		for _, client := range append(localClients, localSpectators...) {
+			if client.userID != event.PlayerID {
+				sendToClient(client, left)
+				continue
+			}
+			// The eliminated player's match is over
+			sendToClient(client, GameMessage{
+				Type:    MsgTypeGameOver,
+				Payload: map[string]interface{}{"winner": "", "reason": reason, "eliminated": true},
+			})
+			delete(gm.clientRooms, client)
+			go gm.refreshPresence(client.userID)
+		}
+		return
+
+	case EventPlayerDisconnected, EventPlayerReconnected:
+		msgType := MsgTypeOpponentDisconnected
+		if event.EventType == EventPlayerReconnected {
+			msgType = MsgTypeOpponentReconnected
 		}
+		for _, client := range localClients {
+			if client.userID != event.PlayerID {
+				sendToClient(client, GameMessage{Type: msgType, Payload: event.Data})
+			}
+		}
+		return
 
 	default:
 		return
@@ -757,7 +1527,7 @@ func (gm *GameManager) handleGameEvent(event GameEvent) {
 
 	// Send to all local clients
 	bytes, _ := json.Marshal(msg)
-	for _, client := range localClients {
+	for _, client := range append(localClients, localSpectators...) {
 		select {
 		case client.send <- bytes:
 		default:
@@ -765,34 +1535,50 @@ func (gm *GameManager) handleGameEvent(event GameEvent) {
 	}
 }
 
-func (gm *GameManager) StartGame(p1, p2 *Client) {
-	log.Printf("Starting game between %s and %s", p1.userID, p2.userID)
+// StartGame starts an in-memory room (single-pod mode) with the given players in seat order
+func (gm *GameManager) StartGame(mode string, clients ...*Client) {
+	gm.startRoom(mode, matchmakingRules(), "", clients...)
+}
+
+// startRoom starts an in-memory room played under rules; rematchOf links it to
+// the room it is a rematch of. Must be called with gm.mutex held.
+func (gm *GameManager) startRoom(mode string, rules GameRules, rematchOf string, clients ...*Client) {
+	players := make([]GamePlayer, len(clients))
+	userIDs := make([]string, len(clients))
+	for i, c := range clients {
+		players[i] = GamePlayer{UserID: c.userID, Name: c.name, Picture: c.picture}
+		userIDs[i] = c.userID
+	}
+	log.Printf("Starting %s game between %s", mode, strings.Join(userIDs, ", "))
+
 	room := &GameRoom{
-		ID:      fmt.Sprintf("%s_%s_%d", p1.userID, p2.userID, time.Now().Unix()),
-		Player1: p1,
-		Player2: p2,
-		State: GameState{
-			TimeRemaining: 60,        // 1 minute
-			P1Name:        p1.userID, // Replace with real name later
-			P2Name:        p2.userID,
-		},
-		Broadcast:         make(chan []byte),
-		Close:             make(chan bool, 1),
-		DoubleClickActive: make(map[string]time.Time),
+		ID:            newRoomID(mode, userIDs),
+		Clients:       clients,
+		Roster:        newRoster(mode, players),
+		Rules:         rules,
+		RematchOf:     rematchOf,
+		TimeRemaining: rules.DurationSeconds,
+		Broadcast:     make(chan []byte),
+		Close:         make(chan bool, 1),
+		Effects:       newPowerUpEffects(),
+		Suspicious:    make(map[string]bool),
 	}
 
 	// Notify players
-	p1Start := GameMessage{Type: MsgTypeGameStart, Payload: map[string]interface{}{"opponent": p2.userID, "role": "p1"}}
-	p2Start := GameMessage{Type: MsgTypeGameStart, Payload: map[string]interface{}{"opponent": p1.userID, "role": "p2"}}
-
-	p1Bytes, _ := json.Marshal(p1Start)
-	p2Bytes, _ := json.Marshal(p2Start)
-
-	p1.send <- p1Bytes
-	p2.send <- p2Bytes
-
-	gm.clientRooms[p1] = room
-	gm.clientRooms[p2] = room
+	for _, c := range clients {
+		payload := gameStartPayload(&room.Roster, c.userID)
+		payload["rules"] = rules
+		if rematchOf != "" {
+			payload["rematchOf"] = rematchOf
+		}
+		if room.Practice() {
+			payload["practice"] = true
+		}
+		c.limiter.Reset()
+		sendToClient(c, GameMessage{Type: MsgTypeGameStart, Payload: payload})
+		gm.clientRooms[c] = room
+		go gm.refreshPresence(c.userID) // Runs under gm.mutex
+	}
 
 	// Start Game Loop
 	go room.Run()
@@ -802,12 +1588,12 @@ func (room *GameRoom) Run() {
 	// Broadcast initial state so clients know game is starting (and see initial time)
 	room.broadcastState()
 
-	// Wait for countdown (5 seconds)
-	time.Sleep(5 * time.Second)
+	// Wait for countdown
+	time.Sleep(room.Rules.Countdown())
 
 	ticker := time.NewTicker(1 * time.Second)
-	// Golden cookie ticker (random interval 5-10s)
-	gcTimer := time.NewTimer(time.Duration(5+rand.Intn(6)) * time.Second)
+	// Golden cookie ticker (random interval from the rules)
+	gcTimer := time.NewTimer(room.Rules.NextGoldenCookie())
 
 	defer func() {
 		ticker.Stop()
@@ -820,8 +1606,8 @@ func (room *GameRoom) Run() {
 			return
 		case <-ticker.C:
 			room.mutex.Lock()
-			room.State.TimeRemaining--
-			timeUp := room.State.TimeRemaining <= 0
+			room.TimeRemaining--
+			timeUp := room.TimeRemaining <= 0
 			room.mutex.Unlock()
 
 			if timeUp {
@@ -831,193 +1617,265 @@ func (room *GameRoom) Run() {
 			room.broadcastState()
 		case <-gcTimer.C:
 			room.SpawnGoldenCookie()
-			gcTimer.Reset(time.Duration(5+rand.Intn(6)) * time.Second)
+			gcTimer.Reset(room.Rules.NextGoldenCookie())
 		}
 	}
 }
 
-func (room *GameRoom) broadcastState() {
+// Over reports whether the room has finished; placeholder rooms of distributed games never do
+func (room *GameRoom) Over() bool {
 	room.mutex.Lock()
 	defer room.mutex.Unlock()
+	return room.ended
+}
 
-	msg := GameMessage{Type: MsgTypeUpdate, Payload: room.State}
-	bytes, _ := json.Marshal(msg)
+// inActiveGame reports whether the client plays a game that is not over yet.
+// Finished in-memory rooms stay in gm.clientRooms until the next game, while
+// distributed placeholders are removed when their game ends.
+func (gm *GameManager) inActiveGame(client *Client) bool {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+	return gm.inActiveGameLocked(client)
+}
 
-	// Non-blocking send to avoid hanging if client is stuck
-	select {
-	case room.Player1.send <- bytes:
-	default:
+// inActiveGameLocked is inActiveGame for callers holding gm.mutex
+func (gm *GameManager) inActiveGameLocked(client *Client) bool {
+	room := gm.clientRooms[client]
+	return room != nil && (room.Clients == nil || !room.Over())
+}
+
+// others returns the clients of all other players still in the room
+func (room *GameRoom) others(client *Client) []*Client {
+	others := make([]*Client, 0, len(room.Clients))
+	for _, c := range room.Clients {
+		if _, out := room.Eliminated[c.userID]; c != client && !out {
+			others = append(others, c)
+		}
 	}
-	select {
-	case room.Player2.send <- bytes:
-	default:
+	return others
+}
+
+// broadcast sends a message to every player still in the room without blocking.
+// Must be called with room.mutex held.
+func (room *GameRoom) broadcast(msg GameMessage) {
+	for _, c := range room.Clients {
+		if _, out := room.Eliminated[c.userID]; !out {
+			sendToClient(c, msg)
+		}
 	}
 }
 
+func (room *GameRoom) broadcastState() {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	// Non-blocking send to avoid hanging if client is stuck
+	room.broadcast(GameMessage{Type: MsgTypeUpdate, Payload: newGameState(&room.Roster, room.TimeRemaining)})
+}
+
 func (room *GameRoom) SpawnGoldenCookie() {
 	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
 	room.GoldenCookieActive = true
 	// Random position (0-100%)
 	room.GoldenCookieX = rand.Float64()*90 + 5
 	room.GoldenCookieY = rand.Float64()*90 + 5
-	room.mutex.Unlock()
+	room.PowerUpType = rollPowerUp()
 
-	msg := GameMessage{
-		Type:    MsgTypeCookieSpawn,
-		Payload: map[string]float64{"x": room.GoldenCookieX, "y": room.GoldenCookieY},
-	}
-	bytes, _ := json.Marshal(msg)
-	room.Player1.send <- bytes
-	room.Player2.send <- bytes
+	room.broadcast(GameMessage{
+		Type: MsgTypeCookieSpawn,
+		Payload: map[string]interface{}{
+			"x":    room.GoldenCookieX,
+			"y":    room.GoldenCookieY,
+			"type": room.PowerUpType,
+		},
+	})
 }
 
 func (room *GameRoom) EndGame() {
-	p1Won := room.State.P1Score > room.State.P2Score
-	var winnerID string
-	if p1Won {
-		winnerID = room.Player1.userID
-	} else if room.State.P2Score > room.State.P1Score {
-		winnerID = room.Player2.userID
-	} else {
-		winnerID = "draw"
+	room.mutex.Lock()
+	if room.ended {
+		room.mutex.Unlock()
+		return
 	}
+	room.ended = true
+	winnerID := room.Winner()
+	results := room.Results()
+	suspicious := maps.Clone(room.Suspicious)
+	seconds := room.Rules.DurationSeconds - max(room.TimeRemaining, 0)
+	roster := room.Roster // Nothing changes anymore once the room has ended
+	clients := room.Clients
+	practice := room.Practice()
+
+	payload := withTeamScores(map[string]interface{}{
+		"winner":  winnerID,
+		"scores":  room.ScoresCopy(),
+		"results": results,
+	}, room.TeamScores())
+	// A bot does not vote, practice games have no rematch
+	if !practice {
+		room.rematch = newRematchVote(room.ID, &room.Roster, room.Rules)
+		payload["rematchWindow"] = rematchWindow.Seconds()
+	}
+	room.broadcast(GameMessage{Type: MsgTypeGameOver, Payload: payload})
+	room.mutex.Unlock()
 
-	msg := GameMessage{Type: MsgTypeGameOver,
-		Payload: map[string]string{"winner": winnerID},
+	gm := clients[0].manager
+	if !practice {
+		time.AfterFunc(rematchWindow, func() { gm.expireRematch(room.takeRematch()) })
 	}
-	bytes, _ := json.Marshal(msg)
-	room.Player1.send <- bytes
-	room.Player2.send <- bytes
 
 	// PERSIST GAME & UPDATE STATS
 	go func() {
-		timestamp := time.Now().Unix()
-
-		// P1
-		db.SaveGameWithMock(db.CookieGame{
-			GameID: room.ID, PlayerID: room.Player1.userID, Timestamp: timestamp,
-			Score: room.State.P1Score, OpponentScore: room.State.P2Score,
-			Reason: "normal", Won: p1Won, WinnerID: winnerID, Opponent: room.Player2.userID,
-			PlayerName: room.Player1.name, PlayerPicture: room.Player1.picture,
-			OpponentName: room.Player2.name, OpponentPicture: room.Player2.picture,
-		})
-		db.UpdateUserStatsWithMock(room.Player1.userID, room.State.P1Score)
+		if practice {
+			savePracticeResults(room.ID, winnerID, results, suspicious)
+			gm.refreshPresence(clients[0].userID)
+			return
+		}
+		saveGameResults(room.ID, room.RematchOf, room.Mode, winnerID, seconds, results, suspicious)
 
-		// P2
-		db.SaveGameWithMock(db.CookieGame{
-			GameID: room.ID, PlayerID: room.Player2.userID, Timestamp: timestamp,
-			Score: room.State.P2Score, OpponentScore: room.State.P1Score,
-			Reason: "normal", Won: !p1Won, WinnerID: winnerID, Opponent: room.Player1.userID,
-			PlayerName: room.Player2.name, PlayerPicture: room.Player2.picture,
-			OpponentName: room.Player1.name, OpponentPicture: room.Player1.picture,
-		})
-		db.UpdateUserStatsWithMock(room.Player2.userID, room.State.P2Score)
+		unlocked := awardAchievements(room.ID, &roster, winnerID)
+		for _, c := range clients {
+			if len(unlocked[c.userID]) > 0 {
+				gm.sendToUser(c.userID, achievementsMessage(unlocked[c.userID]))
+			}
+			gm.refreshPresence(c.userID)
+		}
 	}()
 
-	room.Close <- true
+	select {
+	case room.Close <- true:
+	default:
+	}
+}
+
+// Leave eliminates a player from an in-memory free-for-all room. The room ends
+// once fewer than two players are left.
+func (room *GameRoom) Leave(client *Client, reason string) {
+	room.mutex.Lock()
+	remaining := room.leave(client, reason)
+	room.mutex.Unlock()
+
+	if remaining < 2 {
+		room.EndGame()
+	}
+}
+
+// leave eliminates the player and tells the others, returning how many players are
+// left. Must be called with room.mutex held.
+func (room *GameRoom) leave(client *Client, reason string) int {
+	if _, out := room.Eliminated[client.userID]; out || room.ended {
+		return len(room.Active())
+	}
+	room.Eliminate(client.userID, reason)
+	remaining := len(room.Active())
+	log.Printf("Player %s left free-for-all room %s (%s)", client.userID, room.ID, reason)
+
+	room.broadcast(GameMessage{
+		Type: MsgTypePlayerLeft,
+		Payload: map[string]interface{}{
+			"userId":    client.userID,
+			"reason":    reason,
+			"remaining": remaining,
+			"scores":    room.ScoresCopy(),
+		},
+	})
+	return remaining
 }
 
 func (room *GameRoom) HandleGameMessage(client *Client, msg GameMessage) {
 	room.mutex.Lock()
 	defer room.mutex.Unlock()
 
+	if _, out := room.Eliminated[client.userID]; out || room.ended {
+		return
+	}
+
 	switch msg.Type {
 	case MsgTypeClick:
-		points := 1
-		if expiry, ok := room.DoubleClickActive[client.userID]; ok && time.Now().Before(expiry) {
-			points = 2
-		}
-
-		if client == room.Player1 {
-			room.State.P1Score += points
-		} else {
-			room.State.P2Score += points
+		points := room.Effects.ClickPoints(client.userID, time.Now())
+		if points == 0 {
+			rejectFrozenClick(client, room.Effects.FrozenFor(client.userID, time.Now()))
+			return
 		}
 
-		// Notify opponent immediately for red "particle"
-		opponent := room.Player1
-		if client == room.Player1 {
-			opponent = room.Player2
-		}
+		room.AddPoints(client.userID, points)
+		room.CountClick(client.userID)
 
+		// Notify opponents immediately for red "particle"
 		oppMsg := GameMessage{
 			Type:    MsgTypeOpponentClick,
 			Payload: map[string]int{"count": points},
 		}
-		oppBytes, _ := json.Marshal(oppMsg)
-		select {
-		case opponent.send <- oppBytes:
-		default:
+		for _, opponent := range room.others(client) {
+			sendToClient(opponent, oppMsg)
 		}
 
-		// Send real-time score update to both players
-		scoreMsg := GameMessage{
+		// Send real-time score update to all players
+		state := newGameState(&room.Roster, room.TimeRemaining)
+		room.broadcast(GameMessage{
 			Type: MsgTypeUpdate,
-			Payload: map[string]interface{}{
-				"p1Score": room.State.P1Score,
-				"p2Score": room.State.P2Score,
-			},
-		}
-		scoreBytes, _ := json.Marshal(scoreMsg)
-		select {
-		case room.Player1.send <- scoreBytes:
-		default:
-		}
-		select {
-		case room.Player2.send <- scoreBytes:
-		default:
-		}
+			Payload: withTeamScores(map[string]interface{}{
+				"p1Score": state.P1Score,
+				"p2Score": state.P2Score,
+				"scores":  state.Scores,
+			}, state.TeamScores),
+		})
 
 	case MsgTypeCookieClick:
 		// Attempt to claim golden cookie
 		if room.GoldenCookieActive {
 			room.GoldenCookieActive = false
-			// Award powerup (3 second double-click bonus)
-			room.DoubleClickActive[client.userID] = time.Now().Add(3 * time.Second)
+			// Award powerup to the claimer (and their team)
+			effect := room.Effects.Apply(room.PowerUpType, client.userID, room.Allies(client.userID), room.Targets(client.userID), room.Scores, room.Rules, time.Now())
+			room.CountGoldenCookie(client.userID)
 
-			// Notify players who got it
-			// Send message about who got the double click
-			powerupMsg := GameMessage{
+			// Notify players who got it and what it did
+			state := newGameState(&room.Roster, room.TimeRemaining)
+			room.broadcast(GameMessage{
 				Type: MsgTypeUpdate, // Can reuse update or new type
-				Payload: map[string]interface{}{
+				Payload: withTeamScores(map[string]interface{}{
 					"goldenCookieClaimedBy": client.userID,
-					"p1Score":               room.State.P1Score,
-					"p2Score":               room.State.P2Score,
-				},
-			}
-			bytes, _ := json.Marshal(powerupMsg)
-			room.Player1.send <- bytes
-			room.Player2.send <- bytes
-			room.Player1.send <- bytes
-			room.Player2.send <- bytes
+					"p1Score":               state.P1Score,
+					"p2Score":               state.P2Score,
+					"scores":                state.Scores,
+					"powerUp":               effect,
+				}, state.TeamScores),
+			})
 		}
 
 	case MsgTypeQuit:
 		log.Printf("Processing QUIT_GAME from user: %s", client.userID)
-		otherPlayer := room.Player1
-		if client == room.Player1 {
-			otherPlayer = room.Player2
+
+		if room.Mode == ModeFFA {
+			// Leaving a free-for-all only eliminates the player
+			sendToClient(client, GameMessage{
+				Type:    MsgTypeGameOver,
+				Payload: map[string]interface{}{"winner": "", "reason": "quit", "eliminated": true},
+			})
+			if room.leave(client, "quit") < 2 {
+				go room.EndGame()
+			}
+			return
 		}
 
 		// Send Game Over
-		msg := GameMessage{
+		room.broadcast(GameMessage{
 			Type:    MsgTypeGameOver,
-			Payload: map[string]string{"winner": otherPlayer.userID, "reason": "quit"},
-		}
-		bytes, _ := json.Marshal(msg)
+			Payload: map[string]string{"winner": room.ForfeitWinner(client.userID), "reason": "quit"},
+		})
 
-		select {
-		case room.Player1.send <- bytes:
-		default:
+		// DO NOT PERSIST if game is aborted/quit, only the quitter's rating suffers
+		log.Printf("Game %s aborted by %s, stats NOT saved. Closing room.", room.ID, client.userID)
+		room.ended = true
+		if !room.Practice() {
+			go updateRatings(forfeitResults(&room.Roster, client.userID))
 		}
+
 		select {
-		case room.Player2.send <- bytes:
+		case room.Close <- true:
 		default:
 		}
-
-		// DO NOT PERSIST if game is aborted/quit
-		log.Printf("Game %s aborted by %s, stats NOT saved. Closing room.", room.ID, client.userID)
-
-		room.Close <- true
 	}
 }
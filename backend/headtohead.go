@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+)
+
+// HeadToHead is the record of a player against one opponent in duels, seen from
+// the player
+type HeadToHead struct {
+	Games            int   `json:"games"`
+	Wins             int   `json:"wins"`
+	OpponentWins     int   `json:"opponentWins"`
+	Draws            int   `json:"draws"`
+	LastPlayed       int64 `json:"lastPlayed,omitempty"` // Unix seconds, 0 if they never met
+	BiggestMargin    int   `json:"biggestMargin"`        // Largest winning score difference of either player
+	BiggestMarginWon bool  `json:"biggestMarginWon"`     // The player won the game with the biggest margin
+}
+
+// newHeadToHead turns a stored pair record into the view of userID
+func newHeadToHead(record *db.CookieHeadToHead, userID string) HeadToHead {
+	if record == nil {
+		return HeadToHead{}
+	}
+	h2h := HeadToHead{
+		Games:            record.Games,
+		Wins:             record.WinsA,
+		OpponentWins:     record.WinsB,
+		Draws:            record.Draws,
+		LastPlayed:       record.LastPlayed,
+		BiggestMargin:    record.BiggestMargin,
+		BiggestMarginWon: record.BiggestMargin > 0 && record.BiggestMarginBy == userID,
+	}
+	if record.PlayerB == userID {
+		h2h.Wins, h2h.OpponentWins = record.WinsB, record.WinsA
+	}
+	return h2h
+}
+
+// lookupHeadToHead returns the record of a player against an opponent, empty if it
+// cannot be loaded
+func lookupHeadToHead(userID, opponentID string) HeadToHead {
+	record, err := db.GetHeadToHeadWithMock(userID, opponentID)
+	if err != nil {
+		log.Printf("Failed to load head-to-head of %s vs %s: %v", userID, opponentID, err)
+	}
+	return newHeadToHead(record, userID)
+}
+
+// recordHeadToHead adds a finished duel to the head-to-head record of its players.
+// FFA and team matches have no single opponent and are not counted.
+func recordHeadToHead(mode, winnerID string, results []PlayerResult, timestamp int64) {
+	if mode != ModeDuel || len(results) != 2 {
+		return
+	}
+	a, b := results[0], results[1]
+	if err := db.UpdateHeadToHeadWithMock(a.UserID, b.UserID, a.Score, b.Score, winnerID, timestamp); err != nil {
+		log.Printf("Failed to update head-to-head of %s vs %s: %v", a.UserID, b.UserID, err)
+	}
+}
+
+// handleHeadToHead returns the duel record of a player against an opponent
+func handleHeadToHead(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, opponentID := r.PathValue("userId"), r.PathValue("opponentId")
+	if userID == opponentID {
+		http.Error(w, "A player has no record against themselves", http.StatusBadRequest)
+		return
+	}
+
+	record, err := db.GetHeadToHeadWithMock(userID, opponentID)
+	if err != nil {
+		log.Printf("[API] Error fetching head-to-head of %s vs %s: %v", userID, opponentID, err)
+		http.Error(w, "Failed to fetch head-to-head", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(newHeadToHead(record, userID))
+}
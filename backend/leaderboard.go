@@ -0,0 +1,466 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+)
+
+// The leaderboards live in Redis sorted sets (UserID -> lifetime score or rating, or
+// the points of a time window) that are kept in sync with DynamoDB on every update
+// and rebuilt from it on startup. Names and pictures are cached next to them.
+const (
+	leaderboardScoreKey      = "overcookied:leaderboard:score" // + ":<window>:<start date>" for time windows
+	leaderboardRatingKey     = "overcookied:leaderboard:rating"
+	leaderboardProfilePrefix = "overcookied:leaderboard:profile:" // UserID -> leaderboardProfile JSON
+	leaderboardRebuildLock   = "overcookied:leaderboard:rebuild"  // Held by the pod that rebuilt last
+
+	// Pods starting within this interval of each other (e.g. during a rollout) rebuild once
+	leaderboardRebuildInterval = 5 * time.Minute
+)
+
+// errLeaderboardUnavailable is returned for time window leaderboards while Redis is down
+var errLeaderboardUnavailable = errors.New("leaderboard unavailable")
+
+// Leaderboard time windows rank the points scored since the start of the current
+// day, week (starting Monday) or month in UTC
+const (
+	WindowAllTime = "all"
+	WindowDaily   = "daily"
+	WindowWeekly  = "weekly"
+	WindowMonthly = "monthly"
+)
+
+var leaderboardWindows = []string{WindowDaily, WindowWeekly, WindowMonthly}
+
+// windowTTL keeps the sorted set of a window alive until the window has ended
+var windowTTL = map[string]time.Duration{
+	WindowDaily:   2 * 24 * time.Hour,
+	WindowWeekly:  8 * 24 * time.Hour,
+	WindowMonthly: 32 * 24 * time.Hour,
+}
+
+// Leaderboard query limits
+const (
+	defaultLeaderboardLimit  = 10
+	maxLeaderboardLimit      = 100
+	defaultLeaderboardRadius = 5
+	maxLeaderboardRadius     = 50
+)
+
+// LeaderboardQuery selects a leaderboard: players ranked by lifetime score (default)
+// or "rating", or by the points they scored in a time window
+type LeaderboardQuery struct {
+	SortBy string
+	Window string
+}
+
+// LeaderboardResponse is a part of a leaderboard. Entries are public - they exclude
+// userId and email.
+type LeaderboardResponse struct {
+	Entries    []PublicLeaderboardEntry `json:"entries"`
+	Total      int                      `json:"total"`                // Ranked players
+	NextCursor string                   `json:"nextCursor,omitempty"` // Pass as ?cursor= for the next page
+	Rank       int                      `json:"rank,omitempty"`       // "me" view: the caller's rank, 0 if unranked
+}
+
+// leaderboardProfile is the public part of a user shown on the leaderboard
+type leaderboardProfile struct {
+	Name    string `json:"name"`
+	Picture string `json:"picture"`
+}
+
+// windowStart returns the start of the time window containing now
+func windowStart(window string, now time.Time) time.Time {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch window {
+	case WindowWeekly:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case WindowMonthly:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// key returns the sorted set holding the leaderboard at the given time
+func (q LeaderboardQuery) key(now time.Time) string {
+	if q.Window != WindowAllTime {
+		return fmt.Sprintf("%s:%s:%s", leaderboardScoreKey, q.Window, windowStart(q.Window, now).Format("20060102"))
+	}
+	if q.SortBy == "rating" {
+		return leaderboardRatingKey
+	}
+	return leaderboardScoreKey
+}
+
+func profileJSON(name, picture string) string {
+	data, _ := json.Marshal(leaderboardProfile{Name: name, Picture: picture})
+	return string(data)
+}
+
+// windowScores sums up the points every player scored since the given Unix time.
+// Practice games against bots are skipped.
+func windowScores(games []db.CookieGame, since int64) map[string]int {
+	scores := make(map[string]int)
+	for _, g := range games {
+		if g.Timestamp < since || g.Practice {
+			continue
+		}
+		scores[g.PlayerID] += g.Score
+	}
+	return scores
+}
+
+// RebuildLeaderboard replaces the Redis leaderboards with the users and recent games
+// stored in DynamoDB, unless another pod has just done so
+func RebuildLeaderboard() error {
+	locked, err := kvSetNX(leaderboardRebuildLock, GetPodID(), leaderboardRebuildInterval)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		log.Printf("[LEADERBOARD] Rebuilt recently by another pod, skipping")
+		return nil
+	}
+	if err := rebuildLeaderboard(); err != nil {
+		kvDel(leaderboardRebuildLock) // Let the next pod try again
+		return err
+	}
+	return nil
+}
+
+// rebuildLeaderboard copies the users and recent games into the Redis leaderboards
+func rebuildLeaderboard() error {
+	users, err := db.ListUsersWithMock()
+	if err != nil {
+		return err
+	}
+
+	scores := make([]ZMember, len(users))
+	ratings := make([]ZMember, len(users))
+	profiles := make(map[string]string, len(users))
+	for i, u := range users {
+		scores[i] = ZMember{Member: u.UserID, Score: float64(u.Score)}
+		ratings[i] = ZMember{Member: u.UserID, Score: float64(u.EffectiveRating())}
+		profiles[leaderboardProfilePrefix+u.UserID] = profileJSON(u.Name, u.Picture)
+	}
+
+	if err := kvMSet(profiles); err != nil {
+		return err
+	}
+	if err := kvZReplace(leaderboardScoreKey, scores, 0); err != nil {
+		return err
+	}
+	if err := kvZReplace(leaderboardRatingKey, ratings, 0); err != nil {
+		return err
+	}
+
+	// The current week may have started in the previous month
+	now := time.Now()
+	since := windowStart(WindowMonthly, now)
+	if week := windowStart(WindowWeekly, now); week.Before(since) {
+		since = week
+	}
+	games, err := db.ListGamesSinceWithMock(since.Unix())
+	if err != nil {
+		return err
+	}
+	for _, window := range leaderboardWindows {
+		var members []ZMember
+		for userID, score := range windowScores(games, windowStart(window, now).Unix()) {
+			members = append(members, ZMember{Member: userID, Score: float64(score)})
+		}
+		query := LeaderboardQuery{Window: window}
+		if err := kvZReplace(query.key(now), members, windowTTL[window]); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[LEADERBOARD] Rebuilt leaderboard with %d users and %d recent games", len(users), len(games))
+	return nil
+}
+
+// syncLeaderboardUser copies a user's stored stats and profile into the leaderboard,
+// e.g. after they signed in for the first time
+func syncLeaderboardUser(userID string) {
+	if !IsRedisAvailable() {
+		return
+	}
+	user, err := db.GetUserWithMock(userID)
+	if err != nil || user == nil {
+		return
+	}
+
+	kvSet(leaderboardProfilePrefix+userID, profileJSON(user.Name, user.Picture), 0)
+	kvZAdd(leaderboardScoreKey, ZMember{Member: userID, Score: float64(user.Score)})
+	kvZAdd(leaderboardRatingKey, ZMember{Member: userID, Score: float64(user.EffectiveRating())})
+}
+
+// recordLeaderboardScore mirrors UpdateUserStats: adds the points of a finished game
+// to the lifetime score and to every time window
+func recordLeaderboardScore(res PlayerResult) {
+	if !IsRedisAvailable() {
+		return
+	}
+	if err := kvZIncrBy(leaderboardScoreKey, res.UserID, float64(res.Score), 0); err != nil {
+		log.Printf("[LEADERBOARD] Failed to update score of %s: %v", res.UserID, err)
+	}
+
+	now := time.Now()
+	for _, window := range leaderboardWindows {
+		query := LeaderboardQuery{Window: window}
+		if err := kvZIncrBy(query.key(now), res.UserID, float64(res.Score), windowTTL[window]); err != nil {
+			log.Printf("[LEADERBOARD] Failed to update %s score of %s: %v", window, res.UserID, err)
+		}
+	}
+	kvSet(leaderboardProfilePrefix+res.UserID, profileJSON(res.Name, res.Picture), 0)
+}
+
+// recordLeaderboardRating mirrors UpdateUserRating
+func recordLeaderboardRating(userID string, rating int) {
+	if !IsRedisAvailable() {
+		return
+	}
+	if err := kvZAdd(leaderboardRatingKey, ZMember{Member: userID, Score: float64(rating)}); err != nil {
+		log.Printf("[LEADERBOARD] Failed to update rating of %s: %v", userID, err)
+	}
+}
+
+// LeaderboardPage returns limit entries starting at offset (0 = first place). Served
+// from Redis, or from DynamoDB if Redis is down.
+func LeaderboardPage(q LeaderboardQuery, offset, limit int) (LeaderboardResponse, error) {
+	if !IsRedisAvailable() {
+		list, err := usersLeaderboard(q)
+		if err != nil {
+			return LeaderboardResponse{}, err
+		}
+		return list.page(offset, limit), nil
+	}
+
+	key := q.key(time.Now())
+	members, err := kvZRevRange(key, int64(offset), int64(offset+limit-1))
+	if err != nil {
+		return LeaderboardResponse{}, err
+	}
+	total, err := kvZCard(key)
+	if err != nil {
+		return LeaderboardResponse{}, err
+	}
+	entries, err := leaderboardEntries(q, members, offset)
+	if err != nil {
+		return LeaderboardResponse{}, err
+	}
+	return newLeaderboardPage(entries, offset, int(total)), nil
+}
+
+// LeaderboardAround returns the rank of a user (1 = first place) together with the
+// entries of the players up to radius places above and below
+func LeaderboardAround(q LeaderboardQuery, userID string, radius int) (LeaderboardResponse, error) {
+	if !IsRedisAvailable() {
+		list, err := usersLeaderboard(q)
+		if err != nil {
+			return LeaderboardResponse{}, err
+		}
+		return list.around(userID, radius), nil
+	}
+
+	key := q.key(time.Now())
+	total, err := kvZCard(key)
+	if err != nil {
+		return LeaderboardResponse{}, err
+	}
+	position, ok, err := kvZRevRank(key, userID)
+	if err != nil {
+		return LeaderboardResponse{}, err
+	}
+	if !ok {
+		return LeaderboardResponse{Entries: []PublicLeaderboardEntry{}, Total: int(total)}, nil
+	}
+
+	start := max(position-int64(radius), 0)
+	members, err := kvZRevRange(key, start, position+int64(radius))
+	if err != nil {
+		return LeaderboardResponse{}, err
+	}
+	entries, err := leaderboardEntries(q, members, int(start))
+	if err != nil {
+		return LeaderboardResponse{}, err
+	}
+	return LeaderboardResponse{Entries: entries, Total: int(total), Rank: int(position) + 1}, nil
+}
+
+// leaderboardEntries resolves a page of sorted set members starting at offset into
+// public entries, filling in the profile and the other stat of every player
+func leaderboardEntries(q LeaderboardQuery, members []ZMember, offset int) ([]PublicLeaderboardEntry, error) {
+	userIDs := make([]string, len(members))
+	profileKeys := make([]string, len(members))
+	for i, m := range members {
+		userIDs[i] = m.Member
+		profileKeys[i] = leaderboardProfilePrefix + m.Member
+	}
+
+	profiles, err := kvMGet(profileKeys...)
+	if err != nil {
+		return nil, err
+	}
+	rankedByRating := q.Window == WindowAllTime && q.SortBy == "rating"
+	otherKey := leaderboardRatingKey
+	if rankedByRating {
+		otherKey = leaderboardScoreKey
+	}
+	others, err := kvZMScore(otherKey, userIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]PublicLeaderboardEntry, len(members))
+	for i, m := range members {
+		var profile leaderboardProfile
+		json.Unmarshal([]byte(profiles[i]), &profile)
+
+		score, rating := int(m.Score), int(others[i])
+		if rankedByRating {
+			score, rating = int(others[i]), int(m.Score)
+		}
+		if rating == 0 {
+			rating = db.DefaultRating
+		}
+		entries[i] = PublicLeaderboardEntry{
+			Name:    profile.Name,
+			Picture: profile.Picture,
+			Score:   score,
+			Rating:  rating,
+			Rank:    offset + i + 1,
+		}
+	}
+	return entries, nil
+}
+
+// usersLeaderboard builds the all-time leaderboard from the users stored in DynamoDB,
+// the fallback when Redis is unavailable. Ties are ordered like in a Redis sorted set.
+// Time windows would need a scan of all recent games and are not offered.
+func usersLeaderboard(q LeaderboardQuery) (rankedList, error) {
+	if q.Window != WindowAllTime {
+		return rankedList{}, errLeaderboardUnavailable
+	}
+	users, err := db.ListUsersWithMock()
+	if err != nil {
+		return rankedList{}, err
+	}
+
+	value := func(u db.CookieUser) int {
+		if q.SortBy == "rating" {
+			return u.EffectiveRating()
+		}
+		return u.Score
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if value(users[i]) != value(users[j]) {
+			return value(users[i]) > value(users[j])
+		}
+		return users[i].UserID > users[j].UserID
+	})
+
+	list := rankedList{
+		entries: make([]PublicLeaderboardEntry, len(users)),
+		userIDs: make([]string, len(users)),
+	}
+	for i, u := range users {
+		list.entries[i] = PublicLeaderboardEntry{
+			Name:    u.Name,
+			Picture: u.Picture,
+			Score:   u.Score,
+			Rating:  u.EffectiveRating(),
+			Rank:    i + 1,
+		}
+		list.userIDs[i] = u.UserID
+	}
+	return list, nil
+}
+
+// rankedList is a whole leaderboard held in memory, with the UserID behind every entry
+type rankedList struct {
+	entries []PublicLeaderboardEntry
+	userIDs []string
+}
+
+// page returns limit entries starting at offset
+func (l rankedList) page(offset, limit int) LeaderboardResponse {
+	end := min(offset+limit, len(l.entries))
+	start := min(offset, end)
+	return newLeaderboardPage(l.entries[start:end], offset, len(l.entries))
+}
+
+// around returns the rank of a user with the entries up to radius places above and below
+func (l rankedList) around(userID string, radius int) LeaderboardResponse {
+	for i, id := range l.userIDs {
+		if id == userID {
+			start, end := max(i-radius, 0), min(i+radius+1, len(l.entries))
+			return LeaderboardResponse{Entries: l.entries[start:end], Total: len(l.entries), Rank: l.entries[i].Rank}
+		}
+	}
+	return LeaderboardResponse{Entries: []PublicLeaderboardEntry{}, Total: len(l.entries)}
+}
+
+// newLeaderboardPage wraps a page of entries starting at offset, with a cursor to the
+// next page if there are more entries
+func newLeaderboardPage(entries []PublicLeaderboardEntry, offset, total int) LeaderboardResponse {
+	resp := LeaderboardResponse{Entries: entries, Total: total}
+	if next := offset + len(entries); len(entries) > 0 && next < total {
+		resp.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(next)))
+	}
+	return resp
+}
+
+// parseLeaderboardWindow validates the window query parameter, all-time if empty
+func parseLeaderboardWindow(param string) (string, bool) {
+	switch param {
+	case "", WindowAllTime:
+		return WindowAllTime, true
+	case WindowDaily, WindowWeekly, WindowMonthly:
+		return param, true
+	}
+	return "", false
+}
+
+// parseLeaderboardPage reads the page to return from the cursor, or offset and limit
+// query parameters
+func parseLeaderboardPage(query url.Values) (offset, limit int, err error) {
+	if limit, err = intParam(query, "limit", defaultLeaderboardLimit, 1, maxLeaderboardLimit); err != nil {
+		return 0, 0, err
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid cursor")
+		}
+		if offset, err = strconv.Atoi(string(decoded)); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid cursor")
+		}
+		return offset, limit, nil
+	}
+	offset, err = intParam(query, "offset", 0, 0, 1<<30)
+	return offset, limit, err
+}
+
+// intParam reads an optional integer query parameter within [lo, hi]
+func intParam(query url.Values, name string, def, lo, hi int) (int, error) {
+	param := query.Get(name)
+	if param == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(param)
+	if err != nil || v < lo || v > hi {
+		return 0, fmt.Errorf("%s must be a number between %d and %d", name, lo, hi)
+	}
+	return v, nil
+}
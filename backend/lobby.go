@@ -0,0 +1,329 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Lobby is a private game waiting for a friend to join via its invite code
+type Lobby struct {
+	Code      string      `json:"code"`
+	Host      QueueEntry  `json:"host"`
+	Guest     *QueueEntry `json:"guest,omitempty"`
+	Rules     GameRules   `json:"rules"`
+	BestOf    int         `json:"bestOf,omitempty"` // Rounds of a series, 0 for a single game
+	CreatedAt int64       `json:"createdAt"`
+}
+
+const (
+	lobbyKeyPrefix     = "overcookied:lobby:"
+	lobbyHostKeyPrefix = "overcookied:lobby:host:" // UserID -> code of the lobby they host
+	lobbyTTL           = 10 * time.Minute
+	lobbyCodeLength    = 6
+	lobbyCodeAlphabet  = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // No 0/O or 1/I to keep codes readable
+)
+
+var (
+	errLobbyNotFound = errors.New("lobby not found")
+	errLobbyFull     = errors.New("lobby is full")
+	errLobbyOwn      = errors.New("cannot join own lobby")
+)
+
+// generateLobbyCode returns a random invite code
+func generateLobbyCode() (string, error) {
+	code := make([]byte, lobbyCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(lobbyCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = lobbyCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// normalizeLobbyCode makes user-typed codes case and whitespace insensitive
+func normalizeLobbyCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CreateLobby stores a new lobby hosted by host, to be played under rules as a
+// best-of-N series if bestOf is above 1, and returns it. Any lobby previously
+// hosted by the same user is cancelled.
+func CreateLobby(host QueueEntry, rules GameRules, bestOf int) (*Lobby, error) {
+	if _, err := CancelLobby(host.UserID); err != nil && !errors.Is(err, errLobbyNotFound) && !errors.Is(err, errLobbyFull) {
+		return nil, err
+	}
+
+	lobby := Lobby{Host: host, Rules: rules, BestOf: bestOf, CreatedAt: time.Now().Unix()}
+
+	// Retry on the (unlikely) event of a code collision
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := generateLobbyCode()
+		if err != nil {
+			return nil, err
+		}
+		lobby.Code = code
+
+		lobbyJSON, err := json.Marshal(lobby)
+		if err != nil {
+			return nil, err
+		}
+
+		created, err := kvSetNX(lobbyKeyPrefix+code, string(lobbyJSON), lobbyTTL)
+		if err != nil {
+			return nil, err
+		}
+		if !created {
+			continue
+		}
+
+		if err := kvSet(lobbyHostKeyPrefix+host.UserID, code, lobbyTTL); err != nil {
+			return nil, err
+		}
+
+		log.Printf("Lobby %s created by %s", code, host.UserID)
+		return &lobby, nil
+	}
+
+	return nil, fmt.Errorf("could not generate a unique lobby code")
+}
+
+// JoinLobby claims the guest slot of a lobby. The lobby is kept for a short
+// while after being filled so late joiners get a "full" error instead of "not found".
+func JoinLobby(code string, guest QueueEntry) (*Lobby, error) {
+	code = normalizeLobbyCode(code)
+	var lobby Lobby
+
+	err := kvUpdate(lobbyKeyPrefix+code, time.Minute, func(current string, found bool) (string, error) {
+		if !found {
+			return "", errLobbyNotFound
+		}
+		if err := json.Unmarshal([]byte(current), &lobby); err != nil {
+			return "", err
+		}
+		if lobby.Host.UserID == guest.UserID {
+			return "", errLobbyOwn
+		}
+		if lobby.Guest != nil {
+			return "", errLobbyFull
+		}
+
+		lobby.Guest = &guest
+		updated, err := json.Marshal(lobby)
+		return string(updated), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	kvDel(lobbyHostKeyPrefix + lobby.Host.UserID)
+	log.Printf("Lobby %s joined by %s", code, guest.UserID)
+	return &lobby, nil
+}
+
+// CancelLobby removes the open lobby hosted by hostID and returns its code
+func CancelLobby(hostID string) (string, error) {
+	code, found, err := kvGet(lobbyHostKeyPrefix + hostID)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", errLobbyNotFound
+	}
+
+	err = kvUpdate(lobbyKeyPrefix+code, lobbyTTL, func(current string, found bool) (string, error) {
+		if !found {
+			return "", nil
+		}
+		var lobby Lobby
+		if err := json.Unmarshal([]byte(current), &lobby); err != nil {
+			return "", err
+		}
+		if lobby.Guest != nil {
+			return "", errLobbyFull // Game already started
+		}
+		return "", nil // Delete
+	})
+	kvDel(lobbyHostKeyPrefix + hostID)
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("Lobby %s cancelled by host %s", code, hostID)
+	return code, nil
+}
+
+// queueEntryFor builds the matchmaking entry describing a local client
+func queueEntryFor(client *Client) QueueEntry {
+	return QueueEntry{
+		UserID:   client.userID,
+		Name:     client.name,
+		Picture:  client.picture,
+		PodID:    GetPodID(),
+		JoinedAt: time.Now().Unix(),
+	}
+}
+
+// handleCreateLobby opens a private lobby for the client and replies with its code.
+// The host may pick a rules preset, the matchmaking preset is used otherwise, and
+// ask for a best-of-N series.
+func (gm *GameManager) handleCreateLobby(client *Client, preset string, bestOf int) {
+	if !IsRedisAvailable() {
+		sendError(client, "unavailable", "Private lobbies are not available right now")
+		return
+	}
+
+	rules := matchmakingRules()
+	if preset != "" {
+		var ok bool
+		if rules, ok = rulesForPreset(preset); !ok {
+			sendError(client, "invalid_preset", "Unknown game rules preset")
+			return
+		}
+	}
+	if !validBestOf(bestOf) {
+		sendError(client, "invalid_best_of", "A series is best of 3 or 5")
+		return
+	}
+
+	if gm.inActiveGame(client) {
+		sendError(client, "already_in_game", "You are already in a game")
+		return
+	}
+
+	// A host waits in their lobby, not in the public queue
+	RemoveFromQueue(client.userID)
+
+	lobby, err := CreateLobby(queueEntryFor(client), rules, bestOf)
+	if err != nil {
+		log.Printf("Failed to create lobby for %s: %v", client.userID, err)
+		sendError(client, "lobby_error", "Could not create lobby")
+		return
+	}
+
+	sendToClient(client, GameMessage{
+		Type: MsgTypeLobbyCreated,
+		Payload: map[string]interface{}{
+			"code":      lobby.Code,
+			"expiresIn": lobbyTTL.Seconds(),
+			"rules":     lobby.Rules,
+			"bestOf":    lobby.BestOf,
+		},
+	})
+}
+
+// handleJoinLobby joins the lobby with the given code and starts the game through
+// the regular distributed match flow
+func (gm *GameManager) handleJoinLobby(client *Client, code string) {
+	if !IsRedisAvailable() {
+		sendError(client, "unavailable", "Private lobbies are not available right now")
+		return
+	}
+
+	if normalizeLobbyCode(code) == "" {
+		sendError(client, "invalid_request", "code is required")
+		return
+	}
+
+	if gm.inActiveGame(client) {
+		sendError(client, "already_in_game", "You are already in a game")
+		return
+	}
+
+	// A host joining someone else's lobby gives up their own
+	if own, _, _ := kvGet(lobbyHostKeyPrefix + client.userID); own != "" && own == normalizeLobbyCode(code) {
+		sendError(client, "lobby_own", "You cannot join your own lobby")
+		return
+	}
+	if !gm.leaveOwnLobby(client) {
+		return
+	}
+
+	lobby, err := JoinLobby(code, queueEntryFor(client))
+	switch {
+	case errors.Is(err, errLobbyNotFound):
+		sendError(client, "lobby_not_found", "Invalid or expired lobby code")
+		return
+	case errors.Is(err, errLobbyFull):
+		sendError(client, "lobby_full", "This lobby is already full")
+		return
+	case errors.Is(err, errLobbyOwn):
+		sendError(client, "lobby_own", "You cannot join your own lobby")
+		return
+	case err != nil:
+		log.Printf("Failed to join lobby %s: %v", code, err)
+		sendError(client, "lobby_error", "Could not join lobby")
+		return
+	}
+
+	RemoveFromQueue(client.userID)
+
+	if lobby.BestOf > 1 {
+		if err := gm.startSeries([]QueueEntry{lobby.Host, *lobby.Guest}, lobby.Rules, lobby.BestOf); err != nil {
+			log.Printf("Failed to start series for lobby %s: %v", lobby.Code, err)
+			sendError(client, "lobby_error", "Could not start the series")
+		}
+		return
+	}
+
+	roomID := newRoomID(ModeDuel, []string{lobby.Host.UserID, lobby.Guest.UserID})
+	if err := CreateDistributedGame(roomID, ModeDuel, []QueueEntry{lobby.Host, *lobby.Guest}, lobby.Rules); err != nil {
+		log.Printf("Failed to create distributed game for lobby %s: %v", lobby.Code, err)
+		sendError(client, "lobby_error", "Could not start the game")
+		return
+	}
+
+	match := newMatchNotification(roomID, ModeDuel, []string{lobby.Host.UserID, lobby.Guest.UserID})
+	if err := PublishMatchNotification(match); err != nil {
+		log.Printf("Failed to publish match notification for lobby %s: %v", lobby.Code, err)
+	}
+}
+
+// leaveOwnLobby closes the lobby the client hosts before they look for another
+// game. Returns false if the lobby was joined in the meantime, i.e. its game is
+// about to start.
+func (gm *GameManager) leaveOwnLobby(client *Client) bool {
+	code, err := CancelLobby(client.userID)
+	switch {
+	case err == nil:
+		sendToClient(client, GameMessage{
+			Type:    MsgTypeLobbyCancelled,
+			Payload: map[string]string{"code": code},
+		})
+	case errors.Is(err, errLobbyFull):
+		sendError(client, "lobby_full", "Your lobby was already joined")
+		return false
+	case !errors.Is(err, errLobbyNotFound):
+		log.Printf("Failed to close lobby of %s: %v", client.userID, err)
+	}
+	return true
+}
+
+// handleCancelLobby closes the lobby hosted by the client
+func (gm *GameManager) handleCancelLobby(client *Client) {
+	code, err := CancelLobby(client.userID)
+	if errors.Is(err, errLobbyNotFound) {
+		sendError(client, "lobby_not_found", "You have no open lobby")
+		return
+	}
+	if errors.Is(err, errLobbyFull) {
+		sendError(client, "lobby_full", "Your lobby was already joined")
+		return
+	}
+	if err != nil {
+		sendError(client, "lobby_error", "Could not cancel lobby")
+		return
+	}
+
+	sendToClient(client, GameMessage{
+		Type:    MsgTypeLobbyCancelled,
+		Payload: map[string]string{"code": code},
+	})
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -47,7 +48,7 @@ func enableCors(w *http.ResponseWriter) {
 	frontendURL = strings.TrimSuffix(frontendURL, "/")
 
 	(*w).Header().Set("Access-Control-Allow-Origin", frontendURL)
-	(*w).Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	(*w).Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 	(*w).Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	(*w).Header().Set("Access-Control-Allow-Credentials", "true")
 }
@@ -57,8 +58,16 @@ type PublicLeaderboardEntry struct {
 	Name    string `json:"name"`
 	Picture string `json:"picture"`
 	Score   int    `json:"score"`
+	Rating  int    `json:"rating"`
+	Rank    int    `json:"rank"`
+
+	// Season leaderboards only
+	Games int `json:"games,omitempty"`
+	Wins  int `json:"wins,omitempty"`
 }
 
+// handleLeaderboard serves a page of the leaderboard (?limit=, ?offset= or ?cursor=),
+// or with ?view=me the caller's rank and the entries around it (?radius=)
 func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
 	enableCors(&w)
 	if r.Method == "OPTIONS" {
@@ -66,25 +75,90 @@ func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	users, err := db.GetLeaderboardWithMock(10)
+
+	query := r.URL.Query()
+	offset, limit, err := parseLeaderboardPage(query)
 	if err != nil {
-		log.Printf("[API] Error fetching leaderboard: %v", err)
-		http.Error(w, "Failed to fetch leaderboard", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	radius, err := intParam(query, "radius", defaultLeaderboardRadius, 0, maxLeaderboardRadius)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	// ?window=daily|weekly|monthly ranks by the points scored in the current period
+	window, ok := parseLeaderboardWindow(query.Get("window"))
+	if !ok {
+		http.Error(w, "Unknown window", http.StatusBadRequest)
+		return
+	}
+	// ?sort=rating ranks by Elo instead of the lifetime score sum
+	sortBy := query.Get("sort")
+
+	var userID string
+	if query.Get("view") == "me" {
+		userID = requestClaims(r).UserID
+	}
 
-	// Convert to public format - exclude userId and email
-	publicEntries := make([]PublicLeaderboardEntry, len(users))
-	for i, u := range users {
-		publicEntries[i] = PublicLeaderboardEntry{
-			Name:    u.Name,
-			Picture: u.Picture,
-			Score:   u.Score,
+	var resp LeaderboardResponse
+
+	// ?season=current or ?season=<number> ranks by the points of one season
+	if param := query.Get("season"); param != "" {
+		if window != WindowAllTime {
+			http.Error(w, "Season leaderboards have no time window", http.StatusBadRequest)
+			return
+		}
+		season, err := findSeason(param)
+		if err != nil {
+			log.Printf("[API] Error looking up season %s: %v", param, err)
+			http.Error(w, "Failed to fetch leaderboard", http.StatusInternalServerError)
+			return
+		}
+		if season == nil {
+			http.Error(w, "Season not found", http.StatusNotFound)
+			return
 		}
+
+		list, err := seasonLeaderboard(season, sortBy)
+		if err != nil {
+			log.Printf("[API] Error fetching leaderboard of season %d: %v", season.SeasonID, err)
+			http.Error(w, "Failed to fetch leaderboard", http.StatusInternalServerError)
+			return
+		}
+		if userID != "" {
+			resp = list.around(userID, radius)
+		} else {
+			resp = list.page(offset, limit)
+		}
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	if window != WindowAllTime && sortBy == "rating" {
+		http.Error(w, "Rating leaderboards have no time window", http.StatusBadRequest)
+		return
 	}
-	json.NewEncoder(w).Encode(publicEntries)
+	q := LeaderboardQuery{SortBy: sortBy, Window: window}
+	if userID != "" {
+		resp, err = LeaderboardAround(q, userID, radius)
+	} else {
+		resp, err = LeaderboardPage(q, offset, limit)
+	}
+	if errors.Is(err, errLeaderboardUnavailable) {
+		http.Error(w, "Time window leaderboards are temporarily unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		log.Printf("[API] Error fetching leaderboard: %v", err)
+		http.Error(w, "Failed to fetch leaderboard", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
 }
 
+// handleHistory returns the caller's own match history, newest first (?limit=, ?cursor=).
+// Other players' games are only available through their public profile.
 func handleHistory(w http.ResponseWriter, r *http.Request) {
 	enableCors(&w)
 	if r.Method == "OPTIONS" {
@@ -92,13 +166,23 @@ func handleHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	userID := r.URL.Query().Get("userId")
-	if userID == "" {
-		http.Error(w, "Missing userId parameter", http.StatusBadRequest)
+
+	userID := requestClaims(r).UserID
+	if other := r.URL.Query().Get("userId"); other != "" && other != userID {
+		http.Error(w, "Forbidden: use /api/users/{userId} for other players", http.StatusForbidden)
+		return
+	}
+	limit, err := intParam(r.URL.Query(), "limit", defaultHistoryLimit, 1, maxHistoryLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	games, err := db.GetGameHistoryWithMock(userID, 20)
+	games, nextCursor, err := db.GetGameHistoryWithMock(userID, int32(limit), r.URL.Query().Get("cursor"))
+	if errors.Is(err, db.ErrInvalidCursor) {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("[API] Error fetching history: %v", err)
 		http.Error(w, "Failed to fetch history", http.StatusInternalServerError)
@@ -115,10 +199,34 @@ func handleHistory(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"games":      games,
 		"totalCount": totalCount,
+		"nextCursor": nextCursor,
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
+func handleLiveGames(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if !IsRedisAvailable() {
+		// Single-pod fallback games are not tracked in Redis
+		json.NewEncoder(w).Encode([]LiveGame{})
+		return
+	}
+
+	games, err := ListLiveGames()
+	if err != nil {
+		log.Printf("[API] Error listing live games: %v", err)
+		http.Error(w, "Failed to fetch live games", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(games)
+}
+
 func main() {
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
@@ -134,17 +242,36 @@ func main() {
 	// Initialize Redis/Valkey for distributed matchmaking
 	if err := InitRedis(); err != nil {
 		log.Printf("Warning: Redis not available, using in-memory matchmaking (single-pod mode)")
+	} else if err := RebuildLeaderboard(); err != nil {
+		log.Printf("Warning: Failed to rebuild leaderboard: %v", err)
 	}
 
+	// Load click rate limit / anti-cheat configuration
+	initClickLimits()
+
+	// Load game rule presets, free-for-all matchmaking, season and practice bot settings
+	initGameRules()
+	initFFAConfig()
+	initSeasonConfig()
+	initPracticeConfig()
+
+	// Start the first ranked season and roll over to the next one when it ends
+	go RunSeasonLoop()
+
 	// Initialize Game Manager
 	gameManager := NewGameManager()
 	go gameManager.Run()
+	go gameManager.RunPresenceLoop() // Friends' online status
 
 	// Start matchmaking loop and event subscriptions if Redis is available
 	if IsRedisAvailable() {
 		go gameManager.RunMatchmakingLoop()
 		go gameManager.SubscribeToMatchNotifications()
-		go gameManager.SubscribeToGameEvents() // Subscribe to distributed game events
+		go gameManager.SubscribeToGameEvents()   // Subscribe to distributed game events
+		go gameManager.SubscribeToUserMessages() // Party invites and updates for local players
+		go gameManager.RunTimerFailoverLoop()    // Resume games whose timer pod died
+		go gameManager.RunTournamentLoop()       // Start bracket matches and settle no-shows
+		go RunReplayFlushLoop()                  // Batch replay events into Redis
 		log.Println("Distributed matchmaking and game events enabled via Redis")
 	}
 
@@ -161,8 +288,24 @@ func main() {
 	http.HandleFunc("/auth/google/callback", handleGoogleCallback)
 	http.HandleFunc("/auth/verify", handleVerifySession)
 	http.HandleFunc("/auth/logout", handleLogout)
-	http.HandleFunc("/api/leaderboard", handleLeaderboard)
-	http.HandleFunc("/api/history", handleHistory)
+	http.HandleFunc("/api/leaderboard", requireAuth(handleLeaderboard))
+	http.HandleFunc("/api/seasons", requireAuth(handleSeasons))
+	http.HandleFunc("/api/history", requireAuth(handleHistory))
+	http.HandleFunc("/api/users/{userId}", requireAuth(handleUserProfile))
+	http.HandleFunc("/api/users/{userId}/stats", requireAuth(handleUserStats))
+	http.HandleFunc("/api/users/{userId}/vs/{opponentId}", requireAuth(handleHeadToHead))
+	http.HandleFunc("/api/users/{userId}/achievements", requireAuth(handleUserAchievements))
+	http.HandleFunc("/api/friends", requireAuth(gameManager.handleFriends))
+	http.HandleFunc("/api/friends/{userId}", requireAuth(gameManager.handleFriendRemove))
+	http.HandleFunc("/api/friends/{userId}/request", requireAuth(gameManager.handleFriendRequest))
+	http.HandleFunc("/api/friends/{userId}/accept", requireAuth(gameManager.handleFriendAccept))
+	http.HandleFunc("/api/games/live", requireAuth(handleLiveGames))
+	http.HandleFunc("/api/games/{gameId}/replay", requireAuth(handleReplay))
+	http.HandleFunc("/api/series/{seriesId}", requireAuth(handleSeries))
+	http.HandleFunc("/api/tournaments", requireAuth(handleTournaments))
+	http.HandleFunc("/api/tournaments/{tournamentId}", requireAuth(handleTournament))
+	http.HandleFunc("/api/tournaments/{tournamentId}/players", requireAuth(handleTournamentRegistration))
+	http.HandleFunc("/api/tournaments/{tournamentId}/start", requireAuth(gameManager.handleTournamentStart))
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		serveWs(gameManager, w, r)
 	})
@@ -9,9 +9,19 @@ import (
 
 // MockDynamoDB provides an in-memory mock for DynamoDB operations
 type MockDynamoDB struct {
-	mu    sync.RWMutex
-	users map[string]CookieUser
-	games []CookieGame
+	mu           sync.RWMutex
+	users        map[string]CookieUser
+	games        []CookieGame
+	replays      map[string]CookieReplay
+	replayChunks map[string][]CookieReplayChunk
+	seasons      map[int]CookieSeason
+	seasonStats  map[int]map[string]CookieSeasonStats // SeasonID -> UserID -> stats
+	playerStats  map[string]CookiePlayerStats
+	headToHead   map[string]CookieHeadToHead             // PairID -> record
+	achievements map[string]map[string]CookieAchievement // UserID -> AchievementID -> unlock
+	friends      map[string]map[string]CookieFriend      // UserID -> FriendID -> side of the friendship
+	series       map[string]CookieSeries
+	tournaments  map[string]CookieTournament
 }
 
 // CookieUser represents a user in the mock database
@@ -21,8 +31,12 @@ type CookieUser struct {
 	Name    string `json:"name"`
 	Picture string `json:"picture"`
 	Score   int    `json:"score"`
+	Rating  int    `json:"rating"`
 }
 
+// defaultRating mirrors db.DefaultRating
+const defaultRating = 1000
+
 // CookieGame represents a game record in the mock database
 type CookieGame struct {
 	GameID          string `json:"gameId"`
@@ -38,6 +52,146 @@ type CookieGame struct {
 	PlayerPicture   string `json:"playerPicture"`
 	OpponentName    string `json:"opponentName"`
 	OpponentPicture string `json:"opponentPicture"`
+	Suspicious      bool   `json:"suspicious"`
+	Mode            string `json:"mode"`
+	Placement       int    `json:"placement"`
+	PlayerCount     int    `json:"playerCount"`
+	Team            string `json:"team,omitempty"`
+	TeamScore       int    `json:"teamScore,omitempty"`
+	RematchOf       string `json:"rematchOf,omitempty"`
+	Practice        bool   `json:"practice,omitempty"`
+}
+
+// CookieReplay represents a recorded game timeline in the mock database
+type CookieReplay struct {
+	GameID    string `json:"gameId"`
+	Player1ID string `json:"player1Id"`
+	Player2ID string `json:"player2Id"`
+	P1Name    string `json:"p1Name"`
+	P2Name    string `json:"p2Name"`
+	P1Picture string `json:"p1Picture"`
+	P2Picture string `json:"p2Picture"`
+	StartedAt int64  `json:"startedAt"`
+	Players   string `json:"players"`
+	Chunks    int    `json:"chunks"`
+}
+
+// CookieReplayChunk represents a part of a replay timeline in the mock database
+type CookieReplayChunk struct {
+	GameID string `json:"gameId"`
+	Chunk  int    `json:"chunk"`
+	Events string `json:"events"`
+}
+
+// CookieSeason represents a ranked season in the mock database
+type CookieSeason struct {
+	SeasonID int    `json:"seasonId"`
+	Name     string `json:"name"`
+	StartsAt int64  `json:"startsAt"`
+	EndsAt   int64  `json:"endsAt"`
+	Archived bool   `json:"archived"`
+}
+
+// CookieSeasonStats represents a player's record of one season in the mock database
+type CookieSeasonStats struct {
+	SeasonID  int    `json:"seasonId"`
+	UserID    string `json:"userId"`
+	Name      string `json:"name"`
+	Picture   string `json:"picture"`
+	Score     int    `json:"score"`
+	Rating    int    `json:"rating"`
+	Games     int    `json:"games"`
+	Wins      int    `json:"wins"`
+	FinalRank int    `json:"finalRank"`
+}
+
+// CookiePlayerStats represents a player's lifetime statistics in the mock database
+type CookiePlayerStats struct {
+	UserID        string `json:"userId"`
+	Games         int    `json:"games"`
+	Wins          int    `json:"wins"`
+	Losses        int    `json:"losses"`
+	Draws         int    `json:"draws"`
+	TotalScore    int    `json:"totalScore"`
+	BestScore     int    `json:"bestScore"`
+	TotalClicks   int    `json:"totalClicks"`
+	TotalSeconds  int    `json:"totalSeconds"`
+	CurrentStreak int    `json:"currentStreak"`
+	LongestStreak int    `json:"longestStreak"`
+	GoldenCookies int    `json:"goldenCookies"`
+}
+
+// CookieHeadToHead represents the record of two players against each other in the mock database
+type CookieHeadToHead struct {
+	PairID          string `json:"pairId"`
+	PlayerA         string `json:"playerA"`
+	PlayerB         string `json:"playerB"`
+	Games           int    `json:"games"`
+	WinsA           int    `json:"winsA"`
+	WinsB           int    `json:"winsB"`
+	Draws           int    `json:"draws"`
+	LastPlayed      int64  `json:"lastPlayed"`
+	BiggestMargin   int    `json:"biggestMargin"`
+	BiggestMarginBy string `json:"biggestMarginBy"`
+}
+
+// CookieAchievement represents an unlocked achievement in the mock database
+type CookieAchievement struct {
+	UserID        string `json:"userId"`
+	AchievementID string `json:"achievementId"`
+	GameID        string `json:"gameId"`
+	UnlockedAt    int64  `json:"unlockedAt"`
+}
+
+// CookieFriend represents one side of a friendship in the mock database
+type CookieFriend struct {
+	UserID   string `json:"userId"`
+	FriendID string `json:"friendId"`
+	Status   string `json:"status"`
+	Since    int64  `json:"since"`
+}
+
+// CookieSeries represents a finished best-of-N series in the mock database
+type CookieSeries struct {
+	SeriesID  string         `json:"seriesId"`
+	BestOf    int            `json:"bestOf"`
+	Mode      string         `json:"mode"`
+	PlayerIDs []string       `json:"playerIds"`
+	Names     []string       `json:"names"`
+	Wins      map[string]int `json:"wins"`
+	Draws     int            `json:"draws"`
+	GameIDs   []string       `json:"gameIds"`
+	WinnerID  string         `json:"winnerId"`
+	Reason    string         `json:"reason"`
+	StartedAt int64          `json:"startedAt"`
+	EndedAt   int64          `json:"endedAt"`
+}
+
+// CookieTournament represents a tournament bracket in the mock database
+type CookieTournament struct {
+	TournamentID string `json:"tournamentId"`
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	CreatedAt    int64  `json:"createdAt"`
+	Version      int    `json:"version"`
+	Bracket      string `json:"bracket"`
+}
+
+// Friendship states, mirroring the db package
+const (
+	friendOutgoing = "outgoing"
+	friendIncoming = "incoming"
+	friendAccepted = "accepted"
+)
+
+// PlayerGame mirrors db.PlayerGame
+type PlayerGame struct {
+	Score         int
+	Clicks        int
+	Seconds       int
+	GoldenCookies int
+	Won           bool
+	Draw          bool
 }
 
 var mockDynamoInstance *MockDynamoDB
@@ -47,8 +201,18 @@ var mockDynamoOnce sync.Once
 func GetMockDynamoDB() *MockDynamoDB {
 	mockDynamoOnce.Do(func() {
 		mockDynamoInstance = &MockDynamoDB{
-			users: make(map[string]CookieUser),
-			games: make([]CookieGame, 0),
+			users:        make(map[string]CookieUser),
+			games:        make([]CookieGame, 0),
+			replays:      make(map[string]CookieReplay),
+			replayChunks: make(map[string][]CookieReplayChunk),
+			seasons:      make(map[int]CookieSeason),
+			seasonStats:  make(map[int]map[string]CookieSeasonStats),
+			playerStats:  make(map[string]CookiePlayerStats),
+			headToHead:   make(map[string]CookieHeadToHead),
+			achievements: make(map[string]map[string]CookieAchievement),
+			friends:      make(map[string]map[string]CookieFriend),
+			series:       make(map[string]CookieSeries),
+			tournaments:  make(map[string]CookieTournament),
 		}
 		// Add some sample data for local development
 		mockDynamoInstance.seedData()
@@ -61,9 +225,9 @@ func GetMockDynamoDB() *MockDynamoDB {
 func (m *MockDynamoDB) seedData() {
 	// Sample users
 	sampleUsers := []CookieUser{
-		{UserID: "mock-user-1", Email: "alice@example.com", Name: "Alice Baker", Picture: "", Score: 1500},
-		{UserID: "mock-user-2", Email: "bob@example.com", Name: "Bob Chef", Picture: "", Score: 1200},
-		{UserID: "mock-user-3", Email: "charlie@example.com", Name: "Charlie Cook", Picture: "", Score: 900},
+		{UserID: "mock-user-1", Email: "alice@example.com", Name: "Alice Baker", Picture: "", Score: 1500, Rating: 1080},
+		{UserID: "mock-user-2", Email: "bob@example.com", Name: "Bob Chef", Picture: "", Score: 1200, Rating: 1010},
+		{UserID: "mock-user-3", Email: "charlie@example.com", Name: "Charlie Cook", Picture: "", Score: 900, Rating: 970},
 	}
 	for _, u := range sampleUsers {
 		m.users[u.UserID] = u
@@ -105,8 +269,11 @@ func (m *MockDynamoDB) SaveUser(user CookieUser) error {
 
 	existing, exists := m.users[user.UserID]
 	if exists {
-		// Preserve score when updating
+		// Preserve score and rating when updating
 		user.Score = existing.Score
+		user.Rating = existing.Rating
+	} else if user.Rating == 0 {
+		user.Rating = defaultRating
 	}
 	m.users[user.UserID] = user
 	return nil
@@ -145,6 +312,59 @@ func (m *MockDynamoDB) GetTopUsers(limit int) ([]CookieUser, error) {
 	return users[:limit], nil
 }
 
+// ListUsers returns all users in no particular order
+func (m *MockDynamoDB) ListUsers() ([]CookieUser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := make([]CookieUser, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// GetTopUsersByRating returns the top users by rating
+func (m *MockDynamoDB) GetTopUsersByRating(limit int) ([]CookieUser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := make([]CookieUser, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, u)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return effectiveRating(users[i]) > effectiveRating(users[j])
+	})
+
+	if limit > len(users) {
+		limit = len(users)
+	}
+	return users[:limit], nil
+}
+
+// SetUserRating stores a user's rating
+func (m *MockDynamoDB) SetUserRating(userID string, rating int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, exists := m.users[userID]
+	if !exists {
+		return nil
+	}
+	user.Rating = rating
+	m.users[userID] = user
+	return nil
+}
+
+func effectiveRating(u CookieUser) int {
+	if u.Rating == 0 {
+		return defaultRating
+	}
+	return u.Rating
+}
+
 // IncrementUserScore increments a user's score
 func (m *MockDynamoDB) IncrementUserScore(userID string, delta int) error {
 	m.mu.Lock()
@@ -170,11 +390,9 @@ func (m *MockDynamoDB) SaveGame(game CookieGame) error {
 	return nil
 }
 
-// GetGamesByPlayer returns games for a specific player, sorted by timestamp descending
-func (m *MockDynamoDB) GetGamesByPlayer(playerID string, limit int) ([]CookieGame, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
+// playerGamesLocked returns the games of a player, sorted by timestamp descending and
+// ties by GameID descending. Caller holds m.mu.
+func (m *MockDynamoDB) playerGamesLocked(playerID string) []CookieGame {
 	playerGames := make([]CookieGame, 0)
 	for _, g := range m.games {
 		if g.PlayerID == playerID {
@@ -182,17 +400,49 @@ func (m *MockDynamoDB) GetGamesByPlayer(playerID string, limit int) ([]CookieGam
 		}
 	}
 
-	// Sort by timestamp descending
 	sort.Slice(playerGames, func(i, j int) bool {
-		return playerGames[i].Timestamp > playerGames[j].Timestamp
+		if playerGames[i].Timestamp != playerGames[j].Timestamp {
+			return playerGames[i].Timestamp > playerGames[j].Timestamp
+		}
+		return playerGames[i].GameID > playerGames[j].GameID
 	})
+	return playerGames
+}
 
+// GetGamesByPlayer returns games for a specific player, sorted by timestamp descending
+func (m *MockDynamoDB) GetGamesByPlayer(playerID string, limit int) ([]CookieGame, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	playerGames := m.playerGamesLocked(playerID)
 	if limit > len(playerGames) {
 		limit = len(playerGames)
 	}
 	return playerGames[:limit], nil
 }
 
+// GetGamesByPlayerAfter returns up to limit games of a player that come after the game
+// with the given timestamp and GameID in history order. An empty gameID starts at the
+// newest game.
+func (m *MockDynamoDB) GetGamesByPlayerAfter(playerID string, limit int, timestamp int64, gameID string) ([]CookieGame, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	playerGames := m.playerGamesLocked(playerID)
+	start := 0
+	if gameID != "" {
+		for start < len(playerGames) {
+			g := playerGames[start]
+			if g.Timestamp < timestamp || (g.Timestamp == timestamp && g.GameID < gameID) {
+				break
+			}
+			start++
+		}
+	}
+	end := min(start+limit, len(playerGames))
+	return playerGames[start:end], nil
+}
+
 // GetRecentGames returns the most recent games across all players
 func (m *MockDynamoDB) GetRecentGames(limit int) ([]CookieGame, error) {
 	m.mu.RLock()
@@ -230,6 +480,20 @@ func (m *MockDynamoDB) GetUserStats(userID string) (wins int, losses int, totalG
 	return
 }
 
+// ListGamesSince returns the games of all players since the given Unix time
+func (m *MockDynamoDB) ListGamesSince(since int64) ([]CookieGame, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	games := make([]CookieGame, 0)
+	for _, g := range m.games {
+		if g.Timestamp >= since {
+			games = append(games, g)
+		}
+	}
+	return games, nil
+}
+
 // CountGamesByPlayer returns the total number of games for a player
 func (m *MockDynamoDB) CountGamesByPlayer(playerID string) int {
 	m.mu.RLock()
@@ -243,3 +507,379 @@ func (m *MockDynamoDB) CountGamesByPlayer(playerID string) int {
 	}
 	return count
 }
+
+// --- Replay Operations ---
+
+// SaveReplay stores (or replaces) the replay of a game with its timeline chunks
+func (m *MockDynamoDB) SaveReplay(replay CookieReplay, chunks []CookieReplayChunk) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	replay.Chunks = len(chunks)
+	m.replays[replay.GameID] = replay
+	m.replayChunks[replay.GameID] = append([]CookieReplayChunk(nil), chunks...)
+	return nil
+}
+
+// GetReplay returns the replay of a game and its timeline chunks in order, nil if
+// none exists
+func (m *MockDynamoDB) GetReplay(gameID string) (*CookieReplay, []CookieReplayChunk, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	replay, ok := m.replays[gameID]
+	if !ok {
+		return nil, nil, nil
+	}
+	chunks := append([]CookieReplayChunk(nil), m.replayChunks[gameID]...)
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Chunk < chunks[j].Chunk })
+	return &replay, chunks, nil
+}
+
+// --- Season Operations ---
+
+// CreateSeason stores a new season; returns false if the season ID is taken
+func (m *MockDynamoDB) CreateSeason(season CookieSeason) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.seasons[season.SeasonID]; exists {
+		return false, nil
+	}
+	m.seasons[season.SeasonID] = season
+	return true, nil
+}
+
+// ListSeasons returns all seasons, oldest first
+func (m *MockDynamoDB) ListSeasons() ([]CookieSeason, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seasons := make([]CookieSeason, 0, len(m.seasons))
+	for _, s := range m.seasons {
+		seasons = append(seasons, s)
+	}
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].SeasonID < seasons[j].SeasonID
+	})
+	return seasons, nil
+}
+
+// UpdateSeasonStats adds the result of one game to a player's season record
+func (m *MockDynamoDB) UpdateSeasonStats(seasonID int, userID, name, picture string, score int, won bool, rating int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.seasonStats[seasonID] == nil {
+		m.seasonStats[seasonID] = make(map[string]CookieSeasonStats)
+	}
+	stats := m.seasonStats[seasonID][userID]
+	stats.SeasonID, stats.UserID = seasonID, userID
+	stats.Name, stats.Picture, stats.Rating = name, picture, rating
+	stats.Score += score
+	stats.Games++
+	if won {
+		stats.Wins++
+	}
+	m.seasonStats[seasonID][userID] = stats
+	return nil
+}
+
+// GetSeasonStats returns the records of all players of a season, ordered by score
+func (m *MockDynamoDB) GetSeasonStats(seasonID int) ([]CookieSeasonStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make([]CookieSeasonStats, 0, len(m.seasonStats[seasonID]))
+	for _, s := range m.seasonStats[seasonID] {
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Score != stats[j].Score {
+			return stats[i].Score > stats[j].Score
+		}
+		return stats[i].UserID < stats[j].UserID
+	})
+	return stats, nil
+}
+
+// ArchiveSeason stores the final ranks of a season and marks it as archived
+func (m *MockDynamoDB) ArchiveSeason(seasonID int, ranks map[string]int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for userID, rank := range ranks {
+		if stats, ok := m.seasonStats[seasonID][userID]; ok {
+			stats.FinalRank = rank
+			m.seasonStats[seasonID][userID] = stats
+		}
+	}
+	if season, ok := m.seasons[seasonID]; ok {
+		season.Archived = true
+		m.seasons[seasonID] = season
+	}
+	return nil
+}
+
+// --- Player Stats Operations ---
+
+// UpdatePlayerStats adds a finished game to a player's lifetime statistics
+func (m *MockDynamoDB) UpdatePlayerStats(userID string, game PlayerGame) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.playerStats[userID]
+	stats.UserID = userID
+	stats.Games++
+	switch {
+	case game.Won:
+		stats.Wins++
+		stats.CurrentStreak++
+	case game.Draw:
+		stats.Draws++
+		stats.CurrentStreak = 0
+	default:
+		stats.Losses++
+		stats.CurrentStreak = 0
+	}
+	stats.TotalScore += game.Score
+	stats.TotalClicks += game.Clicks
+	stats.TotalSeconds += game.Seconds
+	stats.GoldenCookies += game.GoldenCookies
+	stats.BestScore = max(stats.BestScore, game.Score)
+	stats.LongestStreak = max(stats.LongestStreak, stats.CurrentStreak)
+	m.playerStats[userID] = stats
+	return nil
+}
+
+// GetPlayerStats returns a player's lifetime statistics, nil if they never played
+func (m *MockDynamoDB) GetPlayerStats(userID string) (*CookiePlayerStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats, ok := m.playerStats[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &stats, nil
+}
+
+// --- Head-to-Head Operations ---
+
+// UpdateHeadToHead adds a finished duel between two players to their head-to-head
+// record. winnerID is one of the players, anything else counts as a draw.
+func (m *MockDynamoDB) UpdateHeadToHead(userA, userB string, scoreA, scoreB int, winnerID string, timestamp int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Same pair order as db.HeadToHeadID
+	if userB < userA {
+		userA, userB = userB, userA
+		scoreA, scoreB = scoreB, scoreA
+	}
+	pairID := userA + "#" + userB
+
+	record := m.headToHead[pairID]
+	record.PairID, record.PlayerA, record.PlayerB = pairID, userA, userB
+	record.Games++
+	record.LastPlayed = timestamp
+	margin := 0
+	switch winnerID {
+	case userA:
+		record.WinsA++
+		margin = scoreA - scoreB
+	case userB:
+		record.WinsB++
+		margin = scoreB - scoreA
+	default:
+		record.Draws++
+	}
+	if margin > record.BiggestMargin {
+		record.BiggestMargin, record.BiggestMarginBy = margin, winnerID
+	}
+	m.headToHead[pairID] = record
+	return nil
+}
+
+// GetHeadToHead returns the head-to-head record of two players, nil if they never met
+func (m *MockDynamoDB) GetHeadToHead(userA, userB string) (*CookieHeadToHead, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if userB < userA {
+		userA, userB = userB, userA
+	}
+	record, ok := m.headToHead[userA+"#"+userB]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// --- Achievement Operations ---
+
+// UnlockAchievement stores an unlocked achievement, false if the player already had it
+func (m *MockDynamoDB) UnlockAchievement(achievement CookieAchievement) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	unlocked, ok := m.achievements[achievement.UserID]
+	if !ok {
+		unlocked = make(map[string]CookieAchievement)
+		m.achievements[achievement.UserID] = unlocked
+	}
+	if _, exists := unlocked[achievement.AchievementID]; exists {
+		return false, nil
+	}
+	unlocked[achievement.AchievementID] = achievement
+	return true, nil
+}
+
+// GetAchievements returns all achievements a player has unlocked, in unlock order
+func (m *MockDynamoDB) GetAchievements(userID string) ([]CookieAchievement, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	achievements := make([]CookieAchievement, 0, len(m.achievements[userID]))
+	for _, a := range m.achievements[userID] {
+		achievements = append(achievements, a)
+	}
+	sort.Slice(achievements, func(i, j int) bool {
+		if achievements[i].UnlockedAt != achievements[j].UnlockedAt {
+			return achievements[i].UnlockedAt < achievements[j].UnlockedAt
+		}
+		return achievements[i].AchievementID < achievements[j].AchievementID
+	})
+	return achievements, nil
+}
+
+// --- Friend Operations ---
+
+// putFriendLocked stores one side of a friendship; caller must hold m.mu
+func (m *MockDynamoDB) putFriendLocked(f CookieFriend) {
+	if m.friends[f.UserID] == nil {
+		m.friends[f.UserID] = make(map[string]CookieFriend)
+	}
+	m.friends[f.UserID][f.FriendID] = f
+}
+
+// AddFriendRequest stores a friend request, false if the players are already linked
+func (m *MockDynamoDB) AddFriendRequest(fromID, toID string, timestamp int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.friends[fromID][toID]; exists {
+		return false, nil
+	}
+	if _, exists := m.friends[toID][fromID]; exists {
+		return false, nil
+	}
+	m.putFriendLocked(CookieFriend{UserID: fromID, FriendID: toID, Status: friendOutgoing, Since: timestamp})
+	m.putFriendLocked(CookieFriend{UserID: toID, FriendID: fromID, Status: friendIncoming, Since: timestamp})
+	return true, nil
+}
+
+// AcceptFriendRequest turns a pending request from fromID into a friendship, false if there is none
+func (m *MockDynamoDB) AcceptFriendRequest(userID, fromID string, timestamp int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	incoming, ok := m.friends[userID][fromID]
+	if !ok || incoming.Status != friendIncoming {
+		return false, nil
+	}
+	m.putFriendLocked(CookieFriend{UserID: userID, FriendID: fromID, Status: friendAccepted, Since: timestamp})
+	m.putFriendLocked(CookieFriend{UserID: fromID, FriendID: userID, Status: friendAccepted, Since: timestamp})
+	return true, nil
+}
+
+// RemoveFriend deletes a friendship or pending request from both sides
+func (m *MockDynamoDB) RemoveFriend(userID, friendID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.friends[userID], friendID)
+	delete(m.friends[friendID], userID)
+	return nil
+}
+
+// ListFriends returns all friendships and pending requests of a player, ordered by friend ID
+func (m *MockDynamoDB) ListFriends(userID string) ([]CookieFriend, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	friends := make([]CookieFriend, 0, len(m.friends[userID]))
+	for _, f := range m.friends[userID] {
+		friends = append(friends, f)
+	}
+	sort.Slice(friends, func(i, j int) bool {
+		return friends[i].FriendID < friends[j].FriendID
+	})
+	return friends, nil
+}
+
+// --- Series Operations ---
+
+// SaveSeries stores (or replaces) a series record
+func (m *MockDynamoDB) SaveSeries(series CookieSeries) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.series[series.SeriesID] = series
+	return nil
+}
+
+// GetSeries returns a series record, nil if none exists
+func (m *MockDynamoDB) GetSeries(seriesID string) (*CookieSeries, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	series, ok := m.series[seriesID]
+	if !ok {
+		return nil, nil
+	}
+	return &series, nil
+}
+
+// --- Tournament Operations ---
+
+// SaveTournament stores a tournament, false if the stored version is not the one
+// before tournament.Version
+func (m *MockDynamoDB) SaveTournament(tournament CookieTournament) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, exists := m.tournaments[tournament.TournamentID]
+	if exists && current.Version != tournament.Version-1 || !exists && tournament.Version > 1 {
+		return false, nil
+	}
+	m.tournaments[tournament.TournamentID] = tournament
+	return true, nil
+}
+
+// GetTournament returns a tournament, nil if none exists
+func (m *MockDynamoDB) GetTournament(tournamentID string) (*CookieTournament, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tournament, ok := m.tournaments[tournamentID]
+	if !ok {
+		return nil, nil
+	}
+	return &tournament, nil
+}
+
+// ListTournaments returns all tournaments, newest first
+func (m *MockDynamoDB) ListTournaments() ([]CookieTournament, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tournaments := make([]CookieTournament, 0, len(m.tournaments))
+	for _, t := range m.tournaments {
+		tournaments = append(tournaments, t)
+	}
+	sort.Slice(tournaments, func(i, j int) bool {
+		return tournaments[i].CreatedAt > tournaments[j].CreatedAt
+	})
+	return tournaments, nil
+}
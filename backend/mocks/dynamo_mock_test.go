@@ -8,8 +8,18 @@ import (
 // resetMockDynamoDB creates a fresh MockDynamoDB instance for testing
 func newTestMockDynamoDB() *MockDynamoDB {
 	return &MockDynamoDB{
-		users: make(map[string]CookieUser),
-		games: make([]CookieGame, 0),
+		users:        make(map[string]CookieUser),
+		games:        make([]CookieGame, 0),
+		replays:      make(map[string]CookieReplay),
+		replayChunks: make(map[string][]CookieReplayChunk),
+		seasons:      make(map[int]CookieSeason),
+		seasonStats:  make(map[int]map[string]CookieSeasonStats),
+		playerStats:  make(map[string]CookiePlayerStats),
+		headToHead:   make(map[string]CookieHeadToHead),
+		achievements: make(map[string]map[string]CookieAchievement),
+		friends:      make(map[string]map[string]CookieFriend),
+		series:       make(map[string]CookieSeries),
+		tournaments:  make(map[string]CookieTournament),
 	}
 }
 
@@ -129,6 +139,50 @@ func TestGetTopUsers(t *testing.T) {
 	}
 }
 
+func TestSaveUser_DefaultRating(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	db.SaveUser(CookieUser{UserID: "new-user", Name: "New"})
+
+	retrieved, _ := db.GetUser("new-user")
+	if retrieved.Rating != defaultRating {
+		t.Errorf("New user should start with rating %d, got %d", defaultRating, retrieved.Rating)
+	}
+
+	// Logging in again must not reset the rating
+	db.SetUserRating("new-user", 1234)
+	db.SaveUser(CookieUser{UserID: "new-user", Name: "New"})
+	retrieved, _ = db.GetUser("new-user")
+	if retrieved.Rating != 1234 {
+		t.Errorf("Rating was not preserved: got %d, want 1234", retrieved.Rating)
+	}
+}
+
+func TestGetTopUsersByRating(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	users := []CookieUser{
+		{UserID: "user1", Score: 900, Rating: 1100},
+		{UserID: "user2", Score: 100, Rating: 1300},
+		{UserID: "user3", Score: 500, Rating: 0}, // Legacy user without rating
+	}
+	for _, u := range users {
+		db.users[u.UserID] = u
+	}
+
+	top, err := db.GetTopUsersByRating(3)
+	if err != nil {
+		t.Fatalf("GetTopUsersByRating failed: %v", err)
+	}
+
+	expected := []string{"user2", "user1", "user3"}
+	for i, id := range expected {
+		if top[i].UserID != id {
+			t.Errorf("Position %d: expected %s, got %s", i, id, top[i].UserID)
+		}
+	}
+}
+
 func TestGetTopUsers_LimitExceedsTotal(t *testing.T) {
 	db := newTestMockDynamoDB()
 
@@ -213,6 +267,26 @@ func TestSaveAndGetGame(t *testing.T) {
 	}
 }
 
+func TestSaveGame_Placement(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	db.SaveGame(CookieGame{
+		GameID: "ffa-1", PlayerID: "player-3", Timestamp: 1706500000,
+		Score: 80, Mode: "ffa", Placement: 3, PlayerCount: 5,
+	})
+
+	games, err := db.GetGamesByPlayer("player-3", 10)
+	if err != nil {
+		t.Fatalf("GetGamesByPlayer failed: %v", err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("Expected 1 game, got %d", len(games))
+	}
+	if games[0].Mode != "ffa" || games[0].Placement != 3 || games[0].PlayerCount != 5 {
+		t.Errorf("Placement mismatch: got mode %s, placement %d of %d", games[0].Mode, games[0].Placement, games[0].PlayerCount)
+	}
+}
+
 func TestGetGamesByPlayer_SortedByTimestamp(t *testing.T) {
 	db := newTestMockDynamoDB()
 
@@ -237,6 +311,31 @@ func TestGetGamesByPlayer_SortedByTimestamp(t *testing.T) {
 	}
 }
 
+func TestGetGamesByPlayerAfter(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	games := []CookieGame{
+		{GameID: "game-1", PlayerID: "player-1", Timestamp: 1000},
+		{GameID: "game-2", PlayerID: "player-1", Timestamp: 2000},
+		{GameID: "game-3", PlayerID: "player-1", Timestamp: 2000},
+		{GameID: "game-4", PlayerID: "player-2", Timestamp: 1500},
+	}
+	for _, g := range games {
+		db.SaveGame(g)
+	}
+
+	first, _ := db.GetGamesByPlayerAfter("player-1", 2, 0, "")
+	if len(first) != 2 || first[0].GameID != "game-3" || first[1].GameID != "game-2" {
+		t.Fatalf("Unexpected first page: %v", first)
+	}
+
+	last := first[len(first)-1]
+	second, _ := db.GetGamesByPlayerAfter("player-1", 2, last.Timestamp, last.GameID)
+	if len(second) != 1 || second[0].GameID != "game-1" {
+		t.Errorf("Unexpected second page: %v", second)
+	}
+}
+
 func TestCountGamesByPlayer(t *testing.T) {
 	db := newTestMockDynamoDB()
 
@@ -308,3 +407,270 @@ func TestConcurrentUserOperations(t *testing.T) {
 		t.Errorf("Concurrent score updates failed: got %d, want %d", user.Score, iterations)
 	}
 }
+
+func TestSaveAndGetReplay(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	replay := CookieReplay{
+		GameID:    "game-1",
+		Player1ID: "user-1",
+		Player2ID: "user-2",
+		StartedAt: 1700000000000,
+	}
+	chunks := []CookieReplayChunk{
+		{GameID: "game-1", Chunk: 2, Events: `[{"t":500,"type":"CLICK","player":"p2"}]`},
+		{GameID: "game-1", Chunk: 1, Events: `[{"t":0,"type":"CLICK","player":"p1"}]`},
+	}
+	if err := db.SaveReplay(replay, chunks); err != nil {
+		t.Fatalf("SaveReplay failed: %v", err)
+	}
+
+	got, gotChunks, err := db.GetReplay("game-1")
+	if err != nil {
+		t.Fatalf("GetReplay failed: %v", err)
+	}
+	if got == nil {
+		t.Fatal("Expected replay to exist")
+	}
+	if got.Chunks != 2 || got.Player2ID != "user-2" {
+		t.Errorf("Replay mismatch: got %+v", got)
+	}
+	if len(gotChunks) != 2 || gotChunks[0].Chunk != 1 || gotChunks[1].Events != chunks[0].Events {
+		t.Errorf("Expected chunks in order, got %+v", gotChunks)
+	}
+
+	missing, missingChunks, err := db.GetReplay("missing")
+	if err != nil || missing != nil || missingChunks != nil {
+		t.Errorf("Expected nil replay for unknown game, got %+v (err %v)", missing, err)
+	}
+}
+
+func TestCreateSeason_Duplicate(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	created, err := db.CreateSeason(CookieSeason{SeasonID: 1, Name: "Season 1", StartsAt: 100, EndsAt: 200})
+	if err != nil || !created {
+		t.Fatalf("Expected season to be created, got %v (err %v)", created, err)
+	}
+	created, _ = db.CreateSeason(CookieSeason{SeasonID: 1, Name: "Other", StartsAt: 150, EndsAt: 250})
+	if created {
+		t.Error("Expected duplicate season to be rejected")
+	}
+	db.CreateSeason(CookieSeason{SeasonID: 2, Name: "Season 2", StartsAt: 200, EndsAt: 300})
+
+	seasons, _ := db.ListSeasons()
+	if len(seasons) != 2 || seasons[0].SeasonID != 1 || seasons[0].Name != "Season 1" {
+		t.Errorf("Unexpected seasons: %+v", seasons)
+	}
+}
+
+func TestSeasonStats(t *testing.T) {
+	db := newTestMockDynamoDB()
+	db.CreateSeason(CookieSeason{SeasonID: 1})
+
+	db.UpdateSeasonStats(1, "user-1", "Alice", "", 50, true, 1016)
+	db.UpdateSeasonStats(1, "user-1", "Alice", "", 30, false, 1001)
+	db.UpdateSeasonStats(1, "user-2", "Bob", "", 120, true, 1020)
+	db.UpdateSeasonStats(2, "user-1", "Alice", "", 10, true, 1010)
+
+	stats, _ := db.GetSeasonStats(1)
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 players in season 1, got %d", len(stats))
+	}
+	if stats[0].UserID != "user-2" {
+		t.Errorf("Expected highest score first, got %s", stats[0].UserID)
+	}
+	alice := stats[1]
+	if alice.Score != 80 || alice.Games != 2 || alice.Wins != 1 || alice.Rating != 1001 {
+		t.Errorf("Season stats not accumulated: %+v", alice)
+	}
+
+	db.ArchiveSeason(1, map[string]int{"user-2": 1, "user-1": 2})
+	stats, _ = db.GetSeasonStats(1)
+	if stats[0].FinalRank != 1 || stats[1].FinalRank != 2 {
+		t.Errorf("Final ranks not stored: %+v", stats)
+	}
+	seasons, _ := db.ListSeasons()
+	if !seasons[0].Archived {
+		t.Error("Expected season to be archived")
+	}
+}
+
+func TestPlayerStats(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	if stats, _ := db.GetPlayerStats("user-1"); stats != nil {
+		t.Errorf("Expected no stats before the first game, got %+v", stats)
+	}
+
+	db.UpdatePlayerStats("user-1", PlayerGame{Score: 100, Clicks: 90, Seconds: 60, GoldenCookies: 1, Won: true})
+	db.UpdatePlayerStats("user-1", PlayerGame{Score: 140, Clicks: 120, Seconds: 60, Won: true})
+	db.UpdatePlayerStats("user-1", PlayerGame{Score: 80, Clicks: 70, Seconds: 60, GoldenCookies: 2})
+	db.UpdatePlayerStats("user-1", PlayerGame{Score: 90, Clicks: 80, Seconds: 60, Won: true})
+	db.UpdatePlayerStats("user-1", PlayerGame{Score: 90, Clicks: 80, Seconds: 60, Draw: true})
+
+	stats, _ := db.GetPlayerStats("user-1")
+	if stats == nil {
+		t.Fatal("Expected stats after playing")
+	}
+	if stats.Games != 5 || stats.Wins != 3 || stats.Losses != 1 || stats.Draws != 1 {
+		t.Errorf("Unexpected record: %+v", stats)
+	}
+	if stats.TotalScore != 500 || stats.BestScore != 140 || stats.TotalClicks != 440 || stats.TotalSeconds != 300 || stats.GoldenCookies != 3 {
+		t.Errorf("Unexpected totals: %+v", stats)
+	}
+	if stats.CurrentStreak != 0 || stats.LongestStreak != 2 {
+		t.Errorf("Expected streaks 0/2, got %d/%d", stats.CurrentStreak, stats.LongestStreak)
+	}
+}
+
+func TestHeadToHead(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	if record, _ := db.GetHeadToHead("user-2", "user-1"); record != nil {
+		t.Errorf("Expected no record before the first duel, got %+v", record)
+	}
+
+	db.UpdateHeadToHead("user-2", "user-1", 100, 80, "user-2", 1000)
+	db.UpdateHeadToHead("user-1", "user-2", 150, 90, "user-1", 2000)
+	db.UpdateHeadToHead("user-1", "user-2", 70, 70, "draw", 3000)
+	db.UpdateHeadToHead("user-2", "user-1", 50, 110, "user-2", 4000) // user-1 forfeited
+
+	record, _ := db.GetHeadToHead("user-2", "user-1")
+	if record == nil {
+		t.Fatal("Expected a record after playing")
+	}
+	if record.PlayerA != "user-1" || record.PlayerB != "user-2" {
+		t.Errorf("Expected players in stored order, got %s/%s", record.PlayerA, record.PlayerB)
+	}
+	if record.Games != 4 || record.WinsA != 1 || record.WinsB != 2 || record.Draws != 1 {
+		t.Errorf("Unexpected record: %+v", record)
+	}
+	if record.LastPlayed != 4000 {
+		t.Errorf("Expected last played 4000, got %d", record.LastPlayed)
+	}
+	if record.BiggestMargin != 60 || record.BiggestMarginBy != "user-1" {
+		t.Errorf("Expected biggest margin 60 by user-1, got %d by %s", record.BiggestMargin, record.BiggestMarginBy)
+	}
+}
+
+func TestAchievements(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	if created, _ := db.UnlockAchievement(CookieAchievement{UserID: "user-1", AchievementID: "first_win", GameID: "game-1", UnlockedAt: 100}); !created {
+		t.Error("Expected first unlock to be stored")
+	}
+	if created, _ := db.UnlockAchievement(CookieAchievement{UserID: "user-1", AchievementID: "first_win", GameID: "game-2", UnlockedAt: 200}); created {
+		t.Error("Expected second unlock of the same achievement to be ignored")
+	}
+	db.UnlockAchievement(CookieAchievement{UserID: "user-1", AchievementID: "golden_touch", GameID: "game-2", UnlockedAt: 200})
+
+	achievements, _ := db.GetAchievements("user-1")
+	if len(achievements) != 2 {
+		t.Fatalf("Expected 2 achievements, got %d", len(achievements))
+	}
+	if achievements[0].AchievementID != "first_win" || achievements[0].GameID != "game-1" {
+		t.Errorf("Expected the original first_win unlock first, got %+v", achievements[0])
+	}
+
+	if others, _ := db.GetAchievements("user-2"); len(others) != 0 {
+		t.Errorf("Expected no achievements for user-2, got %d", len(others))
+	}
+}
+
+func TestFriends(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	if created, _ := db.AddFriendRequest("user-1", "user-2", 100); !created {
+		t.Fatal("Expected the friend request to be stored")
+	}
+	if created, _ := db.AddFriendRequest("user-2", "user-1", 110); created {
+		t.Error("Expected a request in the other direction to be rejected")
+	}
+	if accepted, _ := db.AcceptFriendRequest("user-1", "user-2", 120); accepted {
+		t.Error("Expected the sender to be unable to accept their own request")
+	}
+
+	incoming, _ := db.ListFriends("user-2")
+	if len(incoming) != 1 || incoming[0].FriendID != "user-1" || incoming[0].Status != friendIncoming {
+		t.Fatalf("Expected an incoming request from user-1, got %+v", incoming)
+	}
+
+	if accepted, _ := db.AcceptFriendRequest("user-2", "user-1", 130); !accepted {
+		t.Fatal("Expected the request to be accepted")
+	}
+	for _, userID := range []string{"user-1", "user-2"} {
+		friends, _ := db.ListFriends(userID)
+		if len(friends) != 1 || friends[0].Status != friendAccepted || friends[0].Since != 130 {
+			t.Errorf("Expected an accepted friendship for %s, got %+v", userID, friends)
+		}
+	}
+
+	db.RemoveFriend("user-2", "user-1")
+	if friends, _ := db.ListFriends("user-1"); len(friends) != 0 {
+		t.Errorf("Expected the friendship to be removed from both sides, got %+v", friends)
+	}
+}
+
+func TestSaveAndGetSeries(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	series := CookieSeries{
+		SeriesID:  "series-1",
+		BestOf:    3,
+		Mode:      "duel",
+		PlayerIDs: []string{"user-1", "user-2"},
+		Wins:      map[string]int{"user-1": 2, "user-2": 1},
+		GameIDs:   []string{"game-1", "game-2", "game-3"},
+		WinnerID:  "user-1",
+		Reason:    "normal",
+	}
+	if err := db.SaveSeries(series); err != nil {
+		t.Fatalf("SaveSeries failed: %v", err)
+	}
+
+	got, err := db.GetSeries("series-1")
+	if err != nil {
+		t.Fatalf("GetSeries failed: %v", err)
+	}
+	if got == nil || got.WinnerID != "user-1" || len(got.GameIDs) != 3 || got.Wins["user-2"] != 1 {
+		t.Errorf("Series mismatch: got %+v", got)
+	}
+
+	missing, err := db.GetSeries("missing")
+	if err != nil || missing != nil {
+		t.Errorf("Expected nil series for unknown ID, got %+v (err %v)", missing, err)
+	}
+}
+
+func TestSaveTournamentVersioning(t *testing.T) {
+	db := newTestMockDynamoDB()
+
+	tournament := CookieTournament{TournamentID: "cup-1", Name: "Office Cup", Status: "registration", Version: 1}
+	if ok, err := db.SaveTournament(tournament); err != nil || !ok {
+		t.Fatalf("SaveTournament failed: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := db.SaveTournament(tournament); ok {
+		t.Error("Expected a second creation to be rejected")
+	}
+
+	tournament.Version = 2
+	tournament.Status = "running"
+	if ok, err := db.SaveTournament(tournament); err != nil || !ok {
+		t.Fatalf("SaveTournament update failed: ok=%v err=%v", ok, err)
+	}
+	// A pod that read version 1 lost the race
+	if ok, _ := db.SaveTournament(tournament); ok {
+		t.Error("Expected a stale update to be rejected")
+	}
+
+	got, err := db.GetTournament("cup-1")
+	if err != nil || got == nil || got.Status != "running" || got.Version != 2 {
+		t.Errorf("Tournament mismatch: got %+v (err %v)", got, err)
+	}
+
+	list, err := db.ListTournaments()
+	if err != nil || len(list) != 1 {
+		t.Errorf("Expected 1 tournament, got %d (err %v)", len(list), err)
+	}
+}
@@ -0,0 +1,364 @@
+package mocks
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MockKV provides an in-memory mock for plain Redis keys, sets, sorted sets, lists and
+// Pub/Sub channels (lobbies, parties, leaderboards, replays, ...) that don't need a
+// dedicated mock
+type MockKV struct {
+	mu          sync.Mutex
+	values      map[string]kvEntry
+	sets        map[string]map[string]bool
+	zsets       map[string]map[string]float64
+	zsetExpiry  map[string]time.Time
+	lists       map[string]kvList
+	subscribers map[string][]chan string
+}
+
+// ZMember is a member of a sorted set with its score
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+type kvEntry struct {
+	value     string
+	expiresAt time.Time // Zero means no expiry
+}
+
+func (e kvEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+type kvList struct {
+	items     []string
+	expiresAt time.Time // Zero means no expiry
+}
+
+var mockKVInstance *MockKV
+var mockKVOnce sync.Once
+
+// GetMockKV returns the singleton mock key-value store
+func GetMockKV() *MockKV {
+	mockKVOnce.Do(func() {
+		mockKVInstance = NewMockKV()
+		log.Println("[MOCK] In-memory key-value store initialized")
+	})
+	return mockKVInstance
+}
+
+// NewMockKV creates an empty key-value store
+func NewMockKV() *MockKV {
+	return &MockKV{
+		values:      make(map[string]kvEntry),
+		sets:        make(map[string]map[string]bool),
+		zsets:       make(map[string]map[string]float64),
+		zsetExpiry:  make(map[string]time.Time),
+		lists:       make(map[string]kvList),
+		subscribers: make(map[string][]chan string),
+	}
+}
+
+func expiryFor(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// getLocked returns a live value, dropping it if it has expired. Caller holds m.mu.
+func (m *MockKV) getLocked(key string) (string, bool) {
+	entry, ok := m.values[key]
+	if !ok {
+		return "", false
+	}
+	if entry.expired(time.Now()) {
+		delete(m.values, key)
+		return "", false
+	}
+	return entry.value, true
+}
+
+// Get returns the value stored at key and whether it exists
+func (m *MockKV) Get(key string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.getLocked(key)
+}
+
+// Set stores a value with an optional TTL (0 = no expiry)
+func (m *MockKV) Set(key, value string, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = kvEntry{value: value, expiresAt: expiryFor(ttl)}
+}
+
+// SetNX stores a value only if the key does not exist yet
+func (m *MockKV) SetNX(key, value string, ttl time.Duration) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.getLocked(key); exists {
+		return false
+	}
+	m.values[key] = kvEntry{value: value, expiresAt: expiryFor(ttl)}
+	return true
+}
+
+// Del removes keys (values, sets, sorted sets and lists)
+func (m *MockKV) Del(keys ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.values, key)
+		delete(m.sets, key)
+		delete(m.zsets, key)
+		delete(m.zsetExpiry, key)
+		delete(m.lists, key)
+	}
+}
+
+// Update atomically replaces the value at key with the result of fn.
+// Returning an empty string deletes the key; returning an error aborts the update.
+func (m *MockKV) Update(key string, ttl time.Duration, fn func(current string, found bool) (string, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, found := m.getLocked(key)
+	next, err := fn(current, found)
+	if err != nil {
+		return err
+	}
+
+	if next == "" {
+		delete(m.values, key)
+		return nil
+	}
+	m.values[key] = kvEntry{value: next, expiresAt: expiryFor(ttl)}
+	return nil
+}
+
+// SAdd adds members to a set
+func (m *MockKV) SAdd(key string, members ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set, ok := m.sets[key]
+	if !ok {
+		set = make(map[string]bool)
+		m.sets[key] = set
+	}
+	for _, member := range members {
+		set[member] = true
+	}
+}
+
+// SRem removes members from a set
+func (m *MockKV) SRem(key string, members ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set, ok := m.sets[key]
+	if !ok {
+		return
+	}
+	for _, member := range members {
+		delete(set, member)
+	}
+	if len(set) == 0 {
+		delete(m.sets, key)
+	}
+}
+
+// SMembers returns the sorted members of a set
+func (m *MockKV) SMembers(key string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members := make([]string, 0, len(m.sets[key]))
+	for member := range m.sets[key] {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// zsetLocked returns a live sorted set, dropping it if it has expired. Caller holds m.mu.
+func (m *MockKV) zsetLocked(key string) map[string]float64 {
+	if expiresAt, ok := m.zsetExpiry[key]; ok && time.Now().After(expiresAt) {
+		delete(m.zsets, key)
+		delete(m.zsetExpiry, key)
+	}
+	return m.zsets[key]
+}
+
+// ZAdd sets the score of sorted set members
+func (m *MockKV) ZAdd(key string, members ...ZMember) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.zsetLocked(key) == nil {
+		m.zsets[key] = make(map[string]float64)
+	}
+	for _, z := range members {
+		m.zsets[key][z.Member] = z.Score
+	}
+}
+
+// ZIncrBy adds delta to the score of a sorted set member (starting at 0) and returns the
+// new score. A positive ttl (re)sets the expiry of the set, 0 keeps it.
+func (m *MockKV) ZIncrBy(key, member string, delta float64, ttl time.Duration) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.zsetLocked(key) == nil {
+		m.zsets[key] = make(map[string]float64)
+	}
+	if ttl > 0 {
+		m.zsetExpiry[key] = expiryFor(ttl)
+	}
+	m.zsets[key][member] += delta
+	return m.zsets[key][member]
+}
+
+// ZReplace atomically replaces a sorted set with the given members and sets its TTL
+// (0 = no expiry)
+func (m *MockKV) ZReplace(key string, ttl time.Duration, members ...ZMember) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	zset := make(map[string]float64, len(members))
+	for _, z := range members {
+		zset[z.Member] = z.Score
+	}
+	m.zsets[key] = zset
+	delete(m.zsetExpiry, key)
+	if ttl > 0 {
+		m.zsetExpiry[key] = expiryFor(ttl)
+	}
+}
+
+// zRevSortedLocked returns the members of a sorted set, highest score first and ties
+// in reverse lexicographical order like Redis. Caller holds m.mu.
+func (m *MockKV) zRevSortedLocked(key string) []ZMember {
+	zset := m.zsetLocked(key)
+	members := make([]ZMember, 0, len(zset))
+	for member, score := range zset {
+		members = append(members, ZMember{Member: member, Score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score > members[j].Score
+		}
+		return members[i].Member > members[j].Member
+	})
+	return members
+}
+
+// ZRevRange returns the members from position start to stop (inclusive, highest score
+// first). A negative stop counts from the end like in Redis.
+func (m *MockKV) ZRevRange(key string, start, stop int) []ZMember {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members := m.zRevSortedLocked(key)
+	if stop < 0 || stop >= len(members) {
+		stop = len(members) - 1
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop {
+		return []ZMember{}
+	}
+	return members[start : stop+1]
+}
+
+// ZRevRank returns the position of a member, highest score first, and whether it exists
+func (m *MockKV) ZRevRank(key, member string) (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, z := range m.zRevSortedLocked(key) {
+		if z.Member == member {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// ZScore returns the score of a sorted set member and whether it exists
+func (m *MockKV) ZScore(key, member string) (float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	score, ok := m.zsetLocked(key)[member]
+	return score, ok
+}
+
+// ZCard returns the number of members of a sorted set
+func (m *MockKV) ZCard(key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.zsetLocked(key))
+}
+
+// RPush appends items to a list and (re)sets its TTL (0 = no expiry)
+func (m *MockKV) RPush(key string, ttl time.Duration, items ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := m.lists[key]
+	if !list.expiresAt.IsZero() && time.Now().After(list.expiresAt) {
+		list.items = nil
+	}
+	list.items = append(list.items, items...)
+	list.expiresAt = expiryFor(ttl)
+	m.lists[key] = list
+}
+
+// LRange returns all items of a list in insertion order
+func (m *MockKV) LRange(key string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, ok := m.lists[key]
+	if !ok {
+		return nil
+	}
+	if !list.expiresAt.IsZero() && time.Now().After(list.expiresAt) {
+		delete(m.lists, key)
+		return nil
+	}
+	items := make([]string, len(list.items))
+	copy(items, list.items)
+	return items
+}
+
+// Publish delivers a message to all subscribers of a channel
+func (m *MockKV) Publish(channel, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, sub := range m.subscribers[channel] {
+		select {
+		case sub <- message:
+		default:
+			// Channel full, skip
+		}
+	}
+}
+
+// Subscribe returns a channel receiving all messages published to channel
+func (m *MockKV) Subscribe(channel string) chan string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch := make(chan string, 100)
+	m.subscribers[channel] = append(m.subscribers[channel], ch)
+	return ch
+}
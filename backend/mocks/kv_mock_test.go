@@ -0,0 +1,195 @@
+package mocks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKVSetAndGet(t *testing.T) {
+	kv := NewMockKV()
+
+	kv.Set("key-1", "value-1", 0)
+
+	value, ok := kv.Get("key-1")
+	if !ok {
+		t.Fatal("Expected key-1 to exist")
+	}
+	if value != "value-1" {
+		t.Errorf("Value mismatch: got %s, want value-1", value)
+	}
+
+	if _, ok := kv.Get("missing"); ok {
+		t.Error("Expected missing key to not exist")
+	}
+}
+
+func TestKVExpiry(t *testing.T) {
+	kv := NewMockKV()
+
+	kv.Set("short-lived", "value", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := kv.Get("short-lived"); ok {
+		t.Error("Expected key to expire after its TTL")
+	}
+}
+
+func TestKVSetNX(t *testing.T) {
+	kv := NewMockKV()
+
+	if !kv.SetNX("lock", "pod-1", 0) {
+		t.Fatal("First SetNX should succeed")
+	}
+	if kv.SetNX("lock", "pod-2", 0) {
+		t.Error("Second SetNX should fail while key exists")
+	}
+
+	value, _ := kv.Get("lock")
+	if value != "pod-1" {
+		t.Errorf("SetNX overwrote existing value: got %s, want pod-1", value)
+	}
+
+	// Expired keys can be taken again
+	kv.Set("expiring-lock", "pod-1", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if !kv.SetNX("expiring-lock", "pod-2", 0) {
+		t.Error("SetNX should succeed once the previous value expired")
+	}
+}
+
+func TestKVUpdate(t *testing.T) {
+	kv := NewMockKV()
+	kv.Set("counter", "1", 0)
+
+	err := kv.Update("counter", 0, func(current string, found bool) (string, error) {
+		if !found || current != "1" {
+			t.Errorf("Unexpected current value %q (found=%v)", current, found)
+		}
+		return "2", nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if value, _ := kv.Get("counter"); value != "2" {
+		t.Errorf("Update not applied: got %s, want 2", value)
+	}
+
+	// Errors abort the update
+	abort := errors.New("abort")
+	if err := kv.Update("counter", 0, func(string, bool) (string, error) { return "3", abort }); err != abort {
+		t.Errorf("Expected abort error, got %v", err)
+	}
+	if value, _ := kv.Get("counter"); value != "2" {
+		t.Errorf("Aborted update was applied: got %s, want 2", value)
+	}
+
+	// Empty value deletes the key
+	kv.Update("counter", 0, func(string, bool) (string, error) { return "", nil })
+	if _, ok := kv.Get("counter"); ok {
+		t.Error("Expected key to be deleted by empty update")
+	}
+}
+
+func TestKVSets(t *testing.T) {
+	kv := NewMockKV()
+
+	kv.SAdd("set", "b", "a", "c")
+	kv.SAdd("set", "a")
+	kv.SRem("set", "c")
+
+	members := kv.SMembers("set")
+	if len(members) != 2 || members[0] != "a" || members[1] != "b" {
+		t.Errorf("Expected [a b], got %v", members)
+	}
+
+	kv.Del("set")
+	if len(kv.SMembers("set")) != 0 {
+		t.Error("Expected set to be deleted")
+	}
+}
+
+func TestKVLists(t *testing.T) {
+	kv := NewMockKV()
+
+	kv.RPush("list", 0, "a", "b")
+	kv.RPush("list", 0, "c")
+
+	items := kv.LRange("list")
+	if len(items) != 3 || items[0] != "a" || items[2] != "c" {
+		t.Errorf("Expected [a b c], got %v", items)
+	}
+
+	kv.RPush("short-lived", 10*time.Millisecond, "x")
+	time.Sleep(20 * time.Millisecond)
+	if len(kv.LRange("short-lived")) != 0 {
+		t.Error("Expected list to expire after its TTL")
+	}
+
+	kv.Del("list")
+	if len(kv.LRange("list")) != 0 {
+		t.Error("Expected list to be deleted")
+	}
+}
+
+func TestKVPubSub(t *testing.T) {
+	kv := NewMockKV()
+
+	sub := kv.Subscribe("channel")
+	other := kv.Subscribe("other")
+	kv.Publish("channel", "hello")
+
+	select {
+	case msg := <-sub:
+		if msg != "hello" {
+			t.Errorf("Expected hello, got %s", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for message")
+	}
+
+	select {
+	case msg := <-other:
+		t.Errorf("Unexpected message on other channel: %s", msg)
+	default:
+	}
+}
+
+func TestKVSortedSets(t *testing.T) {
+	kv := NewMockKV()
+
+	kv.ZAdd("board", ZMember{Member: "a", Score: 10}, ZMember{Member: "b", Score: 30})
+	kv.ZIncrBy("board", "c", 20, 0)
+	if score := kv.ZIncrBy("board", "a", 5, 0); score != 15 {
+		t.Errorf("Expected score 15, got %v", score)
+	}
+
+	top := kv.ZRevRange("board", 0, 1)
+	if len(top) != 2 || top[0].Member != "b" || top[1].Member != "c" {
+		t.Errorf("Unexpected top 2: %v", top)
+	}
+	if all := kv.ZRevRange("board", 1, -1); len(all) != 2 || all[1].Member != "a" {
+		t.Errorf("Unexpected range: %v", all)
+	}
+	if rank, ok := kv.ZRevRank("board", "a"); !ok || rank != 2 {
+		t.Errorf("Expected rank 2, got %d (%v)", rank, ok)
+	}
+	if _, ok := kv.ZRevRank("board", "missing"); ok {
+		t.Error("Expected missing member to have no rank")
+	}
+
+	kv.ZReplace("board", 0, ZMember{Member: "d", Score: 1})
+	if kv.ZCard("board") != 1 {
+		t.Errorf("Expected replaced set to have 1 member, got %d", kv.ZCard("board"))
+	}
+	kv.Del("board")
+	if kv.ZCard("board") != 0 {
+		t.Error("Expected sorted set to be deleted")
+	}
+
+	kv.ZIncrBy("daily", "a", 1, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := kv.ZScore("daily", "a"); ok {
+		t.Error("Expected sorted set to expire after its TTL")
+	}
+}
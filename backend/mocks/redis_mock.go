@@ -27,14 +27,21 @@ type QueueEntry struct {
 	Picture  string `json:"picture"`
 	PodID    string `json:"podId"`
 	JoinedAt int64  `json:"joinedAt"`
+	Rating   int    `json:"rating"`
+	Mode     string `json:"mode,omitempty"`
+
+	PartyID   string `json:"partyId,omitempty"`
+	PartySize int    `json:"partySize,omitempty"`
 }
 
 // MatchNotification is sent when a match is found
 type MatchNotification struct {
-	Player1ID string `json:"player1Id"`
-	Player2ID string `json:"player2Id"`
-	RoomID    string `json:"roomId"`
-	HostPodID string `json:"hostPodId"`
+	Player1ID string   `json:"player1Id"`
+	Player2ID string   `json:"player2Id"`
+	PlayerIDs []string `json:"playerIds"`
+	Mode      string   `json:"mode"`
+	RoomID    string   `json:"roomId"`
+	HostPodID string   `json:"hostPodId"`
 }
 
 var mockRedisInstance *MockRedis
@@ -86,26 +93,37 @@ func (m *MockRedis) GetPodID() string {
 	return m.podID
 }
 
-// AddToQueue adds a player to the mock matchmaking queue
+// AddToQueue adds a player with the default rating to the mock matchmaking queue
 func (m *MockRedis) AddToQueue(userID, name, picture string) error {
+	return m.AddToQueueWithRating(userID, name, picture, defaultRating)
+}
+
+// AddToQueueWithRating adds a player with the given skill rating to the mock matchmaking queue
+func (m *MockRedis) AddToQueueWithRating(userID, name, picture string, rating int) error {
+	return m.Enqueue(QueueEntry{
+		UserID:   userID,
+		Name:     name,
+		Picture:  picture,
+		JoinedAt: time.Now().Unix(),
+		Rating:   rating,
+	})
+}
+
+// Enqueue adds a prepared entry to the mock matchmaking queue, replacing any
+// previous entry of the same user
+func (m *MockRedis) Enqueue(entry QueueEntry) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// Remove existing entry for this user (if rejoining)
-	for i, entry := range m.queue {
-		if entry.UserID == userID {
+	for i, queued := range m.queue {
+		if queued.UserID == entry.UserID {
 			m.queue = append(m.queue[:i], m.queue[i+1:]...)
 			break
 		}
 	}
 
-	entry := QueueEntry{
-		UserID:   userID,
-		Name:     name,
-		Picture:  picture,
-		PodID:    m.podID,
-		JoinedAt: time.Now().Unix(),
-	}
+	entry.PodID = m.podID
 	m.queue = append(m.queue, entry)
 
 	// Sort by JoinedAt (oldest first) - mimics Redis Sorted Set behavior
@@ -167,6 +185,44 @@ func (m *MockRedis) TryMatch() ([]QueueEntry, error) {
 	return matched, nil
 }
 
+// TakeFromQueue atomically removes the given players from the queue.
+// Returns nil if any of them is no longer queued, leaving the queue untouched.
+func (m *MockRedis) TakeFromQueue(userIDs ...string) []QueueEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taken := make([]QueueEntry, 0, len(userIDs))
+	for _, userID := range userIDs {
+		found := false
+		for _, entry := range m.queue {
+			if entry.UserID == userID {
+				taken = append(taken, entry)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	remaining := make([]QueueEntry, 0, len(m.queue))
+	for _, entry := range m.queue {
+		keep := true
+		for _, userID := range userIDs {
+			if entry.UserID == userID {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			remaining = append(remaining, entry)
+		}
+	}
+	m.queue = remaining
+	return taken
+}
+
 // PublishMatch sends a match notification
 func (m *MockRedis) PublishMatch(notification MatchNotification) error {
 	m.mu.Lock()
@@ -215,24 +271,67 @@ func (m *MockRedis) GetQueueEntries() []QueueEntry {
 
 // GameState stores the game state in memory
 type GameState struct {
-	RoomID             string           `json:"roomId"`
-	Player1ID          string           `json:"player1Id"`
-	Player2ID          string           `json:"player2Id"`
-	Player1Name        string           `json:"player1Name"`
-	Player2Name        string           `json:"player2Name"`
-	Player1Picture     string           `json:"player1Picture"`
-	Player2Picture     string           `json:"player2Picture"`
-	P1Score            int              `json:"p1Score"`
-	P2Score            int              `json:"p2Score"`
-	TimeRemaining      int              `json:"timeRemaining"`
-	GoldenCookieActive bool             `json:"goldenCookieActive"`
-	GoldenCookieX      float64          `json:"goldenCookieX"`
-	GoldenCookieY      float64          `json:"goldenCookieY"`
-	DoubleClickExpiry  map[string]int64 `json:"doubleClickExpiry"`
-	GameStarted        bool             `json:"gameStarted"`
-	GameEnded          bool             `json:"gameEnded"`
-	WinnerID           string           `json:"winnerId"`
-	TimerPodID         string           `json:"timerPodId"`
+	Mode               string            `json:"mode"`
+	Players            []GamePlayer      `json:"players"`
+	Scores             map[string]int    `json:"scores"`
+	Eliminated         map[string]string `json:"eliminated"`
+	Clicks             map[string]int    `json:"clicks,omitempty"`
+	GoldenCookies      map[string]int    `json:"goldenCookies,omitempty"`
+	MaxDeficit         map[string]int    `json:"maxDeficit,omitempty"`
+	RoomID             string            `json:"roomId"`
+	Player1ID          string            `json:"player1Id"`
+	Player2ID          string            `json:"player2Id"`
+	Player1Name        string            `json:"player1Name"`
+	Player2Name        string            `json:"player2Name"`
+	Player1Picture     string            `json:"player1Picture"`
+	Player2Picture     string            `json:"player2Picture"`
+	P1Score            int               `json:"p1Score"`
+	P2Score            int               `json:"p2Score"`
+	TimeRemaining      int               `json:"timeRemaining"`
+	GoldenCookieActive bool              `json:"goldenCookieActive"`
+	GoldenCookieX      float64           `json:"goldenCookieX"`
+	GoldenCookieY      float64           `json:"goldenCookieY"`
+	PowerUpType        string            `json:"powerUpType"`
+	Effects            PowerUpEffects    `json:"effects"`
+	SuspiciousPlayers  map[string]bool   `json:"suspiciousPlayers"`
+	DisconnectedAt     map[string]int64  `json:"disconnectedAt"`
+	Connections        map[string]string `json:"connections"`
+	GameStarted        bool              `json:"gameStarted"`
+	GameEnded          bool              `json:"gameEnded"`
+	WinnerID           string            `json:"winnerId"`
+	TimerPodID         string            `json:"timerPodId"`
+	TimerHeartbeat     int64             `json:"timerHeartbeat"`
+	StartsAt           int64             `json:"startsAt"`
+	Rules              GameRules         `json:"rules"`
+	RematchOf          string            `json:"rematchOf,omitempty"`
+	SeriesID           string            `json:"seriesId,omitempty"`
+	TournamentID       string            `json:"tournamentId,omitempty"`
+	TournamentMatch    string            `json:"tournamentMatch,omitempty"`
+}
+
+// GamePlayer mirrors a match participant of the main package
+type GamePlayer struct {
+	UserID  string `json:"userId"`
+	Name    string `json:"name"`
+	Picture string `json:"picture"`
+}
+
+// GameRules mirrors the per-match rules of the main package
+type GameRules struct {
+	Preset             string `json:"preset"`
+	DurationSeconds    int    `json:"durationSeconds"`
+	CountdownSeconds   int    `json:"countdownSeconds"`
+	GoldenMinSeconds   int    `json:"goldenMinSeconds"`
+	GoldenMaxSeconds   int    `json:"goldenMaxSeconds"`
+	DoubleClickSeconds int    `json:"doubleClickSeconds"`
+}
+
+// PowerUpEffects mirrors the timed power-up effects of the main package
+type PowerUpEffects struct {
+	Multiplier      map[string]int   `json:"multiplier"`
+	MultiplierUntil map[string]int64 `json:"multiplierUntil"`
+	FrozenUntil     map[string]int64 `json:"frozenUntil"`
+	ShieldUntil     map[string]int64 `json:"shieldUntil"`
 }
 
 // GameEvent represents a game event
@@ -247,6 +346,7 @@ type GameEvent struct {
 type MockGameStore struct {
 	mu               sync.RWMutex
 	games            map[string]*GameState
+	playerRooms      map[string]string // UserID -> RoomID
 	eventSubscribers []chan GameEvent
 }
 
@@ -258,6 +358,7 @@ func GetMockGameStore() *MockGameStore {
 	mockGameStoreOnce.Do(func() {
 		mockGameStoreInstance = &MockGameStore{
 			games:            make(map[string]*GameState),
+			playerRooms:      make(map[string]string),
 			eventSubscribers: make([]chan GameEvent, 0),
 		}
 		log.Println("[MOCK] In-memory game store initialized")
@@ -285,6 +386,38 @@ func (s *MockGameStore) GetGameState(roomID string) (*GameState, error) {
 	return state, nil
 }
 
+// UpdateGameState applies fn to a game state while holding the store lock,
+// mimicking a Redis WATCH/MULTI transaction
+func (s *MockGameStore) UpdateGameState(roomID string, fn func(state *GameState) error) (*GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, exists := s.games[roomID]
+	if !exists {
+		return nil, nil
+	}
+
+	updated := *state
+	if err := fn(&updated); err != nil {
+		return nil, err
+	}
+	s.games[roomID] = &updated
+	return &updated, nil
+}
+
+// ListGameIDs returns the room IDs of all stored games
+func (s *MockGameStore) ListGameIDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.games))
+	for id := range s.games {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // DeleteGameState deletes a game state
 func (s *MockGameStore) DeleteGameState(roomID string) error {
 	s.mu.Lock()
@@ -294,6 +427,31 @@ func (s *MockGameStore) DeleteGameState(roomID string) error {
 	return nil
 }
 
+// SetPlayerRoom records which room a player is currently in
+func (s *MockGameStore) SetPlayerRoom(userID, roomID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.playerRooms[userID] = roomID
+	return nil
+}
+
+// GetPlayerRoom returns the room a player is currently in, or "" if none
+func (s *MockGameStore) GetPlayerRoom(userID string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.playerRooms[userID], nil
+}
+
+// ClearPlayerRoom removes the player's room mapping if it still points to roomID
+func (s *MockGameStore) ClearPlayerRoom(userID, roomID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.playerRooms[userID] == roomID {
+		delete(s.playerRooms, userID)
+	}
+	return nil
+}
+
 // PublishGameEvent publishes a game event to all subscribers
 func (s *MockGameStore) PublishGameEvent(event GameEvent) error {
 	s.mu.Lock()
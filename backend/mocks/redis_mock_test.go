@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -193,6 +194,73 @@ func TestTryMatch_QueueOrderMaintained(t *testing.T) {
 	}
 }
 
+func TestAddToQueueWithRating(t *testing.T) {
+	redis := newTestMockRedis()
+
+	redis.AddToQueue("user-1", "One", "")
+	redis.AddToQueueWithRating("user-2", "Two", "", 1400)
+
+	entries := redis.GetQueueEntries()
+	if entries[0].Rating != defaultRating {
+		t.Errorf("Expected default rating %d, got %d", defaultRating, entries[0].Rating)
+	}
+	if entries[1].Rating != 1400 {
+		t.Errorf("Expected rating 1400, got %d", entries[1].Rating)
+	}
+}
+
+func TestEnqueue_KeepsMode(t *testing.T) {
+	redis := newTestMockRedis()
+
+	redis.Enqueue(QueueEntry{UserID: "user-1", Mode: "ffa", JoinedAt: time.Now().Unix()})
+	redis.Enqueue(QueueEntry{UserID: "user-1", Mode: "duel", JoinedAt: time.Now().Unix()})
+
+	entries := redis.GetQueueEntries()
+	if len(entries) != 1 {
+		t.Fatalf("Expected rejoining user to replace the entry, got %d entries", len(entries))
+	}
+	if entries[0].Mode != "duel" {
+		t.Errorf("Expected mode duel, got %s", entries[0].Mode)
+	}
+	if entries[0].PodID != "test-pod" {
+		t.Errorf("Expected pod ID to be set, got %s", entries[0].PodID)
+	}
+}
+
+func TestTakeFromQueue(t *testing.T) {
+	redis := newTestMockRedis()
+
+	redis.AddToQueue("user-1", "One", "")
+	redis.AddToQueue("user-2", "Two", "")
+	redis.AddToQueue("user-3", "Three", "")
+
+	taken := redis.TakeFromQueue("user-1", "user-3")
+	if len(taken) != 2 {
+		t.Fatalf("Expected 2 taken players, got %d", len(taken))
+	}
+	if taken[0].UserID != "user-1" || taken[1].UserID != "user-3" {
+		t.Errorf("Unexpected taken players: %+v", taken)
+	}
+
+	entries := redis.GetQueueEntries()
+	if len(entries) != 1 || entries[0].UserID != "user-2" {
+		t.Errorf("Expected only user-2 to remain, got %+v", entries)
+	}
+}
+
+func TestTakeFromQueue_MissingPlayer(t *testing.T) {
+	redis := newTestMockRedis()
+
+	redis.AddToQueue("user-1", "One", "")
+
+	if taken := redis.TakeFromQueue("user-1", "user-2"); taken != nil {
+		t.Errorf("Expected nil when a player is missing, got %+v", taken)
+	}
+	if redis.GetQueueLength() != 1 {
+		t.Error("Queue should be untouched when a take fails")
+	}
+}
+
 func TestPublishAndSubscribe(t *testing.T) {
 	redis := newTestMockRedis()
 
@@ -276,6 +344,7 @@ func TestGetQueueEntries_ReturnsCopy(t *testing.T) {
 func newTestMockGameStore() *MockGameStore {
 	return &MockGameStore{
 		games:            make(map[string]*GameState),
+		playerRooms:      make(map[string]string),
 		eventSubscribers: make([]chan GameEvent, 0),
 	}
 }
@@ -357,3 +426,98 @@ func TestGameEventPubSub(t *testing.T) {
 		t.Fatal("Timeout waiting for game event")
 	}
 }
+
+func TestUpdateGameState(t *testing.T) {
+	store := newTestMockGameStore()
+	store.SaveGameState(&GameState{RoomID: "room-123", TimerPodID: "pod-1"})
+
+	updated, err := store.UpdateGameState("room-123", func(state *GameState) error {
+		state.TimerPodID = "pod-2"
+		state.TimerHeartbeat = 42
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateGameState failed: %v", err)
+	}
+	if updated.TimerPodID != "pod-2" {
+		t.Errorf("TimerPodID mismatch: got %s, want pod-2", updated.TimerPodID)
+	}
+
+	retrieved, _ := store.GetGameState("room-123")
+	if retrieved.TimerHeartbeat != 42 {
+		t.Errorf("Update was not persisted: got heartbeat %d, want 42", retrieved.TimerHeartbeat)
+	}
+}
+
+func TestUpdateGameState_ErrorDiscardsChanges(t *testing.T) {
+	store := newTestMockGameStore()
+	store.SaveGameState(&GameState{RoomID: "room-123", TimerPodID: "pod-1"})
+
+	_, err := store.UpdateGameState("room-123", func(state *GameState) error {
+		state.TimerPodID = "pod-2"
+		return errors.New("lease held")
+	})
+	if err == nil {
+		t.Fatal("Expected error from update function to be returned")
+	}
+
+	retrieved, _ := store.GetGameState("room-123")
+	if retrieved.TimerPodID != "pod-1" {
+		t.Errorf("Failed update should not be persisted, got TimerPodID %s", retrieved.TimerPodID)
+	}
+}
+
+func TestUpdateGameState_NotFound(t *testing.T) {
+	store := newTestMockGameStore()
+
+	updated, err := store.UpdateGameState("missing-room", func(state *GameState) error {
+		t.Error("Update function should not be called for a missing game")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateGameState failed: %v", err)
+	}
+	if updated != nil {
+		t.Errorf("Expected nil for missing game, got %+v", updated)
+	}
+}
+
+func TestListGameIDs(t *testing.T) {
+	store := newTestMockGameStore()
+	store.SaveGameState(&GameState{RoomID: "room-b"})
+	store.SaveGameState(&GameState{RoomID: "room-a"})
+
+	ids := store.ListGameIDs()
+	if len(ids) != 2 {
+		t.Fatalf("Expected 2 game IDs, got %d", len(ids))
+	}
+	if ids[0] != "room-a" || ids[1] != "room-b" {
+		t.Errorf("Expected sorted IDs [room-a room-b], got %v", ids)
+	}
+}
+
+func TestPlayerRoomMapping(t *testing.T) {
+	store := newTestMockGameStore()
+
+	store.SetPlayerRoom("player-1", "room-1")
+
+	roomID, err := store.GetPlayerRoom("player-1")
+	if err != nil {
+		t.Fatalf("GetPlayerRoom failed: %v", err)
+	}
+	if roomID != "room-1" {
+		t.Errorf("RoomID mismatch: got %s, want room-1", roomID)
+	}
+
+	// Clearing a stale room must not remove a newer mapping
+	store.SetPlayerRoom("player-1", "room-2")
+	store.ClearPlayerRoom("player-1", "room-1")
+	if roomID, _ := store.GetPlayerRoom("player-1"); roomID != "room-2" {
+		t.Errorf("Newer mapping was cleared: got %q, want room-2", roomID)
+	}
+
+	store.ClearPlayerRoom("player-1", "room-2")
+	if roomID, _ := store.GetPlayerRoom("player-1"); roomID != "" {
+		t.Errorf("Expected mapping to be cleared, got %q", roomID)
+	}
+}
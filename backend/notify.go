@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+)
+
+const userNotifyChannel = "overcookied:user:notify"
+
+// UserMessage is a message for a single user, delivered by whichever pod holds
+// their connection
+type UserMessage struct {
+	UserID  string      `json:"userId"`
+	Message GameMessage `json:"message"`
+}
+
+// PublishUserMessage hands a user message to all pods
+func PublishUserMessage(msg UserMessage) error {
+	msgJSON, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return kvPublish(userNotifyChannel, string(msgJSON))
+}
+
+// SubscribeToUserMessages subscribes to user messages published by any pod
+func SubscribeToUserMessages(handler func(UserMessage)) {
+	kvSubscribe(userNotifyChannel, func(message string) {
+		var msg UserMessage
+		if err := json.Unmarshal([]byte(message), &msg); err != nil {
+			log.Printf("Failed to parse user message: %v", err)
+			return
+		}
+		handler(msg)
+	})
+}
+
+// sendToUser delivers a message to a user connected to this or any other pod.
+// Messages for users who are offline are dropped.
+func (gm *GameManager) sendToUser(userID string, msg GameMessage) {
+	gm.mutex.Lock()
+	client, ok := gm.clientsByID[userID]
+	gm.mutex.Unlock()
+	if ok {
+		sendToClient(client, msg)
+		return
+	}
+
+	if !IsRedisAvailable() {
+		return
+	}
+	if err := PublishUserMessage(UserMessage{UserID: userID, Message: msg}); err != nil {
+		log.Printf("Failed to publish message for %s: %v", userID, err)
+	}
+}
+
+// SubscribeToUserMessages delivers user messages published by other pods to local clients
+func (gm *GameManager) SubscribeToUserMessages() {
+	SubscribeToUserMessages(func(msg UserMessage) {
+		gm.mutex.Lock()
+		client, ok := gm.clientsByID[msg.UserID]
+		gm.mutex.Unlock()
+		if ok {
+			sendToClient(client, msg.Message)
+		}
+	})
+}
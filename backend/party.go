@@ -0,0 +1,522 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// Party is a group of players who queue and get matched together. The leader's
+// queue entry stands for the whole party.
+type Party struct {
+	ID       string       `json:"id"`
+	LeaderID string       `json:"leaderId"`
+	Members  []QueueEntry `json:"members"` // Leader first
+	Invited  []string     `json:"invited"` // UserIDs with a pending invite
+}
+
+const (
+	partyKeyPrefix       = "overcookied:party:"
+	partyMemberKeyPrefix = "overcookied:party:member:" // UserID -> ID of their party
+	partyTTL             = 2 * time.Hour
+	maxPartySize         = 4
+)
+
+var (
+	errPartyNotFound  = errors.New("party not found")
+	errPartyFull      = errors.New("party is full")
+	errPartyChanged   = errors.New("party changed while queued")
+	errNotPartyLeader = errors.New("not the party leader")
+	errNotInvited     = errors.New("no pending party invite")
+	errAlreadyInParty = errors.New("already in the party")
+)
+
+// partyFitsMode reports whether a party of the given size can queue for a mode:
+// a duel party plays against each other and a team match needs whole teams
+func partyFitsMode(mode string, size int) bool {
+	switch mode {
+	case ModeFFA:
+		return size <= ffaRoomSize
+	case ModeTeam:
+		return size != teamSize+1
+	default:
+		return size <= 2
+	}
+}
+
+// memberIndex returns the position of a user among the party members, or -1
+func (p *Party) memberIndex(userID string) int {
+	return slices.IndexFunc(p.Members, func(m QueueEntry) bool { return m.UserID == userID })
+}
+
+// view is the client-facing description of the party
+func (p *Party) view() map[string]interface{} {
+	members := make([]GamePlayer, len(p.Members))
+	for i, m := range p.Members {
+		members[i] = m.gamePlayer()
+	}
+	return map[string]interface{}{
+		"id":       p.ID,
+		"leaderId": p.LeaderID,
+		"members":  members,
+		"invited":  p.Invited,
+	}
+}
+
+// GetParty loads a party by ID
+func GetParty(partyID string) (*Party, error) {
+	partyJSON, found, err := kvGet(partyKeyPrefix + partyID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errPartyNotFound
+	}
+
+	var party Party
+	if err := json.Unmarshal([]byte(partyJSON), &party); err != nil {
+		return nil, err
+	}
+	return &party, nil
+}
+
+// GetPartyOf returns the party a user belongs to, or nil if they are in none
+func GetPartyOf(userID string) (*Party, error) {
+	partyID, found, err := kvGet(partyMemberKeyPrefix + userID)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	party, err := GetParty(partyID)
+	if errors.Is(err, errPartyNotFound) {
+		kvDel(partyMemberKeyPrefix + userID)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if party.memberIndex(userID) < 0 {
+		return nil, nil // Stale mapping
+	}
+	return party, nil
+}
+
+// updateParty atomically applies fn to a stored party and returns the result.
+// A party left without members is deleted.
+func updateParty(partyID string, fn func(party *Party) error) (*Party, error) {
+	var party Party
+	err := kvUpdate(partyKeyPrefix+partyID, partyTTL, func(current string, found bool) (string, error) {
+		if !found {
+			return "", errPartyNotFound
+		}
+		party = Party{}
+		if err := json.Unmarshal([]byte(current), &party); err != nil {
+			return "", err
+		}
+		if err := fn(&party); err != nil {
+			return "", err
+		}
+		if len(party.Members) == 0 {
+			return "", nil // Delete
+		}
+
+		updated, err := json.Marshal(party)
+		return string(updated), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &party, nil
+}
+
+// InviteToParty invites a user into the leader's party, creating the party if the
+// leader is not in one yet
+func InviteToParty(leader QueueEntry, inviteeID string) (*Party, error) {
+	current, err := GetPartyOf(leader.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		idBytes := make([]byte, 8)
+		if _, err := rand.Read(idBytes); err != nil {
+			return nil, err
+		}
+		party := Party{
+			ID:       hex.EncodeToString(idBytes),
+			LeaderID: leader.UserID,
+			Members:  []QueueEntry{leader},
+			Invited:  []string{inviteeID},
+		}
+
+		partyJSON, err := json.Marshal(party)
+		if err != nil {
+			return nil, err
+		}
+		if err := kvSet(partyKeyPrefix+party.ID, string(partyJSON), partyTTL); err != nil {
+			return nil, err
+		}
+		if err := kvSet(partyMemberKeyPrefix+leader.UserID, party.ID, partyTTL); err != nil {
+			return nil, err
+		}
+
+		log.Printf("Party %s created by %s", party.ID, leader.UserID)
+		return &party, nil
+	}
+
+	return updateParty(current.ID, func(party *Party) error {
+		if party.LeaderID != leader.UserID {
+			return errNotPartyLeader
+		}
+		if party.memberIndex(inviteeID) >= 0 {
+			return errAlreadyInParty
+		}
+		if len(party.Members) >= maxPartySize {
+			return errPartyFull
+		}
+		if !slices.Contains(party.Invited, inviteeID) {
+			party.Invited = append(party.Invited, inviteeID)
+		}
+		return nil
+	})
+}
+
+// AcceptPartyInvite adds an invited user to a party. The member no longer queues
+// alone, and a queued party is taken out of the queue since its size changed.
+func AcceptPartyInvite(partyID string, member QueueEntry) (*Party, error) {
+	party, err := updateParty(partyID, func(party *Party) error {
+		i := slices.Index(party.Invited, member.UserID)
+		if i < 0 {
+			return errNotInvited
+		}
+		if len(party.Members) >= maxPartySize {
+			return errPartyFull
+		}
+		party.Invited = slices.Delete(party.Invited, i, i+1)
+		party.Members = append(party.Members, member)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := kvSet(partyMemberKeyPrefix+member.UserID, partyID, partyTTL); err != nil {
+		return nil, err
+	}
+	RemoveFromQueue(member.UserID)
+	RemoveFromQueue(party.LeaderID)
+
+	log.Printf("%s joined party %s", member.UserID, partyID)
+	return party, nil
+}
+
+// DeclinePartyInvite withdraws a user's pending invite
+func DeclinePartyInvite(partyID, userID string) (*Party, error) {
+	return updateParty(partyID, func(party *Party) error {
+		i := slices.Index(party.Invited, userID)
+		if i < 0 {
+			return errNotInvited
+		}
+		party.Invited = slices.Delete(party.Invited, i, i+1)
+		return nil
+	})
+}
+
+// LeaveParty removes a user from their party and takes the party out of the queue.
+// The next member takes over as leader. A party with a single member left is
+// disbanded; released lists the users who are no longer in a party because of it.
+func LeaveParty(userID string) (party *Party, released []string, err error) {
+	current, err := GetPartyOf(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if current == nil {
+		return nil, nil, errPartyNotFound
+	}
+
+	var formerLeader string
+	party, err = updateParty(current.ID, func(party *Party) error {
+		released = nil
+		formerLeader = party.LeaderID
+		if i := party.memberIndex(userID); i >= 0 {
+			party.Members = slices.Delete(party.Members, i, i+1)
+		}
+		if len(party.Members) < 2 {
+			for _, m := range party.Members {
+				released = append(released, m.UserID)
+			}
+			party.Members = nil
+			return nil
+		}
+		if party.LeaderID == userID {
+			party.LeaderID = party.Members[0].UserID
+		}
+		return nil
+	})
+	kvDel(partyMemberKeyPrefix + userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	RemoveFromQueue(formerLeader)
+	for _, id := range released {
+		kvDel(partyMemberKeyPrefix + id)
+	}
+
+	if len(party.Members) == 0 {
+		log.Printf("Party %s disbanded after %s left", party.ID, userID)
+		return nil, released, nil
+	}
+	log.Printf("%s left party %s", userID, party.ID)
+	return party, released, nil
+}
+
+// dequeueParty takes the party of a user out of the queue, keeping its members
+// together. Called when a member disconnects.
+func dequeueParty(userID string) {
+	party, err := GetPartyOf(userID)
+	if err != nil {
+		log.Printf("Failed to look up party of %s: %v", userID, err)
+		return
+	}
+	if party != nil {
+		RemoveFromQueue(party.LeaderID)
+	}
+}
+
+// partyMembers returns the players a queue entry stands for: all members for the
+// entry of a party leader, otherwise just the queued player
+func partyMembers(entry QueueEntry) ([]QueueEntry, error) {
+	if entry.PartyID == "" {
+		return []QueueEntry{entry}, nil
+	}
+
+	party, err := GetParty(entry.PartyID)
+	if err != nil {
+		return nil, err
+	}
+	if party.LeaderID != entry.UserID || len(party.Members) != entry.PartySize {
+		return nil, errPartyChanged
+	}
+
+	members := make([]QueueEntry, len(party.Members))
+	for i, m := range party.Members {
+		m.Mode, m.JoinedAt = entry.Mode, entry.JoinedAt
+		m.Rating = lookupRating(m.UserID)
+		members[i] = m
+	}
+	return members, nil
+}
+
+// expandUnits loads the players behind matched queue entries, one group per entry.
+// If a party changed while it was queued the match is called off and the other
+// entries are put back into the queue.
+func expandUnits(units []QueueEntry) ([][]QueueEntry, error) {
+	groups := make([][]QueueEntry, len(units))
+	var failed error
+	for i, unit := range units {
+		members, err := partyMembers(unit)
+		if err != nil {
+			failed = fmt.Errorf("party of %s: %w", unit.UserID, err)
+			continue
+		}
+		groups[i] = members
+	}
+	if failed == nil {
+		return groups, nil
+	}
+
+	for i, unit := range units {
+		if groups[i] != nil {
+			AddToQueue(unit)
+		}
+	}
+	return nil, failed
+}
+
+// flattenUnits lists the players of all groups in queue order
+func flattenUnits(groups [][]QueueEntry) []QueueEntry {
+	var players []QueueEntry
+	for _, group := range groups {
+		players = append(players, group...)
+	}
+	return players
+}
+
+// preparePartyEntry turns the queue entry of a party leader into the entry of the
+// whole party. Returns false (after telling the client) if the party cannot queue.
+func (gm *GameManager) preparePartyEntry(client *Client, party *Party, entry *QueueEntry) bool {
+	if party.LeaderID != client.userID {
+		sendError(client, "not_party_leader", "Only the party leader can join the queue")
+		return false
+	}
+	if !partyFitsMode(entry.Mode, len(party.Members)) {
+		sendError(client, "party_size", fmt.Sprintf("A party of %d cannot queue for %s", len(party.Members), entry.Mode))
+		return false
+	}
+
+	// Every member has to be free to play, not queued on their own or in a game
+	memberIDs := make([]string, 0, len(party.Members)-1)
+	for _, m := range party.Members[1:] {
+		memberIDs = append(memberIDs, m.UserID)
+	}
+	presence := gm.presenceOf(memberIDs)
+	for _, m := range party.Members[1:] {
+		if presence[m.UserID] != PresenceOnline {
+			sendError(client, "player_busy", fmt.Sprintf("%s is not available to play", m.Name))
+			return false
+		}
+	}
+	entry.PartyID, entry.PartySize = party.ID, len(party.Members)
+	return true
+}
+
+// notifyPartyQueued tells the members of a party that their leader queued them
+func (gm *GameManager) notifyPartyQueued(party *Party, mode string) {
+	for _, m := range party.Members[1:] {
+		gm.sendToUser(m.UserID, GameMessage{Type: MsgTypePartyQueued, Payload: map[string]string{"mode": mode}})
+	}
+}
+
+// notifyParty sends the current state of a party to all its members
+func (gm *GameManager) notifyParty(party *Party) {
+	msg := GameMessage{Type: MsgTypePartyUpdate, Payload: map[string]interface{}{"party": party.view()}}
+	for _, m := range party.Members {
+		gm.sendToUser(m.UserID, msg)
+	}
+}
+
+// notifyNoParty tells a user they are no longer in a party
+func (gm *GameManager) notifyNoParty(userID string) {
+	gm.sendToUser(userID, GameMessage{Type: MsgTypePartyUpdate, Payload: map[string]interface{}{"party": nil}})
+}
+
+// sendPartyError reports a failed party request back to the client
+func sendPartyError(client *Client, err error) {
+	switch {
+	case errors.Is(err, errPartyNotFound):
+		sendError(client, "party_not_found", "This party does not exist anymore")
+	case errors.Is(err, errPartyFull):
+		sendError(client, "party_full", fmt.Sprintf("A party has at most %d players", maxPartySize))
+	case errors.Is(err, errNotPartyLeader):
+		sendError(client, "not_party_leader", "Only the party leader can invite players")
+	case errors.Is(err, errNotInvited):
+		sendError(client, "party_not_invited", "You have no pending invite to this party")
+	case errors.Is(err, errAlreadyInParty):
+		sendError(client, "party_member", "This player is already in your party")
+	default:
+		log.Printf("Party request of %s failed: %v", client.userID, err)
+		sendError(client, "party_error", "Could not update the party")
+	}
+}
+
+// handlePartyInvite invites a user into the client's party
+func (gm *GameManager) handlePartyInvite(client *Client, userID string) {
+	if !IsRedisAvailable() {
+		sendError(client, "unavailable", "Parties are not available right now")
+		return
+	}
+	if userID == "" || userID == client.userID {
+		sendError(client, "invalid_request", "userId must be another player")
+		return
+	}
+
+	leader := queueEntryFor(client)
+	party, err := InviteToParty(leader, userID)
+	if err != nil {
+		sendPartyError(client, err)
+		return
+	}
+
+	gm.sendToUser(userID, GameMessage{
+		Type: MsgTypePartyInvitation,
+		Payload: map[string]interface{}{
+			"partyId": party.ID,
+			"from":    leader.gamePlayer(),
+		},
+	})
+	gm.notifyParty(party)
+}
+
+// handlePartyAccept joins the party the client was invited to, leaving their
+// current party first
+func (gm *GameManager) handlePartyAccept(client *Client, partyID string) {
+	if !IsRedisAvailable() {
+		sendError(client, "unavailable", "Parties are not available right now")
+		return
+	}
+	if partyID == "" {
+		sendError(client, "invalid_request", "partyId is required")
+		return
+	}
+
+	current, err := GetPartyOf(client.userID)
+	if err != nil {
+		sendPartyError(client, err)
+		return
+	}
+	if current != nil && current.ID == partyID {
+		gm.notifyParty(current)
+		return
+	}
+	if current != nil {
+		gm.leaveParty(client.userID)
+	}
+
+	party, err := AcceptPartyInvite(partyID, queueEntryFor(client))
+	if err != nil {
+		sendPartyError(client, err)
+		return
+	}
+	gm.notifyParty(party)
+}
+
+// handlePartyDecline turns down a party invite
+func (gm *GameManager) handlePartyDecline(client *Client, partyID string) {
+	if !IsRedisAvailable() {
+		sendError(client, "unavailable", "Parties are not available right now")
+		return
+	}
+
+	party, err := DeclinePartyInvite(partyID, client.userID)
+	if err != nil {
+		sendPartyError(client, err)
+		return
+	}
+	gm.notifyParty(party)
+}
+
+// handlePartyLeave takes the client out of their party
+func (gm *GameManager) handlePartyLeave(client *Client) {
+	if !IsRedisAvailable() {
+		sendError(client, "unavailable", "Parties are not available right now")
+		return
+	}
+
+	if err := gm.leaveParty(client.userID); err != nil {
+		sendPartyError(client, err)
+		return
+	}
+	gm.notifyNoParty(client.userID)
+}
+
+// leaveParty removes a user from their party and tells the remaining members
+func (gm *GameManager) leaveParty(userID string) error {
+	party, released, err := LeaveParty(userID)
+	if err != nil {
+		return err
+	}
+
+	if party != nil {
+		gm.notifyParty(party)
+	}
+	for _, id := range released {
+		gm.notifyNoParty(id)
+	}
+	return nil
+}
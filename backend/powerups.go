@@ -0,0 +1,205 @@
+package main
+
+import (
+	"maps"
+	"math/rand"
+	"time"
+)
+
+// Power-up types. Every golden cookie spawn rolls one of them.
+const (
+	PowerUpDouble = "double" // Claimer's clicks count twice
+	PowerUpTriple = "triple" // Claimer's clicks count three times
+	PowerUpFreeze = "freeze" // Opponent's clicks don't count
+	PowerUpSteal  = "steal"  // Moves a share of the opponent's points to the claimer
+	PowerUpShield = "shield" // Blocks the next freeze or steal aimed at the claimer
+)
+
+// PowerUpDef describes how often a power-up spawns and what it does
+type PowerUpDef struct {
+	Type            string
+	Weight          int // Relative spawn chance
+	DurationSeconds int // Effect duration; 0 for instant effects (double uses GameRules.DoubleClickSeconds)
+	Multiplier      int // Click multiplier for double/triple
+	StealPercent    int // Share of the opponent's score taken by steal
+}
+
+var powerUpCatalog = []PowerUpDef{
+	{Type: PowerUpDouble, Weight: 40, Multiplier: 2},
+	{Type: PowerUpTriple, Weight: 15, DurationSeconds: 3, Multiplier: 3},
+	{Type: PowerUpFreeze, Weight: 15, DurationSeconds: 3},
+	{Type: PowerUpSteal, Weight: 15, StealPercent: 10},
+	{Type: PowerUpShield, Weight: 15, DurationSeconds: 10},
+}
+
+// rollPowerUp picks a random power-up type according to the spawn weights
+func rollPowerUp() string {
+	total := 0
+	for _, def := range powerUpCatalog {
+		total += def.Weight
+	}
+
+	n := rand.Intn(total)
+	for _, def := range powerUpCatalog {
+		if n < def.Weight {
+			return def.Type
+		}
+		n -= def.Weight
+	}
+	return PowerUpDouble
+}
+
+// powerUpDef looks up a power-up, falling back to double for unknown types
+// (e.g. cookies spawned before the catalogue existed)
+func powerUpDef(powerUpType string) PowerUpDef {
+	for _, def := range powerUpCatalog {
+		if def.Type == powerUpType {
+			return def
+		}
+	}
+	return powerUpCatalog[0]
+}
+
+// PowerUpEffects holds the timed power-up effects of all players in a match.
+// Timestamps are Unix millis.
+type PowerUpEffects struct {
+	Multiplier      map[string]int   `json:"multiplier"`      // UserID -> click multiplier while active
+	MultiplierUntil map[string]int64 `json:"multiplierUntil"` // UserID -> multiplier expiry
+	FrozenUntil     map[string]int64 `json:"frozenUntil"`     // UserID -> clicks ignored until
+	ShieldUntil     map[string]int64 `json:"shieldUntil"`     // UserID -> shield expiry
+}
+
+func newPowerUpEffects() PowerUpEffects {
+	return PowerUpEffects{
+		Multiplier:      make(map[string]int),
+		MultiplierUntil: make(map[string]int64),
+		FrozenUntil:     make(map[string]int64),
+		ShieldUntil:     make(map[string]int64),
+	}
+}
+
+// ensure initialises maps missing from states created by older versions
+func (e *PowerUpEffects) ensure() {
+	if e.Multiplier == nil {
+		e.Multiplier = make(map[string]int)
+	}
+	if e.MultiplierUntil == nil {
+		e.MultiplierUntil = make(map[string]int64)
+	}
+	if e.FrozenUntil == nil {
+		e.FrozenUntil = make(map[string]int64)
+	}
+	if e.ShieldUntil == nil {
+		e.ShieldUntil = make(map[string]int64)
+	}
+}
+
+// clone returns a copy that does not share its maps with e
+func (e PowerUpEffects) clone() PowerUpEffects {
+	return PowerUpEffects{
+		Multiplier:      maps.Clone(e.Multiplier),
+		MultiplierUntil: maps.Clone(e.MultiplierUntil),
+		FrozenUntil:     maps.Clone(e.FrozenUntil),
+		ShieldUntil:     maps.Clone(e.ShieldUntil),
+	}
+}
+
+// FrozenFor returns how long the player's clicks are still frozen
+func (e *PowerUpEffects) FrozenFor(userID string, now time.Time) time.Duration {
+	until := e.FrozenUntil[userID]
+	if until <= now.UnixMilli() {
+		return 0
+	}
+	return time.Duration(until-now.UnixMilli()) * time.Millisecond
+}
+
+// ClickPoints returns the points a click of the player is worth right now
+func (e *PowerUpEffects) ClickPoints(userID string, now time.Time) int {
+	if e.FrozenFor(userID, now) > 0 {
+		return 0
+	}
+	if e.MultiplierUntil[userID] > now.UnixMilli() && e.Multiplier[userID] > 1 {
+		return e.Multiplier[userID]
+	}
+	return 1
+}
+
+// PowerUpEffect describes the outcome of a claimed power-up
+type PowerUpEffect struct {
+	Type       string   `json:"type"`
+	ClaimedBy  string   `json:"claimedBy"`
+	Target     string   `json:"target"`               // Player the effect applies to (the first one in team matches)
+	Affected   []string `json:"affected"`             // All players the effect applies to
+	DurationMs int64    `json:"durationMs,omitempty"` // For timed effects
+	Points     int      `json:"points,omitempty"`     // Points moved by steal
+	Blocked    bool     `json:"blocked,omitempty"`    // Absorbed by the targets' shields
+}
+
+// Apply resolves a claimed power-up. Beneficial effects apply to the allies (the
+// claimer, or their whole team), offensive ones to the targets. scores is the
+// scoreboard of the match and is updated in place for instant effects.
+func (e *PowerUpEffects) Apply(powerUpType, claimerID string, allies, targets []string, scores map[string]int, rules GameRules, now time.Time) PowerUpEffect {
+	e.ensure()
+	def := powerUpDef(powerUpType)
+	effect := PowerUpEffect{Type: def.Type, ClaimedBy: claimerID}
+
+	duration := time.Duration(def.DurationSeconds) * time.Second
+	if def.Type == PowerUpDouble {
+		duration = rules.DoubleClick()
+	}
+	until := now.Add(duration).UnixMilli()
+
+	affected := allies
+	if def.Type == PowerUpFreeze || def.Type == PowerUpSteal {
+		// Offensive power-ups can be blocked by a shield, once per shield
+		affected = nil
+		for _, target := range targets {
+			if e.ShieldUntil[target] > now.UnixMilli() {
+				delete(e.ShieldUntil, target)
+				continue
+			}
+			affected = append(affected, target)
+		}
+		effect.Blocked = len(affected) == 0
+		if len(targets) > 0 {
+			effect.Target = targets[0]
+		}
+	} else {
+		effect.Target = claimerID
+	}
+	effect.Affected = affected
+
+	for _, id := range affected {
+		switch def.Type {
+		case PowerUpDouble, PowerUpTriple:
+			e.Multiplier[id] = def.Multiplier
+			e.MultiplierUntil[id] = until
+		case PowerUpFreeze:
+			e.FrozenUntil[id] = until
+		case PowerUpSteal:
+			stolen := scores[id] * def.StealPercent / 100
+			scores[id] -= stolen
+			scores[claimerID] += stolen
+			effect.Points += stolen
+		case PowerUpShield:
+			e.ShieldUntil[id] = until
+		}
+	}
+	if def.Type != PowerUpSteal && !effect.Blocked {
+		effect.DurationMs = duration.Milliseconds()
+	}
+	return effect
+}
+
+// eventData flattens the effect into game event data
+func (effect PowerUpEffect) eventData() map[string]interface{} {
+	return map[string]interface{}{
+		"powerUp":    effect.Type,
+		"claimedBy":  effect.ClaimedBy,
+		"target":     effect.Target,
+		"affected":   effect.Affected,
+		"durationMs": effect.DurationMs,
+		"points":     effect.Points,
+		"blocked":    effect.Blocked,
+	}
+}
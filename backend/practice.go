@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+)
+
+// BotProfile is the difficulty profile of a practice bot. The delay before each
+// click follows a click rate drawn from a normal distribution, and the bot goes
+// for a golden cookie with GoldenChance after a random reaction time.
+type BotProfile struct {
+	Difficulty   string
+	Name         string
+	MeanCPS      float64
+	CPSStdDev    float64
+	ReactionMin  time.Duration // Golden cookie reaction time bounds
+	ReactionMax  time.Duration
+	GoldenChance float64
+}
+
+var botProfiles = map[string]BotProfile{
+	"easy": {
+		Difficulty: "easy", Name: "Rookie Bot", MeanCPS: 4, CPSStdDev: 1,
+		ReactionMin: 1500 * time.Millisecond, ReactionMax: 3 * time.Second, GoldenChance: 0.5,
+	},
+	"medium": {
+		Difficulty: "medium", Name: "Baker Bot", MeanCPS: 6.5, CPSStdDev: 1.5,
+		ReactionMin: 800 * time.Millisecond, ReactionMax: 1800 * time.Millisecond, GoldenChance: 0.8,
+	},
+	"hard": {
+		Difficulty: "hard", Name: "Master Bot", MeanCPS: 9, CPSStdDev: 1.5,
+		ReactionMin: 350 * time.Millisecond, ReactionMax: 900 * time.Millisecond, GoldenChance: 1,
+	},
+}
+
+const (
+	botUserIDPrefix      = "bot:" // Followed by the difficulty
+	defaultBotDifficulty = "medium"
+	botMinCPS            = 1
+	botInboxSize         = 256
+)
+
+// practiceFallbackDelay is how long a solo player waits in the 1v1 queue before
+// a bot steps in (PRACTICE_FALLBACK_SECONDS, 0 disables the fallback)
+var practiceFallbackDelay = 20 * time.Second
+
+// initPracticeConfig loads the practice bot configuration from the environment
+func initPracticeConfig() {
+	if v, err := strconv.Atoi(os.Getenv("PRACTICE_FALLBACK_SECONDS")); err == nil && v >= 0 {
+		// The fallback has to happen before the queue entry expires
+		practiceFallbackDelay = min(time.Duration(v)*time.Second, queueTTL-5*time.Second)
+	}
+	if practiceFallbackDelay > 0 {
+		log.Printf("[PRACTICE] Bot opponent after %s in the 1v1 queue", practiceFallbackDelay)
+	} else {
+		log.Printf("[PRACTICE] Queue fallback to a bot opponent disabled")
+	}
+}
+
+// isBot reports whether a user ID belongs to a practice bot
+func isBot(userID string) bool {
+	return strings.HasPrefix(userID, botUserIDPrefix)
+}
+
+// Practice reports whether the room is a practice game against a bot
+func (r *Roster) Practice() bool {
+	for _, p := range r.Players {
+		if isBot(p.UserID) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextClick draws the delay before the bot's next click
+func (p BotProfile) nextClick() time.Duration {
+	cps := rand.NormFloat64()*p.CPSStdDev + p.MeanCPS
+	// Stay within the click limits real players have to respect
+	cps = max(min(cps, float64(suspiciousCPS-1), maxClicksPerSecond), botMinCPS)
+	return time.Duration(float64(time.Second) / cps)
+}
+
+// reaction draws the time the bot takes to click a golden cookie
+func (p BotProfile) reaction() time.Duration {
+	return p.ReactionMin + time.Duration(rand.Int63n(int64(p.ReactionMax-p.ReactionMin)+1))
+}
+
+// practiceBot is a server-side opponent. It is a Client without a connection:
+// the room's messages land in its send channel, and it plays by passing its
+// clicks to the room like the read pump of a real player would.
+type practiceBot struct {
+	client  *Client
+	profile BotProfile
+}
+
+// newPracticeBot creates a bot playing with the given profile
+func (gm *GameManager) newPracticeBot(profile BotProfile) *practiceBot {
+	return &practiceBot{
+		client: &Client{
+			manager: gm,
+			send:    make(chan []byte, botInboxSize),
+			userID:  botUserIDPrefix + profile.Difficulty,
+			name:    profile.Name,
+			limiter: newClickLimiter(),
+		},
+		profile: profile,
+	}
+}
+
+// play clicks through a game of the room until it is over
+func (b *practiceBot) play(room *GameRoom) {
+	click := GameMessage{Type: MsgTypeClick, Payload: ClickPayload{Count: 1}}
+	clickTimer := time.NewTimer(room.Rules.Countdown() + b.profile.nextClick())
+	// Safety net in case GAME_OVER got lost
+	deadline := time.NewTimer(room.Rules.Countdown() + time.Duration(room.Rules.DurationSeconds)*time.Second + 10*time.Second)
+	defer func() {
+		clickTimer.Stop()
+		deadline.Stop()
+	}()
+
+	for {
+		select {
+		case raw := <-b.client.send:
+			var msg GameMessage
+			if err := json.Unmarshal(raw, &msg); err != nil {
+				continue
+			}
+			switch msg.Type {
+			case MsgTypeCookieSpawn:
+				if rand.Float64() < b.profile.GoldenChance {
+					time.AfterFunc(b.profile.reaction(), func() {
+						room.HandleGameMessage(b.client, GameMessage{Type: MsgTypeCookieClick})
+					})
+				}
+			case MsgTypeGameOver:
+				return
+			}
+
+		case <-clickTimer.C:
+			if room.Over() {
+				return
+			}
+			if allowed, _, _ := b.client.limiter.Allow(); allowed {
+				room.HandleGameMessage(b.client, click)
+			}
+			clickTimer.Reset(b.profile.nextClick())
+
+		case <-deadline.C:
+			return
+		}
+	}
+}
+
+// startPracticeLocked starts an in-memory practice room of the client against a
+// bot. The room lives on this pod, so it works with and without Redis.
+// Must be called with gm.mutex held.
+func (gm *GameManager) startPracticeLocked(client *Client, profile BotProfile) {
+	bot := gm.newPracticeBot(profile)
+	gm.startRoom(ModeDuel, matchmakingRules(), "", client, bot.client)
+
+	// The bot is no connection of this pod
+	room := gm.clientRooms[bot.client]
+	delete(gm.clientRooms, bot.client)
+
+	log.Printf("Practice game of %s against the %s bot in room %s", client.userID, profile.Difficulty, room.ID)
+	go bot.play(room)
+}
+
+// handleJoinPractice starts a practice game against a bot of the requested
+// difficulty right away, leaving the matchmaking queue if needed
+func (gm *GameManager) handleJoinPractice(client *Client, difficulty string) {
+	if difficulty == "" {
+		difficulty = defaultBotDifficulty
+	}
+	profile, ok := botProfiles[difficulty]
+	if !ok {
+		sendError(client, "invalid_difficulty", "Difficulty must be easy, medium or hard")
+		return
+	}
+
+	gm.stopSpectating(client)
+	if IsRedisAvailable() {
+		RemoveFromQueue(client.userID)
+	}
+
+	gm.mutex.Lock()
+	if gm.inActiveGameLocked(client) {
+		gm.mutex.Unlock()
+		sendError(client, "already_in_game", "You are already in a game")
+		return
+	}
+	if gm.waiting == client {
+		gm.waiting = nil
+	}
+	gm.ffaWaiting = removeClient(gm.ffaWaiting, client)
+	gm.teamWaiting = removeClient(gm.teamWaiting, client)
+	delete(gm.queuedAt, client.userID)
+	gm.startPracticeLocked(client, profile)
+	gm.mutex.Unlock()
+}
+
+// schedulePracticeFallback gives a solo player who joined the 1v1 queue at
+// queuedAt a bot opponent if nobody was found for them in time
+func (gm *GameManager) schedulePracticeFallback(client *Client, queuedAt time.Time) {
+	if practiceFallbackDelay <= 0 {
+		return
+	}
+	time.AfterFunc(practiceFallbackDelay, func() { gm.practiceFallback(client, queuedAt) })
+}
+
+// practiceFallback starts a practice game for a player who is still waiting in
+// the queue they joined at queuedAt
+func (gm *GameManager) practiceFallback(client *Client, queuedAt time.Time) {
+	if !gm.stillQueued(client, queuedAt) {
+		return
+	}
+
+	gm.mutex.Lock()
+	local := gm.waiting == client
+	if local {
+		gm.waiting = nil
+	}
+	gm.mutex.Unlock()
+
+	// Taking the player out of the Redis queue waits for the matchmaking lock
+	if !local {
+		if taken, err := TakeFromQueue(client.userID); err != nil || !taken {
+			return // Matched in the meantime
+		}
+	}
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+	if !gm.clients[client] {
+		return // Disconnected meanwhile
+	}
+	delete(gm.queuedAt, client.userID)
+
+	log.Printf("No opponent found for %s within %s, a bot steps in", client.userID, practiceFallbackDelay)
+	gm.startPracticeLocked(client, botProfiles[defaultBotDifficulty])
+}
+
+// stillQueued reports whether the client is connected, idle and still waiting in
+// the queue they joined at queuedAt
+func (gm *GameManager) stillQueued(client *Client, queuedAt time.Time) bool {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	if !gm.clients[client] || !gm.queuedAt[client.userID].Equal(queuedAt) {
+		return false // Gone, matched or queued again since
+	}
+	return !gm.inActiveGameLocked(client)
+}
+
+// savePracticeResults stores the record of a game against a bot. Practice games
+// show up in the player's history but count for nothing else: no score, rating,
+// stats or achievements, and the bot itself gets no record.
+func savePracticeResults(gameID, winnerID string, results []PlayerResult, suspicious map[string]bool) {
+	timestamp := time.Now().Unix()
+
+	for _, res := range results {
+		if isBot(res.UserID) {
+			continue
+		}
+		var bot PlayerResult
+		for _, other := range results {
+			if other.UserID != res.UserID {
+				bot = other
+			}
+		}
+
+		db.SaveGameWithMock(db.CookieGame{
+			GameID: gameID, PlayerID: res.UserID, Timestamp: timestamp,
+			Score: res.Score, OpponentScore: bot.Score,
+			Reason: res.Reason, Won: winnerID == res.UserID, WinnerID: winnerID, Opponent: bot.UserID,
+			PlayerName: res.Name, PlayerPicture: res.Picture,
+			OpponentName: bot.Name, OpponentPicture: bot.Picture,
+			Suspicious: suspicious[res.UserID],
+			Mode:       ModeDuel, Placement: res.Placement, PlayerCount: len(results),
+			Practice: true,
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+)
+
+// Presence of a connected user is published as a Redis key with a TTL by the pod
+// holding their connection. Every pod refreshes the keys of its local users, so
+// the key of a user on a crashed pod simply expires and they show as offline.
+
+const (
+	presenceKeyPrefix = "overcookied:presence:" // UserID -> presenceRecord
+	presenceTTL       = 60 * time.Second
+	presenceRefresh   = 20 * time.Second // Must stay well below presenceTTL
+)
+
+// Presence states
+const (
+	PresenceOffline = "offline"
+	PresenceOnline  = "online"
+	PresenceQueued  = "queued"
+	PresenceInGame  = "in_game"
+)
+
+// presenceRecord is the stored presence of a user
+type presenceRecord struct {
+	Status string `json:"status"`
+	PodID  string `json:"podId"` // Pod holding the user's connection
+}
+
+// storePresence publishes the presence of a local user. Going offline only removes
+// the key if no other pod has taken over the user's connection in the meantime.
+func storePresence(userID, status string) error {
+	key := presenceKeyPrefix + userID
+	if status == PresenceOffline {
+		return kvUpdate(key, presenceTTL, func(current string, found bool) (string, error) {
+			var record presenceRecord
+			if found && json.Unmarshal([]byte(current), &record) == nil && record.PodID != GetPodID() {
+				return current, nil
+			}
+			return "", nil
+		})
+	}
+
+	recordJSON, err := json.Marshal(presenceRecord{Status: status, PodID: GetPodID()})
+	if err != nil {
+		return err
+	}
+	return kvSet(key, string(recordJSON), presenceTTL)
+}
+
+// GetPresence returns the presence of several users; users without a key are offline
+func GetPresence(userIDs []string) (map[string]string, error) {
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = presenceKeyPrefix + id
+	}
+	values, err := kvMGet(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	presence := make(map[string]string, len(userIDs))
+	for i, id := range userIDs {
+		var record presenceRecord
+		if values[i] == "" || json.Unmarshal([]byte(values[i]), &record) != nil {
+			presence[id] = PresenceOffline
+			continue
+		}
+		presence[id] = record.Status
+	}
+	return presence, nil
+}
+
+// presenceOf returns the presence of several users, from Redis or, in single-pod
+// mode, from this pod's connections
+func (gm *GameManager) presenceOf(userIDs []string) map[string]string {
+	if IsRedisAvailable() {
+		presence, err := GetPresence(userIDs)
+		if err == nil {
+			return presence
+		}
+		log.Printf("Failed to fetch presence: %v", err)
+	}
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+	presence := make(map[string]string, len(userIDs))
+	for _, id := range userIDs {
+		presence[id] = gm.localPresenceLocked(id)
+	}
+	return presence
+}
+
+// localPresenceLocked derives the presence of a user from this pod's view of their
+// connection. Caller must hold gm.mutex.
+func (gm *GameManager) localPresenceLocked(userID string) string {
+	client, ok := gm.clientsByID[userID]
+	if !ok {
+		return PresenceOffline
+	}
+	if gm.inActiveGameLocked(client) {
+		delete(gm.queuedAt, userID)
+		return PresenceInGame
+	}
+	if gm.waiting == client || slices.Contains(gm.ffaWaiting, client) || slices.Contains(gm.teamWaiting, client) {
+		return PresenceQueued
+	}
+	if since, ok := gm.queuedAt[userID]; ok {
+		if time.Since(since) < queueTTL {
+			return PresenceQueued
+		}
+		delete(gm.queuedAt, userID) // Queue entry has expired
+	}
+	return PresenceOnline
+}
+
+// refreshPresence republishes the presence of a user of this pod and tells their
+// friends if it changed. Must not be called with gm.mutex held.
+func (gm *GameManager) refreshPresence(userID string) {
+	gm.mutex.Lock()
+	status := gm.localPresenceLocked(userID)
+	previous, known := gm.presence[userID]
+	if status == PresenceOffline {
+		delete(gm.presence, userID)
+	} else {
+		gm.presence[userID] = status
+	}
+	gm.mutex.Unlock()
+
+	if IsRedisAvailable() {
+		if err := storePresence(userID, status); err != nil {
+			log.Printf("Failed to store presence of %s: %v", userID, err)
+		}
+	}
+	if (known && previous != status) || (!known && status != PresenceOffline) {
+		gm.notifyFriendsPresence(userID, status)
+	}
+}
+
+// notifyFriendsPresence sends a user's new presence to all their friends
+func (gm *GameManager) notifyFriendsPresence(userID, status string) {
+	friends, err := db.ListFriendsWithMock(userID)
+	if err != nil {
+		log.Printf("Failed to load friends of %s for presence: %v", userID, err)
+		return
+	}
+	msg := GameMessage{
+		Type:    MsgTypeFriendPresence,
+		Payload: map[string]string{"userId": userID, "status": status},
+	}
+	for _, f := range friends {
+		if f.Status == db.FriendAccepted {
+			gm.sendToUser(f.FriendID, msg)
+		}
+	}
+}
+
+// RunPresenceLoop keeps the presence keys of this pod's users alive and notices
+// queue entries that have expired
+func (gm *GameManager) RunPresenceLoop() {
+	ticker := time.NewTicker(presenceRefresh)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		gm.mutex.Lock()
+		userIDs := make([]string, 0, len(gm.clientsByID))
+		for userID := range gm.clientsByID {
+			userIDs = append(userIDs, userID)
+		}
+		gm.mutex.Unlock()
+
+		for _, userID := range userIDs {
+			gm.refreshPresence(userID)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+)
+
+// Match history page sizes. A page is enriched with the current profiles of all
+// players in one BatchGetItem, which takes at most 100 keys.
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 50
+)
+
+// PublicProfile is what every signed-in player can see about another player.
+// Like PublicLeaderboardEntry it exposes no user IDs or email addresses.
+type PublicProfile struct {
+	Name       string       `json:"name"`
+	Picture    string       `json:"picture"`
+	Score      int          `json:"score"`
+	Rating     int          `json:"rating"`
+	TotalGames int          `json:"totalGames"`
+	Games      []PublicGame `json:"games"`
+	NextCursor string       `json:"nextCursor,omitempty"` // Pass as ?cursor= for older games
+}
+
+// PublicGame is a game of a public profile, seen from the profile's player
+type PublicGame struct {
+	Timestamp       int64  `json:"timestamp"`
+	Mode            string `json:"mode"`
+	Score           int    `json:"score"`
+	OpponentScore   int    `json:"opponentScore"`
+	OpponentName    string `json:"opponentName"`
+	OpponentPicture string `json:"opponentPicture"`
+	Won             bool   `json:"won"`
+	Draw            bool   `json:"draw"`
+	Placement       int    `json:"placement"`
+	PlayerCount     int    `json:"playerCount"`
+}
+
+// PlayerStats are the lifetime statistics of a player, kept up to date after every
+// game instead of being computed from the match history
+type PlayerStats struct {
+	Games         int     `json:"games"`
+	Wins          int     `json:"wins"`
+	Losses        int     `json:"losses"`
+	Draws         int     `json:"draws"`
+	WinRate       float64 `json:"winRate"` // Share of games won, 0..1
+	BestScore     int     `json:"bestScore"`
+	AverageScore  float64 `json:"averageScore"`
+	AverageCPS    float64 `json:"averageCps"` // Clicks per second played
+	CurrentStreak int     `json:"currentStreak"`
+	LongestStreak int     `json:"longestStreak"`
+	GoldenCookies int     `json:"goldenCookies"` // Golden cookies claimed
+}
+
+// newPlayerStats derives the public statistics from the stored totals
+func newPlayerStats(s db.CookiePlayerStats) PlayerStats {
+	stats := PlayerStats{
+		Games:         s.Games,
+		Wins:          s.Wins,
+		Losses:        s.Losses,
+		Draws:         s.Draws,
+		BestScore:     s.BestScore,
+		CurrentStreak: s.CurrentStreak,
+		LongestStreak: s.LongestStreak,
+		GoldenCookies: s.GoldenCookies,
+	}
+	if s.Games > 0 {
+		stats.WinRate = round2(float64(s.Wins) / float64(s.Games))
+		stats.AverageScore = round2(float64(s.TotalScore) / float64(s.Games))
+	}
+	if s.TotalSeconds > 0 {
+		stats.AverageCPS = round2(float64(s.TotalClicks) / float64(s.TotalSeconds))
+	}
+	return stats
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// handleUserProfile returns the public profile of a player with a page of their
+// match history (?limit=, ?cursor=)
+func handleUserProfile(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := r.PathValue("userId")
+	limit, err := intParam(r.URL.Query(), "limit", defaultHistoryLimit, 1, maxHistoryLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := db.GetUserWithMock(userID)
+	if err != nil {
+		log.Printf("[API] Error fetching user %s: %v", userID, err)
+		http.Error(w, "Failed to fetch profile", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	games, nextCursor, err := db.GetGameHistoryWithMock(userID, int32(limit), r.URL.Query().Get("cursor"))
+	if errors.Is(err, db.ErrInvalidCursor) {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("[API] Error fetching history of %s: %v", userID, err)
+		http.Error(w, "Failed to fetch profile", http.StatusInternalServerError)
+		return
+	}
+	totalGames, err := db.CountGamesByPlayerWithMock(userID)
+	if err != nil {
+		log.Printf("[API] Error counting games: %v", err)
+		totalGames = len(games)
+	}
+
+	profile := PublicProfile{
+		Name:       user.Name,
+		Picture:    user.Picture,
+		Score:      user.Score,
+		Rating:     user.EffectiveRating(),
+		TotalGames: totalGames,
+		Games:      make([]PublicGame, len(games)),
+		NextCursor: nextCursor,
+	}
+	for i, g := range games {
+		profile.Games[i] = PublicGame{
+			Timestamp:       g.Timestamp,
+			Mode:            g.Mode,
+			Score:           g.Score,
+			OpponentScore:   g.OpponentScore,
+			OpponentName:    g.OpponentName,
+			OpponentPicture: g.OpponentPicture,
+			Won:             g.Won,
+			Draw:            g.WinnerID == "draw",
+			Placement:       g.Placement,
+			PlayerCount:     g.PlayerCount,
+		}
+	}
+	json.NewEncoder(w).Encode(profile)
+}
+
+// handleUserStats returns the lifetime statistics of a player
+func handleUserStats(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := r.PathValue("userId")
+	user, err := db.GetUserWithMock(userID)
+	if err != nil {
+		log.Printf("[API] Error fetching user %s: %v", userID, err)
+		http.Error(w, "Failed to fetch stats", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	stats, err := db.GetPlayerStatsWithMock(userID)
+	if err != nil {
+		log.Printf("[API] Error fetching stats of %s: %v", userID, err)
+		http.Error(w, "Failed to fetch stats", http.StatusInternalServerError)
+		return
+	}
+	if stats == nil {
+		stats = &db.CookiePlayerStats{UserID: userID} // Has not finished a game yet
+	}
+	json.NewEncoder(w).Encode(newPlayerStats(*stats))
+}
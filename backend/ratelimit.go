@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Click rate limit configuration (overridable via environment variables)
+var (
+	maxClicksPerSecond = 20.0 // CLICK_RATE_LIMIT: sustained clicks per second a client may send
+	clickBurst         = 10.0 // CLICK_BURST: extra clicks allowed in a short burst
+	suspiciousCPS      = 15   // SUSPICIOUS_CPS: click attempts per second considered superhuman
+	suspiciousSeconds  = 5    // SUSPICIOUS_SECONDS: consecutive seconds above SUSPICIOUS_CPS before flagging
+)
+
+// initClickLimits loads the click rate limit configuration from the environment
+func initClickLimits() {
+	if v, err := strconv.ParseFloat(os.Getenv("CLICK_RATE_LIMIT"), 64); err == nil && v > 0 {
+		maxClicksPerSecond = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("CLICK_BURST"), 64); err == nil && v >= 0 {
+		clickBurst = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SUSPICIOUS_CPS")); err == nil && v > 0 {
+		suspiciousCPS = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SUSPICIOUS_SECONDS")); err == nil && v > 0 {
+		suspiciousSeconds = v
+	}
+	log.Printf("[ANTICHEAT] Click limit: %.0f CPS (burst %.0f), flagging above %d CPS for %ds",
+		maxClicksPerSecond, clickBurst, suspiciousCPS, suspiciousSeconds)
+}
+
+// clickLimiter is a per-client token bucket that caps the click rate and
+// detects sustained superhuman click rates
+type clickLimiter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	// Sustained rate tracking in one-second windows
+	windowStart  time.Time
+	windowClicks int
+	fastSeconds  int
+	flagged      bool
+}
+
+func newClickLimiter() *clickLimiter {
+	return &clickLimiter{tokens: maxClicksPerSecond + clickBurst, last: time.Now()}
+}
+
+// Allow registers a click attempt. It reports whether the click is within the rate
+// limit, how long to wait for the next token if not, and whether this attempt
+// caused the client to be flagged as suspicious (reported only once per game).
+func (l *clickLimiter) Allow() (allowed bool, retryAfter time.Duration, flagged bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	// Refill bucket
+	capacity := maxClicksPerSecond + clickBurst
+	l.tokens += now.Sub(l.last).Seconds() * maxClicksPerSecond
+	if l.tokens > capacity {
+		l.tokens = capacity
+	}
+	l.last = now
+
+	// Track attempts per second, rejected ones included
+	if elapsed := now.Sub(l.windowStart); elapsed >= time.Second {
+		if elapsed < 2*time.Second && l.windowClicks >= suspiciousCPS {
+			l.fastSeconds++
+		} else {
+			l.fastSeconds = 0
+		}
+		l.windowStart = now
+		l.windowClicks = 0
+	}
+	l.windowClicks++
+
+	if !l.flagged && l.fastSeconds >= suspiciousSeconds {
+		l.flagged = true
+		flagged = true
+	}
+
+	if l.tokens < 1 {
+		retryAfter = time.Duration((1 - l.tokens) / maxClicksPerSecond * float64(time.Second))
+		return false, retryAfter, flagged
+	}
+
+	l.tokens--
+	return true, 0, flagged
+}
+
+// Reset clears the limiter state, called when the client enters a new game
+func (l *clickLimiter) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens = maxClicksPerSecond + clickBurst
+	l.last = time.Now()
+	l.windowStart = time.Time{}
+	l.windowClicks = 0
+	l.fastSeconds = 0
+	l.flagged = false
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+)
+
+const (
+	eloKFactor = 32 // Maximum rating change per game
+
+	// Matchmaking rating window: players are paired if their rating difference is
+	// within baseRatingWindow, widened by ratingWindowGrowth for every second the
+	// longer-waiting player has been queued. After ratingWindowOpenAfter anyone matches.
+	baseRatingWindow      = 100
+	ratingWindowGrowth    = 25
+	ratingWindowOpenAfter = 20 // seconds, must stay below queueTTL
+)
+
+// lookupRating returns the current rating of a player, or the default rating if
+// the player cannot be loaded
+func lookupRating(userID string) int {
+	user, err := db.GetUserWithMock(userID)
+	if err != nil || user == nil {
+		return db.DefaultRating
+	}
+	return user.EffectiveRating()
+}
+
+// expectedScore is the Elo win expectancy of a player rated ra against rb
+func expectedScore(ra, rb int) float64 {
+	return 1 / (1 + math.Pow(10, float64(rb-ra)/400))
+}
+
+// eloDelta is the rating change of a player rated ra after a game against rb.
+// result is 1 for a win, 0 for a loss and 0.5 for a draw.
+func eloDelta(ra, rb int, result, k float64) int {
+	return int(math.Round(k * (result - expectedScore(ra, rb))))
+}
+
+// updateRatings applies the Elo update for a finished game. Multi-player games are
+// scored as a pairwise duel between every two opponents, decided by placement, with
+// the K-factor split across a player's pairings so a match moves a rating by at most
+// eloKFactor. Teammates are not paired. For two players this is the plain 1v1 update.
+// Returns the new rating of every player.
+func updateRatings(results []PlayerResult) map[string]int {
+	if len(results) < 2 {
+		return nil
+	}
+
+	ratings := make([]int, len(results))
+	for i, res := range results {
+		ratings[i] = lookupRating(res.UserID)
+	}
+
+	opponents := len(results) - 1
+	if results[0].Team != "" {
+		opponents = 0
+		for _, res := range results {
+			if res.Team != results[0].Team {
+				opponents++
+			}
+		}
+	}
+
+	k := float64(eloKFactor) / float64(opponents)
+	deltas := make([]int, len(results))
+	for i := range results {
+		for j := i + 1; j < len(results); j++ {
+			if results[i].Team != "" && results[i].Team == results[j].Team {
+				continue
+			}
+			result := 0.5
+			if results[i].Placement < results[j].Placement {
+				result = 1
+			} else if results[j].Placement < results[i].Placement {
+				result = 0
+			}
+			delta := eloDelta(ratings[i], ratings[j], result, k)
+			deltas[i] += delta
+			deltas[j] -= delta
+		}
+	}
+
+	newRatings := make(map[string]int, len(results))
+	for i, res := range results {
+		newRating := ratings[i] + deltas[i]
+		if err := db.UpdateUserRatingWithMock(res.UserID, newRating); err != nil {
+			log.Printf("Failed to update rating for %s: %v", res.UserID, err)
+			newRatings[res.UserID] = ratings[i]
+			continue
+		}
+		newRatings[res.UserID] = newRating
+		recordLeaderboardRating(res.UserID, newRating)
+		log.Printf("Rating updated: %s %d -> %d", res.UserID, ratings[i], newRating)
+	}
+	return newRatings
+}
+
+// forfeitResults ranks the players of a duel or team match given up by loserID:
+// the loser's side places last, whatever the score
+func forfeitResults(r *Roster, loserID string) []PlayerResult {
+	results := r.Results()
+	loserTeam := r.Team(loserID)
+	for i := range results {
+		results[i].Placement = 1
+		if results[i].UserID == loserID || (loserTeam != "" && results[i].Team == loserTeam) {
+			results[i].Placement = 2
+		}
+	}
+	return results
+}
+
+// ratingWindow returns the accepted rating difference after waiting the given seconds
+func ratingWindow(waitSeconds int64) int {
+	if waitSeconds >= ratingWindowOpenAfter {
+		return math.MaxInt32
+	}
+	return baseRatingWindow + ratingWindowGrowth*int(waitSeconds)
+}
+
+// selectRatedPair picks two queue entries to match. Entries must be ordered by
+// JoinedAt (oldest first); the oldest player who has an acceptable opponent gets
+// the closest-rated one. Parties are skipped. Returns ok=false if nobody can be
+// matched yet.
+func selectRatedPair(entries []QueueEntry, now int64) (int, int, bool) {
+	for i := range entries {
+		if entries[i].unitSize() > 1 {
+			continue
+		}
+		best := -1
+		bestDiff := 0
+		for j := range entries {
+			if i == j || entries[i].UserID == entries[j].UserID || entries[j].unitSize() > 1 {
+				continue
+			}
+
+			// The window is driven by whoever has waited longer
+			wait := now - entries[i].JoinedAt
+			if w := now - entries[j].JoinedAt; w > wait {
+				wait = w
+			}
+
+			diff := entries[i].Rating - entries[j].Rating
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > ratingWindow(wait) {
+				continue
+			}
+			if best == -1 || diff < bestDiff {
+				best = j
+				bestDiff = diff
+			}
+		}
+
+		if best != -1 {
+			return i, best, true
+		}
+	}
+	return 0, 0, false
+}
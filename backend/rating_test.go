@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func TestEloDelta(t *testing.T) {
+	tests := []struct {
+		name   string
+		ra, rb int
+		result float64
+		k      float64
+		want   int
+	}{
+		{"equal win", 1200, 1200, 1, eloKFactor, 16},
+		{"equal loss", 1200, 1200, 0, eloKFactor, -16},
+		{"equal draw", 1200, 1200, 0.5, eloKFactor, 0},
+		{"favourite wins", 1600, 1200, 1, eloKFactor, 3},
+		{"underdog wins", 1200, 1600, 1, eloKFactor, 29},
+		{"underdog loses", 1200, 1600, 0, eloKFactor, -3},
+		{"favourite draws", 1600, 1200, 0.5, eloKFactor, -13},
+		{"split k-factor", 1200, 1200, 1, eloKFactor / 2, 8},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := eloDelta(tc.ra, tc.rb, tc.result, tc.k)
+			if got != tc.want {
+				t.Errorf("Expected delta %d, got %d", tc.want, got)
+			}
+			// Whatever one player wins the other loses
+			if other := eloDelta(tc.rb, tc.ra, 1-tc.result, tc.k); other != -got {
+				t.Errorf("Expected the opponent to get %d, got %d", -got, other)
+			}
+		})
+	}
+}
+
+func TestSelectRatedPair(t *testing.T) {
+	const now = 1000
+	entry := func(userID string, rating int, joinedAt int64) QueueEntry {
+		return QueueEntry{UserID: userID, Rating: rating, JoinedAt: joinedAt}
+	}
+
+	tests := []struct {
+		name    string
+		entries []QueueEntry
+		ok      bool
+		i, j    int
+	}{
+		{"empty queue", nil, false, 0, 0},
+		{"single player", []QueueEntry{entry("a", 1200, now)}, false, 0, 0},
+		{"same user twice", []QueueEntry{entry("a", 1200, now), entry("a", 1200, now)}, false, 0, 0},
+		{"within base window", []QueueEntry{entry("a", 1200, now), entry("b", 1300, now)}, true, 0, 1},
+		{"outside base window", []QueueEntry{entry("a", 1200, now), entry("b", 1301, now)}, false, 0, 0},
+		{"window widened by wait", []QueueEntry{entry("a", 1200, now-4), entry("b", 1400, now)}, true, 0, 1},
+		{"not widened enough", []QueueEntry{entry("a", 1200, now-3), entry("b", 1400, now)}, false, 0, 0},
+		{"opponent's wait counts", []QueueEntry{entry("a", 1200, now), entry("b", 1400, now-4)}, true, 0, 1},
+		{"open after waiting long enough", []QueueEntry{entry("a", 1200, now-ratingWindowOpenAfter), entry("b", 2500, now)}, true, 0, 1},
+		{"closest rated opponent", []QueueEntry{entry("a", 1200, now), entry("b", 1290, now), entry("c", 1210, now)}, true, 0, 2},
+		{"parties are skipped", []QueueEntry{{UserID: "a", Rating: 1200, JoinedAt: now, PartyID: "p", PartySize: 2}, entry("b", 1200, now), entry("c", 1210, now)}, true, 1, 2},
+		{"oldest matchable player first", []QueueEntry{entry("a", 1000, now-1), entry("b", 1500, now), entry("c", 1520, now)}, true, 1, 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			i, j, ok := selectRatedPair(tc.entries, now)
+			if ok != tc.ok {
+				t.Fatalf("Expected ok=%t, got %t (%d, %d)", tc.ok, ok, i, j)
+			}
+			if ok && (i != tc.i || j != tc.j) {
+				t.Errorf("Expected pair (%d, %d), got (%d, %d)", tc.i, tc.j, i, j)
+			}
+		})
+	}
+}
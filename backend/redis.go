@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"time"
 
@@ -19,14 +21,50 @@ type QueueEntry struct {
 	Picture  string `json:"picture"`
 	PodID    string `json:"podId"`
 	JoinedAt int64  `json:"joinedAt"`
+	Rating   int    `json:"rating"`
+	Mode     string `json:"mode,omitempty"` // ModeDuel (default), ModeFFA or ModeTeam
+
+	// Set when a party leader queues for their whole party; the entry then stands
+	// for all members (see partyMembers)
+	PartyID   string `json:"partyId,omitempty"`
+	PartySize int    `json:"partySize,omitempty"`
+}
+
+// gamePlayer returns the match participant described by the entry
+func (e QueueEntry) gamePlayer() GamePlayer {
+	return GamePlayer{UserID: e.UserID, Name: e.Name, Picture: e.Picture}
 }
 
 // MatchNotification is sent via Pub/Sub when a match is found
 type MatchNotification struct {
-	Player1ID string `json:"player1Id"`
-	Player2ID string `json:"player2Id"`
-	RoomID    string `json:"roomId"`
-	HostPodID string `json:"hostPodId"`
+	Player1ID string   `json:"player1Id"`
+	Player2ID string   `json:"player2Id"`
+	PlayerIDs []string `json:"playerIds"` // All players in seat order
+	Mode      string   `json:"mode"`
+	RoomID    string   `json:"roomId"`
+	HostPodID string   `json:"hostPodId"`
+}
+
+// newMatchNotification announces a room hosted by this pod to its players
+func newMatchNotification(roomID, mode string, playerIDs []string) MatchNotification {
+	match := MatchNotification{
+		PlayerIDs: playerIDs,
+		Mode:      mode,
+		RoomID:    roomID,
+		HostPodID: GetPodID(),
+	}
+	if len(playerIDs) >= 2 {
+		match.Player1ID, match.Player2ID = playerIDs[0], playerIDs[1]
+	}
+	return match
+}
+
+// Players returns the IDs of all matched players
+func (m MatchNotification) Players() []string {
+	if len(m.PlayerIDs) > 0 {
+		return m.PlayerIDs
+	}
+	return []string{m.Player1ID, m.Player2ID}
 }
 
 var (
@@ -82,23 +120,26 @@ func InitRedis() error {
 	return nil
 }
 
-// AddToQueue adds a player to the matchmaking queue
-func AddToQueue(client *Client) error {
+// unitSize returns the number of players the entry stands for
+func (e QueueEntry) unitSize() int {
+	if e.PartySize > 1 {
+		return e.PartySize
+	}
+	return 1
+}
+
+// AddToQueue adds a player (or a party, via its leader) to the matchmaking queue
+func AddToQueue(entry QueueEntry) error {
 	if useMockRedis {
-		return mocks.GetMockRedis().AddToQueue(client.userID, client.name, client.picture)
+		return mocks.GetMockRedis().Enqueue(mocks.QueueEntry(entry))
 	}
 
 	if redisClient == nil {
 		return fmt.Errorf("redis not initialized")
 	}
 
-	entry := QueueEntry{
-		UserID:   client.userID,
-		Name:     client.name,
-		Picture:  client.picture,
-		PodID:    podID,
-		JoinedAt: time.Now().Unix(),
-	}
+	// Drop a previous entry, e.g. when switching modes
+	RemoveFromQueue(entry.UserID)
 
 	entryJSON, err := json.Marshal(entry)
 	if err != nil {
@@ -115,7 +156,7 @@ func AddToQueue(client *Client) error {
 		return err
 	}
 
-	log.Printf("Added %s to matchmaking queue", client.userID)
+	log.Printf("Added %s to matchmaking queue", entry.UserID)
 	return nil
 }
 
@@ -150,77 +191,178 @@ func RemoveFromQueue(userID string) error {
 	return nil
 }
 
-// TryMatchmaking attempts to find a match for players in the queue
-// Returns matched player entries if found, nil otherwise
-func TryMatchmaking() (*QueueEntry, *QueueEntry, error) {
+// TakeFromQueue removes a player from the matchmaking queue and reports whether
+// they were still in it, i.e. not matched in the meantime. Holds the matchmaking
+// lock so the player cannot be picked for a match at the same time.
+func TakeFromQueue(userID string) (bool, error) {
+	if useMockRedis {
+		return mocks.GetMockRedis().TakeFromQueue(userID) != nil, nil
+	}
+
+	if redisClient == nil {
+		return false, fmt.Errorf("redis not initialized")
+	}
+
+	// Matchmaking only holds the lock for a moment
+	for attempt := 0; ; attempt++ {
+		acquired, err := redisClient.SetNX(ctx, matchmakingLockKey, podID, 2*time.Second).Result()
+		if err != nil {
+			return false, err
+		}
+		if acquired {
+			break
+		}
+		if attempt == 20 {
+			return false, fmt.Errorf("matchmaking lock busy")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	defer redisClient.Del(ctx, matchmakingLockKey)
+
+	entries, err := redisClient.ZRange(ctx, matchmakingQueueKey, 0, -1).Result()
+	if err != nil {
+		return false, err
+	}
+
+	for _, entryStr := range entries {
+		var entry QueueEntry
+		if err := json.Unmarshal([]byte(entryStr), &entry); err != nil {
+			continue
+		}
+		if entry.UserID == userID {
+			removed, err := redisClient.ZRem(ctx, matchmakingQueueKey, entryStr).Result()
+			return removed == 1, err
+		}
+	}
+	return false, nil
+}
+
+// TryMatchmaking attempts to find a 1v1 match for players in the queue, preferring
+// opponents with a similar rating (see selectRatedPair). A party of two plays
+// against each other right away.
+// Returns the matched players if found, nil otherwise
+func TryMatchmaking() ([]QueueEntry, error) {
+	units, err := matchFromQueue(ModeDuel, func(entries []QueueEntry, now int64) []int {
+		for i, entry := range entries {
+			if entry.unitSize() == 2 {
+				return []int{i}
+			}
+		}
+		i, j, ok := selectRatedPair(entries, now)
+		if !ok {
+			return nil
+		}
+		return []int{i, j}
+	})
+	if err != nil || units == nil {
+		return nil, err
+	}
+
+	groups, err := expandUnits(units)
+	if err != nil {
+		return nil, err
+	}
+	matched := flattenUnits(groups)
+
+	log.Printf("Matched players: %s (%d) vs %s (%d)", matched[0].UserID, matched[0].Rating, matched[1].UserID, matched[1].Rating)
+	return matched, nil
+}
+
+// matchFromQueue runs a matchmaking strategy over the queue entries of one mode.
+// pick receives the live entries (oldest first) and returns the indexes of the players
+// to match, or nil. The picked players are removed from the queue and returned.
+func matchFromQueue(mode string, pick func(entries []QueueEntry, now int64) []int) ([]QueueEntry, error) {
+	now := time.Now().Unix()
+
 	if useMockRedis {
-		matched, err := mocks.GetMockRedis().TryMatch()
-		if err != nil || matched == nil || len(matched) < 2 {
-			return nil, nil, err
+		mockRedis := mocks.GetMockRedis()
+		var entries []QueueEntry
+		for _, e := range mockRedis.GetQueueEntries() {
+			if now-e.JoinedAt >= int64(mocks.QueueTTL.Seconds()) {
+				continue // Stale, about to be cleaned up
+			}
+			if entry := QueueEntry(e); entry.queueMode() == mode {
+				entries = append(entries, entry)
+			}
+		}
+
+		picked := pick(entries, now)
+		if picked == nil {
+			return nil, nil
 		}
-		p1 := QueueEntry{
-			UserID:   matched[0].UserID,
-			Name:     matched[0].Name,
-			Picture:  matched[0].Picture,
-			PodID:    matched[0].PodID,
-			JoinedAt: matched[0].JoinedAt,
+		matched := make([]QueueEntry, len(picked))
+		userIDs := make([]string, len(picked))
+		for k, idx := range picked {
+			matched[k] = entries[idx]
+			userIDs[k] = entries[idx].UserID
 		}
-		p2 := QueueEntry{
-			UserID:   matched[1].UserID,
-			Name:     matched[1].Name,
-			Picture:  matched[1].Picture,
-			PodID:    matched[1].PodID,
-			JoinedAt: matched[1].JoinedAt,
+		if mockRedis.TakeFromQueue(userIDs...) == nil {
+			return nil, nil // Someone left the queue meanwhile
 		}
-		return &p1, &p2, nil
+		return matched, nil
 	}
 
 	if redisClient == nil {
-		return nil, nil, fmt.Errorf("redis not initialized")
+		return nil, fmt.Errorf("redis not initialized")
 	}
 
 	// Acquire distributed lock
 	lockKey := matchmakingLockKey
 	acquired, err := redisClient.SetNX(ctx, lockKey, podID, 2*time.Second).Result()
 	if err != nil || !acquired {
-		return nil, nil, nil // Another pod is handling matchmaking
+		return nil, nil // Another pod is handling matchmaking
 	}
 	defer redisClient.Del(ctx, lockKey)
 
-	// Get first 2 players from queue
-	entries, err := redisClient.ZRange(ctx, matchmakingQueueKey, 0, 1).Result()
+	// Get all waiting players, oldest first
+	rawEntries, err := redisClient.ZRange(ctx, matchmakingQueueKey, 0, -1).Result()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	if len(entries) < 2 {
-		return nil, nil, nil // Not enough players
+	entries := make([]QueueEntry, 0, len(rawEntries))
+	members := make([]string, 0, len(rawEntries))
+	for _, raw := range rawEntries {
+		var entry QueueEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			continue
+		}
+		if entry.queueMode() != mode {
+			continue
+		}
+		entries = append(entries, entry)
+		members = append(members, raw)
 	}
 
-	var player1, player2 QueueEntry
-	if err := json.Unmarshal([]byte(entries[0]), &player1); err != nil {
-		return nil, nil, err
+	picked := pick(entries, now)
+	if picked == nil {
+		return nil, nil
 	}
-	if err := json.Unmarshal([]byte(entries[1]), &player2); err != nil {
-		return nil, nil, err
+
+	matched := make([]QueueEntry, len(picked))
+	pickedMembers := make([]interface{}, len(picked))
+	for k, idx := range picked {
+		matched[k] = entries[idx]
+		pickedMembers[k] = members[idx]
 	}
 
-	// Remove both players from queue
-	redisClient.ZRem(ctx, matchmakingQueueKey, entries[0], entries[1])
+	// Remove matched players from queue
+	redisClient.ZRem(ctx, matchmakingQueueKey, pickedMembers...)
+	return matched, nil
+}
 
-	log.Printf("Matched players: %s vs %s", player1.UserID, player2.UserID)
-	return &player1, &player2, nil
+// queueMode returns the mode the entry is queued for (entries without one are 1v1)
+func (e QueueEntry) queueMode() string {
+	if e.Mode == "" {
+		return ModeDuel
+	}
+	return e.Mode
 }
 
 // PublishMatchNotification publishes a match notification to all pods
 func PublishMatchNotification(match MatchNotification) error {
 	if useMockRedis {
-		return mocks.GetMockRedis().PublishMatch(mocks.MatchNotification{
-			Player1ID: match.Player1ID,
-			Player2ID: match.Player2ID,
-			RoomID:    match.RoomID,
-			HostPodID: match.HostPodID,
-		})
+		return mocks.GetMockRedis().PublishMatch(mocks.MatchNotification(match))
 	}
 
 	if redisClient == nil {
@@ -290,26 +432,39 @@ func GetPodID() string {
 
 // ==================== DISTRIBUTED GAME STATE ====================
 
-// DistributedGameState stores the game state in Redis
+// DistributedGameState stores the game state in Redis. Players and scores live in
+// the embedded Roster; the Player1/Player2 fields mirror the first two seats for 1v1 clients.
 type DistributedGameState struct {
-	RoomID             string           `json:"roomId"`
-	Player1ID          string           `json:"player1Id"`
-	Player2ID          string           `json:"player2Id"`
-	Player1Name        string           `json:"player1Name"`
-	Player2Name        string           `json:"player2Name"`
-	Player1Picture     string           `json:"player1Picture"`
-	Player2Picture     string           `json:"player2Picture"`
-	P1Score            int              `json:"p1Score"`
-	P2Score            int              `json:"p2Score"`
-	TimeRemaining      int              `json:"timeRemaining"`
-	GoldenCookieActive bool             `json:"goldenCookieActive"`
-	GoldenCookieX      float64          `json:"goldenCookieX"`
-	GoldenCookieY      float64          `json:"goldenCookieY"`
-	DoubleClickExpiry  map[string]int64 `json:"doubleClickExpiry"` // UserID -> Unix timestamp
-	GameStarted        bool             `json:"gameStarted"`
-	GameEnded          bool             `json:"gameEnded"`
-	WinnerID           string           `json:"winnerId"`
-	TimerPodID         string           `json:"timerPodId"` // Pod responsible for timer
+	Roster
+	RoomID             string            `json:"roomId"`
+	Player1ID          string            `json:"player1Id"`
+	Player2ID          string            `json:"player2Id"`
+	Player1Name        string            `json:"player1Name"`
+	Player2Name        string            `json:"player2Name"`
+	Player1Picture     string            `json:"player1Picture"`
+	Player2Picture     string            `json:"player2Picture"`
+	P1Score            int               `json:"p1Score"`
+	P2Score            int               `json:"p2Score"`
+	TimeRemaining      int               `json:"timeRemaining"`
+	GoldenCookieActive bool              `json:"goldenCookieActive"`
+	GoldenCookieX      float64           `json:"goldenCookieX"`
+	GoldenCookieY      float64           `json:"goldenCookieY"`
+	PowerUpType        string            `json:"powerUpType"`       // Type of the spawned golden cookie
+	Effects            PowerUpEffects    `json:"effects"`           // Active power-up effects
+	SuspiciousPlayers  map[string]bool   `json:"suspiciousPlayers"` // UserID -> flagged by anti-cheat
+	DisconnectedAt     map[string]int64  `json:"disconnectedAt"`    // UserID -> Unix millis the websocket dropped
+	Connections        map[string]string `json:"connections"`       // UserID -> connection currently playing the seat
+	GameStarted        bool              `json:"gameStarted"`
+	GameEnded          bool              `json:"gameEnded"`
+	WinnerID           string            `json:"winnerId"`
+	TimerPodID         string            `json:"timerPodId"`     // Pod responsible for timer
+	TimerHeartbeat     int64             `json:"timerHeartbeat"` // Unix millis of the timer pod's last lease renewal
+	StartsAt           int64             `json:"startsAt"`       // Unix millis when the countdown ends
+	Rules              GameRules         `json:"rules"`
+	RematchOf          string            `json:"rematchOf,omitempty"` // RoomID of the game this one is a rematch of
+	SeriesID           string            `json:"seriesId,omitempty"`  // Series this game is a round of
+	TournamentID       string            `json:"tournamentId,omitempty"`
+	TournamentMatch    string            `json:"tournamentMatch,omitempty"` // Bracket match this game decides
 }
 
 // GameEvent represents an event that needs to be broadcast to all pods
@@ -324,6 +479,17 @@ const (
 	gameStateKeyPrefix = "overcookied:game:"
 	gameEventChannel   = "overcookied:game:events"
 	gameStateTTL       = 10 * time.Minute
+	activeGamesKey     = "overcookied:games:active"
+	playerRoomPrefix   = "overcookied:player:room:"
+	timerLeaseTimeout  = 5 * time.Second  // Timer pod is considered dead after this long without a heartbeat
+	reconnectGrace     = 20 * time.Second // Time a disconnected player has to reconnect before forfeiting
+	maxTxRetries       = 5
+)
+
+var (
+	errGameNotFound    = errors.New("game not found")
+	errTimerLeaseHeld  = errors.New("timer lease held by another pod")
+	errStaleConnection = errors.New("seat taken over by a newer connection")
 )
 
 // Event types
@@ -335,55 +501,264 @@ const (
 	EventStateUpdate = "STATE_UPDATE"
 	EventGameEnd     = "GAME_END"
 	EventPlayerQuit  = "PLAYER_QUIT"
+
+	EventPlayerDisconnected = "PLAYER_DISCONNECTED"
+	EventPlayerReconnected  = "PLAYER_RECONNECTED"
+	EventPlayerEliminated   = "PLAYER_ELIMINATED" // FFA player left, the match goes on
 )
 
-// CreateDistributedGame creates a new game in Redis or mock store
-func CreateDistributedGame(roomID string, p1, p2 *QueueEntry) error {
+// syncLegacyFields mirrors the first two seats of the roster into the Player1/Player2 fields
+func (s *DistributedGameState) syncLegacyFields() {
+	if len(s.Players) < 2 {
+		return
+	}
+	p1, p2 := s.Players[0], s.Players[1]
+	s.Player1ID, s.Player1Name, s.Player1Picture = p1.UserID, p1.Name, p1.Picture
+	s.Player2ID, s.Player2Name, s.Player2Picture = p2.UserID, p2.Name, p2.Picture
+	s.P1Score = s.Scores[p1.UserID]
+	s.P2Score = s.Scores[p2.UserID]
+}
+
+// upgradeLegacyState builds the roster of a 1v1 game stored before rosters existed
+func (s *DistributedGameState) upgradeLegacyState() {
+	if len(s.Players) > 0 || s.Player1ID == "" {
+		return
+	}
+	s.Roster = newRoster(ModeDuel, []GamePlayer{
+		{UserID: s.Player1ID, Name: s.Player1Name, Picture: s.Player1Picture},
+		{UserID: s.Player2ID, Name: s.Player2Name, Picture: s.Player2Picture},
+	})
+	s.Scores[s.Player1ID] = s.P1Score
+	s.Scores[s.Player2ID] = s.P2Score
+}
+
+// MatchRules returns the rules of the game, falling back to the default preset
+// for games created before rules were stored in the state
+func (s *DistributedGameState) MatchRules() GameRules {
+	if !s.Rules.valid() {
+		return rulePresets[defaultRulesPreset]
+	}
+	return s.Rules
+}
+
+// ExpiredDisconnect returns the ID of a player whose reconnect grace period has
+// run out, or "" if every disconnected player is still within the window
+func (s *DistributedGameState) ExpiredDisconnect(now time.Time) string {
+	for userID, disconnectedAt := range s.DisconnectedAt {
+		if now.Sub(time.UnixMilli(disconnectedAt)) >= reconnectGrace {
+			return userID
+		}
+	}
+	return ""
+}
+
+// CreateDistributedGame creates a new game of the given mode, played under the given
+// rules, in Redis or mock store. Players are seated in the order given. setup
+// adjusts the state before it is stored, e.g. to link a rematch to its previous game.
+func CreateDistributedGame(roomID, mode string, entries []QueueEntry, rules GameRules, setup ...func(*DistributedGameState)) error {
+	if !rules.valid() {
+		rules = matchmakingRules()
+	}
+
+	players := make([]GamePlayer, len(entries))
+	for i, entry := range entries {
+		players[i] = entry.gamePlayer()
+	}
+
 	state := DistributedGameState{
+		Roster:             newRoster(mode, players),
 		RoomID:             roomID,
-		Player1ID:          p1.UserID,
-		Player2ID:          p2.UserID,
-		Player1Name:        p1.Name,
-		Player2Name:        p2.Name,
-		Player1Picture:     p1.Picture,
-		Player2Picture:     p2.Picture,
-		P1Score:            0,
-		P2Score:            0,
-		TimeRemaining:      60,
+		TimeRemaining:      rules.DurationSeconds,
 		GoldenCookieActive: false,
-		DoubleClickExpiry:  make(map[string]int64),
+		Effects:            newPowerUpEffects(),
+		SuspiciousPlayers:  make(map[string]bool),
+		DisconnectedAt:     make(map[string]int64),
+		Connections:        make(map[string]string),
 		GameStarted:        false,
 		GameEnded:          false,
 		TimerPodID:         podID,
+		TimerHeartbeat:     time.Now().UnixMilli(),
+		StartsAt:           time.Now().Add(rules.Countdown()).UnixMilli(),
+		Rules:              rules,
+	}
+	state.syncLegacyFields()
+	for _, fn := range setup {
+		fn(&state)
+	}
+
+	if err := SaveGameState(&state); err != nil {
+		return err
+	}
+
+	for _, userID := range state.IDs() {
+		if err := SetPlayerRoom(userID, roomID); err != nil {
+			log.Printf("Failed to record room for player %s: %v", userID, err)
+		}
+	}
+
+	if useMockRedis {
+		return nil
+	}
+	return redisClient.SAdd(ctx, activeGamesKey, roomID).Err()
+}
+
+// SetPlayerRoom records the room a player is in so they can rejoin after a reconnect
+func SetPlayerRoom(userID, roomID string) error {
+	if useMockRedis {
+		return mocks.GetMockGameStore().SetPlayerRoom(userID, roomID)
+	}
+
+	if redisClient == nil {
+		return fmt.Errorf("redis not initialized")
 	}
 
-	return SaveGameState(&state)
+	return redisClient.Set(ctx, playerRoomPrefix+userID, roomID, gameStateTTL).Err()
+}
+
+// GetPlayerRoom returns the room a player was last placed in, or "" if none
+func GetPlayerRoom(userID string) (string, error) {
+	if useMockRedis {
+		return mocks.GetMockGameStore().GetPlayerRoom(userID)
+	}
+
+	if redisClient == nil {
+		return "", fmt.Errorf("redis not initialized")
+	}
+
+	roomID, err := redisClient.Get(ctx, playerRoomPrefix+userID).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return roomID, err
+}
+
+// ClearPlayerRoom removes the player's room mapping if it still points to roomID
+func ClearPlayerRoom(userID, roomID string) error {
+	if useMockRedis {
+		return mocks.GetMockGameStore().ClearPlayerRoom(userID, roomID)
+	}
+
+	if redisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+
+	key := playerRoomPrefix + userID
+	current, err := redisClient.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if current != roomID {
+		return nil // Player already moved on to another game
+	}
+	return redisClient.Del(ctx, key).Err()
+}
+
+// toMockGameState converts a game state into its mock store representation
+func toMockGameState(state *DistributedGameState) *mocks.GameState {
+	players := make([]mocks.GamePlayer, len(state.Players))
+	for i, p := range state.Players {
+		players[i] = mocks.GamePlayer(p)
+	}
+
+	return &mocks.GameState{
+		Mode:               state.Mode,
+		Players:            players,
+		Scores:             state.Scores,
+		Eliminated:         state.Eliminated,
+		Clicks:             state.Clicks,
+		GoldenCookies:      state.GoldenCookies,
+		MaxDeficit:         state.MaxDeficit,
+		RoomID:             state.RoomID,
+		Player1ID:          state.Player1ID,
+		Player2ID:          state.Player2ID,
+		Player1Name:        state.Player1Name,
+		Player2Name:        state.Player2Name,
+		Player1Picture:     state.Player1Picture,
+		Player2Picture:     state.Player2Picture,
+		P1Score:            state.P1Score,
+		P2Score:            state.P2Score,
+		TimeRemaining:      state.TimeRemaining,
+		GoldenCookieActive: state.GoldenCookieActive,
+		GoldenCookieX:      state.GoldenCookieX,
+		GoldenCookieY:      state.GoldenCookieY,
+		PowerUpType:        state.PowerUpType,
+		Effects:            mocks.PowerUpEffects(state.Effects.clone()),
+		SuspiciousPlayers:  maps.Clone(state.SuspiciousPlayers),
+		DisconnectedAt:     maps.Clone(state.DisconnectedAt),
+		Connections:        maps.Clone(state.Connections),
+		GameStarted:        state.GameStarted,
+		GameEnded:          state.GameEnded,
+		WinnerID:           state.WinnerID,
+		TimerPodID:         state.TimerPodID,
+		TimerHeartbeat:     state.TimerHeartbeat,
+		StartsAt:           state.StartsAt,
+		Rules:              mocks.GameRules(state.Rules),
+		RematchOf:          state.RematchOf,
+		SeriesID:           state.SeriesID,
+		TournamentID:       state.TournamentID,
+		TournamentMatch:    state.TournamentMatch,
+	}
+}
+
+// fromMockGameState converts a mock store game state back into a DistributedGameState
+func fromMockGameState(mockState *mocks.GameState) *DistributedGameState {
+	players := make([]GamePlayer, len(mockState.Players))
+	for i, p := range mockState.Players {
+		players[i] = GamePlayer(p)
+	}
+
+	state := &DistributedGameState{
+		Roster: Roster{
+			Mode:       mockState.Mode,
+			Players:    players,
+			Scores:     maps.Clone(mockState.Scores), // Scores change on every click, don't share with the store
+			Eliminated: maps.Clone(mockState.Eliminated),
+
+			Clicks:        maps.Clone(mockState.Clicks),
+			GoldenCookies: maps.Clone(mockState.GoldenCookies),
+			MaxDeficit:    maps.Clone(mockState.MaxDeficit),
+		},
+		RoomID:             mockState.RoomID,
+		Player1ID:          mockState.Player1ID,
+		Player2ID:          mockState.Player2ID,
+		Player1Name:        mockState.Player1Name,
+		Player2Name:        mockState.Player2Name,
+		Player1Picture:     mockState.Player1Picture,
+		Player2Picture:     mockState.Player2Picture,
+		P1Score:            mockState.P1Score,
+		P2Score:            mockState.P2Score,
+		TimeRemaining:      mockState.TimeRemaining,
+		GoldenCookieActive: mockState.GoldenCookieActive,
+		GoldenCookieX:      mockState.GoldenCookieX,
+		GoldenCookieY:      mockState.GoldenCookieY,
+		PowerUpType:        mockState.PowerUpType,
+		Effects:            PowerUpEffects(mockState.Effects).clone(),
+		SuspiciousPlayers:  maps.Clone(mockState.SuspiciousPlayers),
+		DisconnectedAt:     maps.Clone(mockState.DisconnectedAt),
+		Connections:        maps.Clone(mockState.Connections),
+		GameStarted:        mockState.GameStarted,
+		GameEnded:          mockState.GameEnded,
+		WinnerID:           mockState.WinnerID,
+		TimerPodID:         mockState.TimerPodID,
+		TimerHeartbeat:     mockState.TimerHeartbeat,
+		StartsAt:           mockState.StartsAt,
+		Rules:              GameRules(mockState.Rules),
+		RematchOf:          mockState.RematchOf,
+		SeriesID:           mockState.SeriesID,
+		TournamentID:       mockState.TournamentID,
+		TournamentMatch:    mockState.TournamentMatch,
+	}
+	state.upgradeLegacyState()
+	return state
 }
 
 // SaveGameState saves the game state to Redis or mock store
 func SaveGameState(state *DistributedGameState) error {
 	if useMockRedis {
-		mockState := &mocks.GameState{
-			RoomID:             state.RoomID,
-			Player1ID:          state.Player1ID,
-			Player2ID:          state.Player2ID,
-			Player1Name:        state.Player1Name,
-			Player2Name:        state.Player2Name,
-			Player1Picture:     state.Player1Picture,
-			Player2Picture:     state.Player2Picture,
-			P1Score:            state.P1Score,
-			P2Score:            state.P2Score,
-			TimeRemaining:      state.TimeRemaining,
-			GoldenCookieActive: state.GoldenCookieActive,
-			GoldenCookieX:      state.GoldenCookieX,
-			GoldenCookieY:      state.GoldenCookieY,
-			DoubleClickExpiry:  state.DoubleClickExpiry,
-			GameStarted:        state.GameStarted,
-			GameEnded:          state.GameEnded,
-			WinnerID:           state.WinnerID,
-			TimerPodID:         state.TimerPodID,
-		}
-		return mocks.GetMockGameStore().SaveGameState(mockState)
+		return mocks.GetMockGameStore().SaveGameState(toMockGameState(state))
 	}
 
 	if redisClient == nil {
@@ -406,26 +781,7 @@ func GetGameState(roomID string) (*DistributedGameState, error) {
 		if err != nil || mockState == nil {
 			return nil, err
 		}
-		return &DistributedGameState{
-			RoomID:             mockState.RoomID,
-			Player1ID:          mockState.Player1ID,
-			Player2ID:          mockState.Player2ID,
-			Player1Name:        mockState.Player1Name,
-			Player2Name:        mockState.Player2Name,
-			Player1Picture:     mockState.Player1Picture,
-			Player2Picture:     mockState.Player2Picture,
-			P1Score:            mockState.P1Score,
-			P2Score:            mockState.P2Score,
-			TimeRemaining:      mockState.TimeRemaining,
-			GoldenCookieActive: mockState.GoldenCookieActive,
-			GoldenCookieX:      mockState.GoldenCookieX,
-			GoldenCookieY:      mockState.GoldenCookieY,
-			DoubleClickExpiry:  mockState.DoubleClickExpiry,
-			GameStarted:        mockState.GameStarted,
-			GameEnded:          mockState.GameEnded,
-			WinnerID:           mockState.WinnerID,
-			TimerPodID:         mockState.TimerPodID,
-		}, nil
+		return fromMockGameState(mockState), nil
 	}
 
 	if redisClient == nil {
@@ -442,6 +798,7 @@ func GetGameState(roomID string) (*DistributedGameState, error) {
 	if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
 		return nil, err
 	}
+	state.upgradeLegacyState()
 
 	return &state, nil
 }
@@ -457,11 +814,145 @@ func DeleteGameState(roomID string) error {
 	}
 
 	key := gameStateKeyPrefix + roomID
-	return redisClient.Del(ctx, key).Err()
+	if err := redisClient.Del(ctx, key).Err(); err != nil {
+		return err
+	}
+	return redisClient.SRem(ctx, activeGamesKey, roomID).Err()
+}
+
+// UpdateGameState atomically applies fn to the stored game state and saves the result.
+// If fn returns an error the state is left untouched and the error is returned.
+func UpdateGameState(roomID string, fn func(state *DistributedGameState) error) (*DistributedGameState, error) {
+	if useMockRedis {
+		var updated *DistributedGameState
+		mockState, err := mocks.GetMockGameStore().UpdateGameState(roomID, func(stored *mocks.GameState) error {
+			state := fromMockGameState(stored)
+			if err := fn(state); err != nil {
+				return err
+			}
+			*stored = *toMockGameState(state)
+			updated = state
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if mockState == nil {
+			return nil, errGameNotFound
+		}
+		return updated, nil
+	}
+
+	if redisClient == nil {
+		return nil, fmt.Errorf("redis not initialized")
+	}
+
+	key := gameStateKeyPrefix + roomID
+	var updatedState *DistributedGameState
+
+	txf := func(tx *redis.Tx) error {
+		stateJSON, err := tx.Get(ctx, key).Result()
+		if err == redis.Nil {
+			return errGameNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var state DistributedGameState
+		if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+			return err
+		}
+		state.upgradeLegacyState()
+
+		if err := fn(&state); err != nil {
+			return err
+		}
+
+		newStateJSON, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, string(newStateJSON), gameStateTTL)
+			return nil
+		})
+
+		updatedState = &state
+		return err
+	}
+
+	// Retry if another pod modified the state between WATCH and EXEC
+	for i := 0; i < maxTxRetries; i++ {
+		err := redisClient.Watch(ctx, txf, key)
+		if err != redis.TxFailedErr {
+			return updatedState, err
+		}
+	}
+
+	return nil, redis.TxFailedErr
+}
+
+// ListActiveGames returns the room IDs of all games that have not been cleaned up yet
+func ListActiveGames() ([]string, error) {
+	if useMockRedis {
+		return mocks.GetMockGameStore().ListGameIDs(), nil
+	}
+
+	if redisClient == nil {
+		return nil, fmt.Errorf("redis not initialized")
+	}
+
+	return redisClient.SMembers(ctx, activeGamesKey).Result()
+}
+
+// RemoveActiveGame drops a room from the active games set (used when its state has expired)
+func RemoveActiveGame(roomID string) error {
+	if useMockRedis {
+		return nil
+	}
+
+	if redisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+
+	return redisClient.SRem(ctx, activeGamesKey, roomID).Err()
+}
+
+// ClaimTimerLease takes over the timer of a running game whose timer pod stopped
+// renewing its heartbeat. Returns true if this pod is now the timer pod.
+func ClaimTimerLease(roomID string) (bool, error) {
+	_, err := UpdateGameState(roomID, func(state *DistributedGameState) error {
+		if state.GameEnded {
+			return errTimerLeaseHeld
+		}
+
+		heartbeat := time.UnixMilli(state.TimerHeartbeat)
+		if time.Since(heartbeat) < timerLeaseTimeout {
+			return errTimerLeaseHeld
+		}
+
+		log.Printf("Timer lease for room %s expired (pod %s, last heartbeat %s ago), taking over",
+			roomID, state.TimerPodID, time.Since(heartbeat).Round(time.Second))
+		state.TimerPodID = podID
+		state.TimerHeartbeat = time.Now().UnixMilli()
+		return nil
+	})
+
+	if errors.Is(err, errTimerLeaseHeld) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // PublishGameEvent publishes a game event to all pods
 func PublishGameEvent(event GameEvent) error {
+	recordReplayEvent(event)
+
 	if useMockRedis {
 		return mocks.GetMockGameStore().PublishGameEvent(mocks.GameEvent{
 			RoomID:    event.RoomID,
@@ -521,150 +1012,410 @@ func SubscribeToGameEvents(handler func(GameEvent)) {
 
 // AtomicScoreIncrement atomically increments a player's score
 func AtomicScoreIncrement(roomID, playerID string, points int) (*DistributedGameState, error) {
+	return UpdateGameState(roomID, func(state *DistributedGameState) error {
+		state.AddPoints(playerID, points)
+		state.CountClick(playerID)
+		state.syncLegacyFields()
+		return nil
+	})
+}
+
+// ==================== KEY-VALUE HELPERS ====================
+// Thin wrappers over plain Redis keys and sets with an in-memory mock fallback,
+// used by features that just need to store small JSON documents.
+
+// kvGet returns the value at key and whether it exists
+func kvGet(key string) (string, bool, error) {
 	if useMockRedis {
-		mockState, err := mocks.GetMockGameStore().GetGameState(roomID)
-		if err != nil || mockState == nil {
-			return nil, fmt.Errorf("game not found: %s", roomID)
-		}
+		value, ok := mocks.GetMockKV().Get(key)
+		return value, ok, nil
+	}
 
-		if playerID == mockState.Player1ID {
-			mockState.P1Score += points
-		} else if playerID == mockState.Player2ID {
-			mockState.P2Score += points
-		}
+	if redisClient == nil {
+		return "", false, fmt.Errorf("redis not initialized")
+	}
 
-		mocks.GetMockGameStore().SaveGameState(mockState)
+	value, err := redisClient.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
 
-		return &DistributedGameState{
-			RoomID:             mockState.RoomID,
-			Player1ID:          mockState.Player1ID,
-			Player2ID:          mockState.Player2ID,
-			Player1Name:        mockState.Player1Name,
-			Player2Name:        mockState.Player2Name,
-			Player1Picture:     mockState.Player1Picture,
-			Player2Picture:     mockState.Player2Picture,
-			P1Score:            mockState.P1Score,
-			P2Score:            mockState.P2Score,
-			TimeRemaining:      mockState.TimeRemaining,
-			GoldenCookieActive: mockState.GoldenCookieActive,
-			GoldenCookieX:      mockState.GoldenCookieX,
-			GoldenCookieY:      mockState.GoldenCookieY,
-			DoubleClickExpiry:  mockState.DoubleClickExpiry,
-			GameStarted:        mockState.GameStarted,
-			GameEnded:          mockState.GameEnded,
-			WinnerID:           mockState.WinnerID,
-			TimerPodID:         mockState.TimerPodID,
-		}, nil
+// kvSet stores a value with a TTL (0 = no expiry)
+func kvSet(key, value string, ttl time.Duration) error {
+	if useMockRedis {
+		mocks.GetMockKV().Set(key, value, ttl)
+		return nil
 	}
 
 	if redisClient == nil {
-		return nil, fmt.Errorf("redis not initialized")
+		return fmt.Errorf("redis not initialized")
 	}
 
-	// Use Redis transaction for atomic update
-	key := gameStateKeyPrefix + roomID
+	return redisClient.Set(ctx, key, value, ttl).Err()
+}
 
-	var updatedState *DistributedGameState
+// kvSetNX stores a value only if the key does not exist yet
+func kvSetNX(key, value string, ttl time.Duration) (bool, error) {
+	if useMockRedis {
+		return mocks.GetMockKV().SetNX(key, value, ttl), nil
+	}
 
-	err := redisClient.Watch(ctx, func(tx *redis.Tx) error {
-		stateJSON, err := tx.Get(ctx, key).Result()
-		if err != nil {
-			return err
-		}
+	if redisClient == nil {
+		return false, fmt.Errorf("redis not initialized")
+	}
 
-		var state DistributedGameState
-		if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
-			return err
-		}
+	return redisClient.SetNX(ctx, key, value, ttl).Result()
+}
+
+// kvDel removes keys
+func kvDel(keys ...string) error {
+	if useMockRedis {
+		mocks.GetMockKV().Del(keys...)
+		return nil
+	}
+
+	if redisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+
+	return redisClient.Del(ctx, keys...).Err()
+}
+
+// kvRPush appends values to a list and refreshes its TTL
+func kvRPush(key string, ttl time.Duration, values ...string) error {
+	if useMockRedis {
+		mocks.GetMockKV().RPush(key, ttl, values...)
+		return nil
+	}
+
+	if redisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	pipe := redisClient.TxPipeline()
+	pipe.RPush(ctx, key, args...)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// kvLRange returns all values of a list
+func kvLRange(key string) ([]string, error) {
+	if useMockRedis {
+		return mocks.GetMockKV().LRange(key), nil
+	}
+
+	if redisClient == nil {
+		return nil, fmt.Errorf("redis not initialized")
+	}
 
-		// Update score
-		if playerID == state.Player1ID {
-			state.P1Score += points
-		} else if playerID == state.Player2ID {
-			state.P2Score += points
+	return redisClient.LRange(ctx, key, 0, -1).Result()
+}
+
+// kvMGet returns the values of several keys, "" for keys that don't exist
+func kvMGet(keys ...string) ([]string, error) {
+	if useMockRedis {
+		values := make([]string, len(keys))
+		for i, key := range keys {
+			values[i], _ = mocks.GetMockKV().Get(key)
 		}
+		return values, nil
+	}
 
-		// Save back
-		newStateJSON, err := json.Marshal(state)
-		if err != nil {
-			return err
+	if redisClient == nil {
+		return nil, fmt.Errorf("redis not initialized")
+	}
+
+	if len(keys) == 0 {
+		return []string{}, nil
+	}
+	raw, err := redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, len(raw))
+	for i, v := range raw {
+		values[i], _ = v.(string)
+	}
+	return values, nil
+}
+
+// kvMSet stores several values without expiry in one round trip
+func kvMSet(values map[string]string) error {
+	if useMockRedis {
+		for key, value := range values {
+			mocks.GetMockKV().Set(key, value, 0)
 		}
+		return nil
+	}
 
-		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-			pipe.Set(ctx, key, string(newStateJSON), gameStateTTL)
-			return nil
-		})
+	if redisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
 
-		updatedState = &state
-		return err
-	}, key)
+	if len(values) == 0 {
+		return nil
+	}
+	return redisClient.MSet(ctx, values).Err()
+}
 
-	return updatedState, err
+// ZMember is a member of a sorted set with its score
+type ZMember struct {
+	Member string
+	Score  float64
 }
 
-// AtomicClaimGoldenCookie atomically claims the golden cookie
-func AtomicClaimGoldenCookie(roomID, playerID string) (bool, error) {
+// kvZMScore returns the scores of sorted set members, 0 for missing members
+func kvZMScore(key string, members ...string) ([]float64, error) {
 	if useMockRedis {
-		mockState, err := mocks.GetMockGameStore().GetGameState(roomID)
-		if err != nil || mockState == nil {
-			return false, fmt.Errorf("game not found: %s", roomID)
+		scores := make([]float64, len(members))
+		for i, member := range members {
+			scores[i], _ = mocks.GetMockKV().ZScore(key, member)
 		}
+		return scores, nil
+	}
 
-		if !mockState.GoldenCookieActive {
-			return false, nil
-		}
+	if redisClient == nil {
+		return nil, fmt.Errorf("redis not initialized")
+	}
+
+	if len(members) == 0 {
+		return []float64{}, nil
+	}
+	return redisClient.ZMScore(ctx, key, members...).Result()
+}
+
+// kvZIncrBy adds delta to the score of a sorted set member (starting at 0). A positive
+// ttl refreshes the expiry of the set, 0 leaves it untouched.
+func kvZIncrBy(key, member string, delta float64, ttl time.Duration) error {
+	if useMockRedis {
+		mocks.GetMockKV().ZIncrBy(key, member, delta, ttl)
+		return nil
+	}
 
-		mockState.GoldenCookieActive = false
-		if mockState.DoubleClickExpiry == nil {
-			mockState.DoubleClickExpiry = make(map[string]int64)
+	if redisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+
+	if ttl <= 0 {
+		return redisClient.ZIncrBy(ctx, key, delta, member).Err()
+	}
+	pipe := redisClient.TxPipeline()
+	pipe.ZIncrBy(ctx, key, delta, member)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// kvZAdd sets the score of sorted set members
+func kvZAdd(key string, members ...ZMember) error {
+	if useMockRedis {
+		for _, z := range members {
+			mocks.GetMockKV().ZAdd(key, mocks.ZMember(z))
 		}
-		mockState.DoubleClickExpiry[playerID] = time.Now().Add(3 * time.Second).Unix()
-		mocks.GetMockGameStore().SaveGameState(mockState)
-		return true, nil
+		return nil
 	}
 
 	if redisClient == nil {
-		return false, fmt.Errorf("redis not initialized")
+		return fmt.Errorf("redis not initialized")
 	}
 
-	key := gameStateKeyPrefix + roomID
-	claimed := false
+	zs := make([]redis.Z, len(members))
+	for i, z := range members {
+		zs[i] = redis.Z{Score: z.Score, Member: z.Member}
+	}
+	return redisClient.ZAdd(ctx, key, zs...).Err()
+}
 
-	err := redisClient.Watch(ctx, func(tx *redis.Tx) error {
-		stateJSON, err := tx.Get(ctx, key).Result()
-		if err != nil {
-			return err
+// kvZReplace atomically replaces a sorted set with the given members and sets its TTL
+// (0 = no expiry). The new set is built under a temporary key and renamed over the old one.
+func kvZReplace(key string, members []ZMember, ttl time.Duration) error {
+	if useMockRedis {
+		converted := make([]mocks.ZMember, len(members))
+		for i, z := range members {
+			converted[i] = mocks.ZMember(z)
 		}
+		mocks.GetMockKV().ZReplace(key, ttl, converted...)
+		return nil
+	}
 
-		var state DistributedGameState
-		if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
-			return err
+	if redisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+
+	if len(members) == 0 {
+		return redisClient.Del(ctx, key).Err()
+	}
+
+	tmpKey := key + ":rebuild:" + podID
+	pipe := redisClient.Pipeline()
+	pipe.Del(ctx, tmpKey)
+	for start := 0; start < len(members); start += 1000 {
+		end := min(start+1000, len(members))
+		zs := make([]redis.Z, 0, end-start)
+		for _, z := range members[start:end] {
+			zs = append(zs, redis.Z{Score: z.Score, Member: z.Member})
 		}
+		pipe.ZAdd(ctx, tmpKey, zs...)
+	}
+	if ttl > 0 {
+		pipe.Expire(ctx, tmpKey, ttl)
+	}
+	pipe.Rename(ctx, tmpKey, key)
+	_, err := pipe.Exec(ctx)
+	return err
+}
 
-		// Check if golden cookie is still active
-		if !state.GoldenCookieActive {
-			claimed = false
-			return nil
+// kvZRevRange returns the members from position start to stop (inclusive), highest
+// score first. A stop of -1 returns everything from start on.
+func kvZRevRange(key string, start, stop int64) ([]ZMember, error) {
+	if useMockRedis {
+		mockMembers := mocks.GetMockKV().ZRevRange(key, int(start), int(stop))
+		members := make([]ZMember, len(mockMembers))
+		for i, z := range mockMembers {
+			members[i] = ZMember(z)
 		}
+		return members, nil
+	}
+
+	if redisClient == nil {
+		return nil, fmt.Errorf("redis not initialized")
+	}
 
-		// Claim it!
-		state.GoldenCookieActive = false
-		state.DoubleClickExpiry[playerID] = time.Now().Add(3 * time.Second).Unix()
-		claimed = true
+	zs, err := redisClient.ZRevRangeWithScores(ctx, key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ZMember, len(zs))
+	for i, z := range zs {
+		member, _ := z.Member.(string)
+		members[i] = ZMember{Member: member, Score: z.Score}
+	}
+	return members, nil
+}
 
-		// Save back
-		newStateJSON, err := json.Marshal(state)
+// kvZRevRank returns the position of a member (highest score first) and whether it exists
+func kvZRevRank(key, member string) (int64, bool, error) {
+	if useMockRedis {
+		rank, ok := mocks.GetMockKV().ZRevRank(key, member)
+		return int64(rank), ok, nil
+	}
+
+	if redisClient == nil {
+		return 0, false, fmt.Errorf("redis not initialized")
+	}
+
+	rank, err := redisClient.ZRevRank(ctx, key, member).Result()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return rank, true, nil
+}
+
+// kvZCard returns the number of members of a sorted set
+func kvZCard(key string) (int64, error) {
+	if useMockRedis {
+		return int64(mocks.GetMockKV().ZCard(key)), nil
+	}
+
+	if redisClient == nil {
+		return 0, fmt.Errorf("redis not initialized")
+	}
+
+	return redisClient.ZCard(ctx, key).Result()
+}
+
+// kvPublish sends a message to all pods subscribed to channel
+func kvPublish(channel, message string) error {
+	if useMockRedis {
+		mocks.GetMockKV().Publish(channel, message)
+		return nil
+	}
+
+	if redisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+
+	return redisClient.Publish(ctx, channel, message).Err()
+}
+
+// kvSubscribe calls handler for every message published to channel. It blocks
+// for the lifetime of the subscription, except in mock mode.
+func kvSubscribe(channel string, handler func(message string)) {
+	if useMockRedis {
+		ch := mocks.GetMockKV().Subscribe(channel)
+		go func() {
+			for msg := range ch {
+				handler(msg)
+			}
+		}()
+		return
+	}
+
+	if redisClient == nil {
+		log.Printf("Redis not available, skipping subscription to %s", channel)
+		return
+	}
+
+	pubsub := redisClient.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		handler(msg.Payload)
+	}
+}
+
+// kvUpdate atomically replaces the value at key with the result of fn.
+// Returning an empty string deletes the key; returning an error aborts the update.
+func kvUpdate(key string, ttl time.Duration, fn func(current string, found bool) (string, error)) error {
+	if useMockRedis {
+		return mocks.GetMockKV().Update(key, ttl, fn)
+	}
+
+	if redisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+
+	txf := func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, key).Result()
+		found := err == nil
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		next, err := fn(current, found)
 		if err != nil {
 			return err
 		}
 
 		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-			pipe.Set(ctx, key, string(newStateJSON), gameStateTTL)
+			if next == "" {
+				pipe.Del(ctx, key)
+			} else {
+				pipe.Set(ctx, key, next, ttl)
+			}
 			return nil
 		})
-
 		return err
-	}, key)
+	}
 
-	return claimed, err
+	for i := 0; i < maxTxRetries; i++ {
+		err := redisClient.Watch(ctx, txf, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return redis.TxFailedErr
 }
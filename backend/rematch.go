@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"time"
+)
+
+// RematchVote collects the votes for a rematch of a finished game. Once every
+// player of the game has accepted, a new game with the same players and rules is
+// started. Distributed games keep the vote in Redis, in-memory rooms on the room.
+type RematchVote struct {
+	RoomID   string       `json:"roomId"` // Game the rematch is for
+	Mode     string       `json:"mode"`
+	Players  []GamePlayer `json:"players"` // Seat order
+	Rules    GameRules    `json:"rules"`
+	Accepted []string     `json:"accepted"` // UserIDs who want the rematch
+}
+
+const (
+	rematchKeyPrefix       = "overcookied:rematch:"
+	rematchPlayerKeyPrefix = "overcookied:rematch:player:" // UserID -> RoomID of their open vote
+	rematchWindow          = 15 * time.Second
+	rematchTTL             = rematchWindow + 10*time.Second // Outlives the window so the expiry can still be announced
+)
+
+var errRematchNotFound = errors.New("no rematch vote open")
+
+// newRematchVote opens the vote for a rematch of a finished game
+func newRematchVote(roomID string, r *Roster, rules GameRules) *RematchVote {
+	return &RematchVote{RoomID: roomID, Mode: r.Mode, Players: slices.Clone(r.Players), Rules: rules}
+}
+
+// votes reports whether a player may vote on the rematch
+func (v *RematchVote) votes(userID string) bool {
+	return slices.ContainsFunc(v.Players, func(p GamePlayer) bool { return p.UserID == userID })
+}
+
+// accept records the vote of a player and reports whether everyone has accepted
+func (v *RematchVote) accept(userID string) (bool, error) {
+	if !v.votes(userID) {
+		return false, errRematchNotFound
+	}
+	if !slices.Contains(v.Accepted, userID) {
+		v.Accepted = append(v.Accepted, userID)
+	}
+	return len(v.Accepted) == len(v.Players), nil
+}
+
+// OpenRematch stores the rematch vote of a finished distributed game
+func OpenRematch(vote *RematchVote) error {
+	voteJSON, err := json.Marshal(vote)
+	if err != nil {
+		return err
+	}
+	if err := kvSet(rematchKeyPrefix+vote.RoomID, string(voteJSON), rematchTTL); err != nil {
+		return err
+	}
+	for _, p := range vote.Players {
+		if err := kvSet(rematchPlayerKeyPrefix+p.UserID, vote.RoomID, rematchTTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VoteRematch records a player's answer to the open rematch vote of their last
+// game. The vote is closed once it is declined or everyone has accepted.
+func VoteRematch(userID string, accept bool) (vote *RematchVote, complete bool, err error) {
+	roomID, found, err := kvGet(rematchPlayerKeyPrefix + userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		return nil, false, errRematchNotFound
+	}
+
+	err = kvUpdate(rematchKeyPrefix+roomID, rematchTTL, func(current string, found bool) (string, error) {
+		if !found {
+			return "", errRematchNotFound
+		}
+		vote = &RematchVote{}
+		if err := json.Unmarshal([]byte(current), vote); err != nil {
+			return "", err
+		}
+		if !accept {
+			return "", nil // Delete
+		}
+		if complete, err = vote.accept(userID); err != nil || complete {
+			return "", err
+		}
+		updated, err := json.Marshal(vote)
+		return string(updated), err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if !accept || complete {
+		closeRematch(vote)
+	}
+	return vote, complete, nil
+}
+
+// TakeRematch closes the open rematch vote of a game and returns it
+func TakeRematch(roomID string) (*RematchVote, error) {
+	var vote RematchVote
+	err := kvUpdate(rematchKeyPrefix+roomID, rematchTTL, func(current string, found bool) (string, error) {
+		if !found {
+			return "", errRematchNotFound
+		}
+		if err := json.Unmarshal([]byte(current), &vote); err != nil {
+			return "", err
+		}
+		return "", nil // Delete
+	})
+	if err != nil {
+		return nil, err
+	}
+	closeRematch(&vote)
+	return &vote, nil
+}
+
+// closeRematch removes the player mappings of a closed vote, unless a player has
+// moved on to the vote of a newer game
+func closeRematch(vote *RematchVote) {
+	for _, p := range vote.Players {
+		kvUpdate(rematchPlayerKeyPrefix+p.UserID, rematchTTL, func(current string, found bool) (string, error) {
+			if found && current != vote.RoomID {
+				return current, nil
+			}
+			return "", nil
+		})
+	}
+}
+
+// voteRematch records a player's answer to the rematch vote of an ended in-memory room
+func (room *GameRoom) voteRematch(userID string, accept bool) (*RematchVote, bool, error) {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	vote := room.rematch
+	if vote == nil || !vote.votes(userID) {
+		return nil, false, errRematchNotFound
+	}
+	complete := false
+	if accept {
+		complete, _ = vote.accept(userID)
+	}
+	if !accept || complete {
+		room.rematch = nil
+	}
+	snapshot := *vote
+	snapshot.Accepted = slices.Clone(vote.Accepted)
+	return &snapshot, complete, nil
+}
+
+// takeRematch closes the rematch vote of an in-memory room and returns it
+func (room *GameRoom) takeRematch() *RematchVote {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+	vote := room.rematch
+	room.rematch = nil
+	return vote
+}
+
+// notifyRematch sends a message to every player of a rematch vote
+func (gm *GameManager) notifyRematch(vote *RematchVote, msg GameMessage) {
+	for _, p := range vote.Players {
+		gm.sendToUser(p.UserID, msg)
+	}
+}
+
+// expireRematch closes a rematch vote nobody completed in time
+func (gm *GameManager) expireRematch(vote *RematchVote) {
+	if vote == nil {
+		return
+	}
+	log.Printf("Rematch vote for room %s expired", vote.RoomID)
+	gm.notifyRematch(vote, GameMessage{Type: MsgTypeRematchExpired, Payload: map[string]string{"roomId": vote.RoomID}})
+}
+
+// handleRematchVote records the client's answer to the rematch vote of the game
+// they just finished and starts the rematch once everyone has accepted
+func (gm *GameManager) handleRematchVote(client *Client, accept bool) {
+	gm.mutex.Lock()
+	room := gm.clientRooms[client]
+	gm.mutex.Unlock()
+
+	var vote *RematchVote
+	var complete bool
+	var err error
+	switch {
+	case room != nil && (room.Clients == nil || !room.Over()):
+		sendError(client, "already_in_game", "You are already in a game")
+		return
+	case room != nil:
+		vote, complete, err = room.voteRematch(client.userID, accept)
+	case IsRedisAvailable():
+		vote, complete, err = VoteRematch(client.userID, accept)
+	default:
+		err = errRematchNotFound
+	}
+	if errors.Is(err, errRematchNotFound) {
+		sendError(client, "rematch_unavailable", "There is no rematch to vote on")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to record rematch vote of %s: %v", client.userID, err)
+		sendError(client, "rematch_error", "Could not record your vote")
+		return
+	}
+
+	if !accept {
+		log.Printf("Rematch of room %s declined by %s", vote.RoomID, client.userID)
+		gm.notifyRematch(vote, GameMessage{
+			Type:    MsgTypeRematchDeclined,
+			Payload: map[string]string{"roomId": vote.RoomID, "userId": client.userID},
+		})
+		return
+	}
+	gm.notifyRematch(vote, GameMessage{
+		Type: MsgTypeRematchVote,
+		Payload: map[string]interface{}{
+			"roomId":   vote.RoomID,
+			"userId":   client.userID,
+			"accepted": vote.Accepted,
+			"needed":   len(vote.Players),
+		},
+	})
+	if !complete {
+		return
+	}
+
+	if busy := gm.claimRematchPlayers(vote); busy != "" {
+		log.Printf("Rematch of room %s cancelled, %s is busy", vote.RoomID, busy)
+		gm.notifyRematch(vote, GameMessage{
+			Type:    MsgTypeRematchDeclined,
+			Payload: map[string]string{"roomId": vote.RoomID, "userId": busy},
+		})
+		return
+	}
+
+	if room != nil {
+		gm.startLocalRematch(vote)
+	} else {
+		gm.startDistributedRematch(vote)
+	}
+}
+
+// claimRematchPlayers takes the players of a complete vote out of queues, lobbies
+// and spectating so the rematch cannot overlap with another game. Returns the
+// first player who is offline or busy, "" if everyone is free.
+func (gm *GameManager) claimRematchPlayers(vote *RematchVote) string {
+	userIDs := make([]string, len(vote.Players))
+	for i, p := range vote.Players {
+		userIDs[i] = p.UserID
+	}
+
+	presence := gm.presenceOf(userIDs)
+	for _, userID := range userIDs {
+		if presence[userID] != PresenceOnline {
+			return userID
+		}
+	}
+
+	if IsRedisAvailable() {
+		for _, userID := range userIDs {
+			RemoveFromQueue(userID)
+			if _, err := CancelLobby(userID); errors.Is(err, errLobbyFull) {
+				return userID // Their lobby game is starting
+			}
+		}
+	}
+
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+	for _, userID := range userIDs {
+		client, ok := gm.clientsByID[userID]
+		if !ok {
+			continue // Spectating stops on their pod with the match notification
+		}
+		delete(gm.spectators, client)
+		if gm.waiting == client {
+			gm.waiting = nil
+		}
+		gm.ffaWaiting = removeClient(gm.ffaWaiting, client)
+		gm.teamWaiting = removeClient(gm.teamWaiting, client)
+		delete(gm.queuedAt, userID)
+	}
+	return ""
+}
+
+// startDistributedRematch starts the rematch of a distributed game through the
+// regular match flow
+func (gm *GameManager) startDistributedRematch(vote *RematchVote) {
+	entries := make([]QueueEntry, len(vote.Players))
+	userIDs := make([]string, len(vote.Players))
+	for i, p := range vote.Players {
+		entries[i] = QueueEntry{UserID: p.UserID, Name: p.Name, Picture: p.Picture, Mode: vote.Mode, JoinedAt: time.Now().Unix()}
+		userIDs[i] = p.UserID
+	}
+
+	roomID := newRoomID(vote.Mode, userIDs)
+	linkRematch := func(state *DistributedGameState) { state.RematchOf = vote.RoomID }
+	if err := CreateDistributedGame(roomID, vote.Mode, entries, vote.Rules, linkRematch); err != nil {
+		log.Printf("Failed to create rematch of room %s: %v", vote.RoomID, err)
+		gm.notifyRematch(vote, GameMessage{Type: MsgTypeRematchExpired, Payload: map[string]string{"roomId": vote.RoomID}})
+		return
+	}
+
+	log.Printf("Rematch of room %s starting in room %s", vote.RoomID, roomID)
+	if err := PublishMatchNotification(newMatchNotification(roomID, vote.Mode, userIDs)); err != nil {
+		log.Printf("Failed to publish match notification for rematch %s: %v", roomID, err)
+	}
+}
+
+// startLocalRematch starts the rematch of an in-memory room, provided all its
+// players are still connected
+func (gm *GameManager) startLocalRematch(vote *RematchVote) {
+	gm.mutex.Lock()
+	clients := make([]*Client, len(vote.Players))
+	for i, p := range vote.Players {
+		client, ok := gm.clientsByID[p.UserID]
+		if !ok {
+			gm.mutex.Unlock()
+			gm.notifyRematch(vote, GameMessage{
+				Type:    MsgTypeRematchDeclined,
+				Payload: map[string]string{"roomId": vote.RoomID, "userId": p.UserID},
+			})
+			return
+		}
+		clients[i] = client
+	}
+	log.Printf("Rematch of room %s starting", vote.RoomID)
+	gm.startRoom(vote.Mode, vote.Rules, vote.RoomID, clients...)
+	gm.mutex.Unlock()
+}
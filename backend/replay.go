@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"maps"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+)
+
+// Replays are recorded for distributed games only: every GameEvent published for a
+// room is buffered by the publishing pod and appended to a Redis list in batches
+// while the game runs. Once the game ends, by time, quit or forfeit, the list is
+// persisted to DynamoDB split into chunks.
+
+const (
+	replayKeyPrefix     = "overcookied:replay:"
+	replayFlushInterval = 500 * time.Millisecond
+	replayChunkBytes    = 256 << 10 // Leaves room below the 400KB DynamoDB item limit
+)
+
+// Events recorded by this pod that are not in Redis yet, per room
+var (
+	replayBufferMutex sync.Mutex
+	replayBuffer      = make(map[string][]string)
+)
+
+// replaySnapshotKeys are the score snapshots a CLICK event is published with. The
+// replay only keeps the click's points; handleReplay rebuilds the scores.
+var replaySnapshotKeys = []string{"p1Score", "p2Score", "scores", "teamScores"}
+
+// recordedEvent is a GameEvent as buffered in Redis, stamped with its publish time
+type recordedEvent struct {
+	At    int64     `json:"at"` // Unix ms
+	Event GameEvent `json:"event"`
+}
+
+// ReplayEvent is one entry of a persisted replay timeline
+type ReplayEvent struct {
+	T      int64                  `json:"t"`                // ms relative to the game start, negative during the countdown
+	Type   string                 `json:"type"`             // Event type (CLICK, GOLDEN_SPAWN, STATE_UPDATE, ...)
+	Player string                 `json:"player,omitempty"` // "p1".."pN"
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
+
+// ReplayPlayer is a seat of a recorded game
+type ReplayPlayer struct {
+	Role    string `json:"role"` // "p1".."pN"
+	Name    string `json:"name"`
+	Picture string `json:"picture"`
+}
+
+// Replay is the API representation of a recorded game
+type Replay struct {
+	GameID    string         `json:"gameId"`
+	Players   []ReplayPlayer `json:"players"`
+	P1Name    string         `json:"p1Name"`
+	P2Name    string         `json:"p2Name"`
+	P1Picture string         `json:"p1Picture"`
+	P2Picture string         `json:"p2Picture"`
+	StartedAt int64          `json:"startedAt"`
+	Events    []ReplayEvent  `json:"events"`
+}
+
+// recordReplayEvent buffers an event for the replay of its room
+func recordReplayEvent(event GameEvent) {
+	if event.RoomID == "" {
+		return
+	}
+
+	if event.EventType == EventClick {
+		// Copy, the event is still to be published with its scores
+		data := maps.Clone(event.Data)
+		for _, key := range replaySnapshotKeys {
+			delete(data, key)
+		}
+		event.Data = data
+	}
+
+	recorded, err := json.Marshal(recordedEvent{At: time.Now().UnixMilli(), Event: event})
+	if err != nil {
+		return
+	}
+
+	replayBufferMutex.Lock()
+	replayBuffer[event.RoomID] = append(replayBuffer[event.RoomID], string(recorded))
+	replayBufferMutex.Unlock()
+}
+
+// RunReplayFlushLoop periodically appends the buffered replay events to Redis
+func RunReplayFlushLoop() {
+	ticker := time.NewTicker(replayFlushInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		flushReplayEvents()
+	}
+}
+
+// flushReplayEvents appends this pod's buffered replay events to Redis, one push
+// per room
+func flushReplayEvents() {
+	replayBufferMutex.Lock()
+	pending := replayBuffer
+	replayBuffer = make(map[string][]string)
+	replayBufferMutex.Unlock()
+
+	for roomID, values := range pending {
+		if err := kvRPush(replayKeyPrefix+roomID, gameStateTTL, values...); err != nil {
+			log.Printf("Failed to record %d replay events for room %s: %v", len(values), roomID, err)
+		}
+	}
+}
+
+// replayRole maps a user ID to its player slot so replays don't expose user IDs
+func replayRole(state *DistributedGameState, userID string) string {
+	if role := state.Role(userID); role != "" {
+		return role
+	}
+	return userID // e.g. "draw"
+}
+
+// compactReplayEvent converts a buffered event into its stored form
+func compactReplayEvent(state *DistributedGameState, rec recordedEvent) ReplayEvent {
+	data := make(map[string]interface{}, len(rec.Event.Data))
+	for key, value := range rec.Event.Data {
+		switch key {
+		case "p1Name", "p2Name", "p1Picture", "p2Picture", "results":
+			// Stored once in the replay header
+		case "winner", "claimedBy", "target":
+			id, _ := value.(string)
+			data[key] = replayRole(state, id)
+		case "affected":
+			var roles []string
+			for _, id := range toStrings(value) {
+				roles = append(roles, replayRole(state, id))
+			}
+			data[key] = roles
+		case "scores":
+			scores := make(map[string]int)
+			for id, score := range toScores(value) {
+				scores[replayRole(state, id)] = score
+			}
+			data[key] = scores
+		default:
+			data[key] = value
+		}
+	}
+
+	return ReplayEvent{
+		T:      rec.At - state.StartsAt,
+		Type:   rec.Event.EventType,
+		Player: replayRole(state, rec.Event.PlayerID),
+		Data:   data,
+	}
+}
+
+// saveReplay persists the buffered timeline of a finished game. It waits for the
+// pods to flush the last events of the game first.
+func saveReplay(state *DistributedGameState) {
+	flushReplayEvents()
+	time.Sleep(2 * replayFlushInterval)
+
+	key := replayKeyPrefix + state.RoomID
+	raw, err := kvLRange(key)
+	if err != nil {
+		log.Printf("Failed to load replay events for room %s: %v", state.RoomID, err)
+		return
+	}
+
+	recorded := make([]recordedEvent, 0, len(raw))
+	for _, item := range raw {
+		var rec recordedEvent
+		if err := json.Unmarshal([]byte(item), &rec); err != nil {
+			continue
+		}
+		recorded = append(recorded, rec)
+	}
+	// Batches of different pods arrive out of order
+	sort.SliceStable(recorded, func(i, j int) bool { return recorded[i].At < recorded[j].At })
+
+	chunks, err := replayChunks(state, recorded)
+	if err != nil {
+		log.Printf("Failed to encode replay for room %s: %v", state.RoomID, err)
+		return
+	}
+
+	players := make([]ReplayPlayer, len(state.Players))
+	for i, p := range state.Players {
+		players[i] = ReplayPlayer{Role: state.Role(p.UserID), Name: p.Name, Picture: p.Picture}
+	}
+	playersJSON, err := json.Marshal(players)
+	if err != nil {
+		log.Printf("Failed to encode replay players for room %s: %v", state.RoomID, err)
+		return
+	}
+
+	err = db.SaveReplayWithMock(db.CookieReplay{
+		GameID:    state.RoomID,
+		Player1ID: state.Player1ID,
+		Player2ID: state.Player2ID,
+		P1Name:    state.Player1Name,
+		P2Name:    state.Player2Name,
+		P1Picture: state.Player1Picture,
+		P2Picture: state.Player2Picture,
+		StartedAt: state.StartsAt,
+		Players:   string(playersJSON),
+	}, chunks)
+	if err != nil {
+		log.Printf("Failed to save replay for room %s: %v", state.RoomID, err)
+		return
+	}
+
+	kvDel(key)
+}
+
+// replayChunks splits the compacted timeline into JSON arrays of at most
+// replayChunkBytes each
+func replayChunks(state *DistributedGameState, recorded []recordedEvent) ([]db.CookieReplayChunk, error) {
+	var chunks []db.CookieReplayChunk
+	var parts []string
+	size := 0
+	flush := func() {
+		chunks = append(chunks, db.CookieReplayChunk{
+			GameID: state.RoomID,
+			Chunk:  len(chunks) + 1,
+			Events: "[" + strings.Join(parts, ",") + "]",
+		})
+		parts, size = nil, 0
+	}
+
+	for _, rec := range recorded {
+		encoded, err := json.Marshal(compactReplayEvent(state, rec))
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) > 0 && size+len(encoded)+1 > replayChunkBytes {
+			flush()
+		}
+		parts = append(parts, string(encoded))
+		size += len(encoded) + 1
+	}
+	if len(parts) > 0 {
+		flush()
+	}
+	return chunks, nil
+}
+
+// rebuildReplayScores adds the scores after every CLICK event, which only record
+// their points. Events that carry a score snapshot reset the running totals.
+func rebuildReplayScores(events []ReplayEvent) {
+	scores := make(map[string]int)
+	for i := range events {
+		ev := &events[i]
+		if snapshot, ok := ev.Data["scores"]; ok {
+			if scores = toScores(snapshot); scores == nil {
+				scores = make(map[string]int)
+			}
+			continue
+		}
+		if ev.Type != EventClick {
+			continue
+		}
+		scores[ev.Player] += toInt(ev.Data["points"])
+		if ev.Data == nil {
+			ev.Data = make(map[string]interface{})
+		}
+		ev.Data["scores"] = maps.Clone(scores)
+	}
+}
+
+// handleReplay returns the recorded timeline of a game
+func handleReplay(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	gameID := r.PathValue("gameId")
+	stored, chunks, err := db.GetReplayWithMock(gameID)
+	if err != nil {
+		log.Printf("[API] Error fetching replay %s: %v", gameID, err)
+		http.Error(w, "Failed to fetch replay", http.StatusInternalServerError)
+		return
+	}
+	if stored == nil {
+		http.Error(w, "Replay not found", http.StatusNotFound)
+		return
+	}
+
+	replay := Replay{
+		GameID:    stored.GameID,
+		P1Name:    stored.P1Name,
+		P2Name:    stored.P2Name,
+		P1Picture: stored.P1Picture,
+		P2Picture: stored.P2Picture,
+		StartedAt: stored.StartedAt,
+		Players:   []ReplayPlayer{},
+		Events:    []ReplayEvent{},
+	}
+	if stored.Players != "" {
+		// Replays recorded before rosters only have the p1/p2 header
+		if err := json.Unmarshal([]byte(stored.Players), &replay.Players); err != nil {
+			log.Printf("[API] Corrupt replay players %s: %v", gameID, err)
+		}
+	}
+	for _, chunk := range chunks {
+		var events []ReplayEvent
+		if err := json.Unmarshal([]byte(chunk.Events), &events); err != nil {
+			log.Printf("[API] Corrupt replay %s: %v", gameID, err)
+			http.Error(w, "Failed to fetch replay", http.StatusInternalServerError)
+			return
+		}
+		replay.Events = append(replay.Events, events...)
+	}
+	rebuildReplayScores(replay.Events)
+	json.NewEncoder(w).Encode(replay)
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Game modes
+const (
+	ModeDuel = "duel" // Classic 1v1
+	ModeFFA  = "ffa"  // Free-for-all with 3-8 players
+	ModeTeam = "team" // 2v2, teammates share a team score
+)
+
+// Team IDs of a team match. Seats alternate between the teams, so "p1" and "p2"
+// are always opponents.
+const (
+	Team1 = "team1"
+	Team2 = "team2"
+)
+
+// GamePlayer identifies a participant of a match
+type GamePlayer struct {
+	UserID  string `json:"userId"`
+	Name    string `json:"name"`
+	Picture string `json:"picture"`
+}
+
+// Roster tracks the players and scores of a match. A duel is simply a roster of two.
+type Roster struct {
+	Mode       string            `json:"mode"`
+	Players    []GamePlayer      `json:"players"`    // Seat order; seat 0 is "p1"
+	Scores     map[string]int    `json:"scores"`     // UserID -> score
+	Eliminated map[string]string `json:"eliminated"` // UserID -> reason, for FFA players who left early
+
+	// Per-player statistics of the match
+	Clicks        map[string]int `json:"clicks,omitempty"`        // UserID -> accepted clicks
+	GoldenCookies map[string]int `json:"goldenCookies,omitempty"` // UserID -> golden cookies claimed
+	MaxDeficit    map[string]int `json:"maxDeficit,omitempty"`    // UserID -> most points the player trailed by
+}
+
+// PlayerResult is a player's final standing in a match
+type PlayerResult struct {
+	GamePlayer
+	Score     int    `json:"score"`
+	Placement int    `json:"placement"`      // 1 = best; tied scores share a placement
+	Reason    string `json:"reason"`         // "normal", or why the player left early
+	Team      string `json:"team,omitempty"` // Team matches only
+	TeamScore int    `json:"teamScore,omitempty"`
+
+	Clicks        int `json:"clicks"`
+	GoldenCookies int `json:"goldenCookies"`
+}
+
+func newRoster(mode string, players []GamePlayer) Roster {
+	scores := make(map[string]int, len(players))
+	for _, p := range players {
+		scores[p.UserID] = 0
+	}
+	return Roster{
+		Mode:       mode,
+		Players:    players,
+		Scores:     scores,
+		Eliminated: make(map[string]string),
+	}
+}
+
+// IDs returns the user IDs of all players in seat order
+func (r *Roster) IDs() []string {
+	ids := make([]string, len(r.Players))
+	for i, p := range r.Players {
+		ids[i] = p.UserID
+	}
+	return ids
+}
+
+// Seat returns the seat index of a player, or -1 if they are not in the match
+func (r *Roster) Seat(userID string) int {
+	for i, p := range r.Players {
+		if p.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+// Role returns the client-facing seat name of a player ("p1", "p2", ...)
+func (r *Roster) Role(userID string) string {
+	seat := r.Seat(userID)
+	if seat < 0 {
+		return ""
+	}
+	return fmt.Sprintf("p%d", seat+1)
+}
+
+// Team returns the team of a player, or "" outside of team matches
+func (r *Roster) Team(userID string) string {
+	seat := r.Seat(userID)
+	if r.Mode != ModeTeam || seat < 0 {
+		return ""
+	}
+	if seat%2 == 0 {
+		return Team1
+	}
+	return Team2
+}
+
+// TeamMembers returns the user IDs of a team's players in seat order
+func (r *Roster) TeamMembers(team string) []string {
+	var members []string
+	for _, p := range r.Players {
+		if r.Team(p.UserID) == team {
+			members = append(members, p.UserID)
+		}
+	}
+	return members
+}
+
+// TeamScores returns the team totals of a team match, nil for other modes
+func (r *Roster) TeamScores() map[string]int {
+	if r.Mode != ModeTeam {
+		return nil
+	}
+	totals := map[string]int{Team1: 0, Team2: 0}
+	for _, p := range r.Players {
+		totals[r.Team(p.UserID)] += r.Scores[p.UserID]
+	}
+	return totals
+}
+
+// otherTeam returns the opposing team ID
+func otherTeam(team string) string {
+	if team == Team1 {
+		return Team2
+	}
+	return Team1
+}
+
+// Allies returns the players who benefit from a power-up claimed by the player:
+// the whole team in a team match, otherwise just the player
+func (r *Roster) Allies(userID string) []string {
+	if team := r.Team(userID); team != "" {
+		return r.TeamMembers(team)
+	}
+	return []string{userID}
+}
+
+// Targets returns the players hit by an offensive power-up claimed by the player:
+// the opposing team in a team match, otherwise the leading opponent
+func (r *Roster) Targets(userID string) []string {
+	if team := r.Team(userID); team != "" {
+		return r.TeamMembers(otherTeam(team))
+	}
+	if opponent := r.LeadingOpponent(userID); opponent != "" {
+		return []string{opponent}
+	}
+	return nil
+}
+
+// ForfeitWinner returns the winner of a duel or team match that a player abandoned
+func (r *Roster) ForfeitWinner(loserID string) string {
+	if team := r.Team(loserID); team != "" {
+		return otherTeam(team)
+	}
+	return r.LeadingOpponent(loserID)
+}
+
+// AddPoints adds (or with a negative value removes) points of a player
+func (r *Roster) AddPoints(userID string, points int) {
+	if r.Seat(userID) < 0 {
+		return
+	}
+	if r.Scores == nil {
+		r.Scores = make(map[string]int)
+	}
+	r.Scores[userID] += points
+	r.trackDeficits()
+}
+
+// Lead returns how many points a player is ahead of the best opponent (the other
+// team in a team match); negative while trailing
+func (r *Roster) Lead(userID string) int {
+	if team := r.Team(userID); team != "" {
+		teamScores := r.TeamScores()
+		return teamScores[team] - teamScores[otherTeam(team)]
+	}
+	opponent := r.LeadingOpponent(userID)
+	if opponent == "" {
+		return 0
+	}
+	return r.Scores[userID] - r.Scores[opponent]
+}
+
+// trackDeficits remembers the largest deficit of every active player, sampled
+// whenever points change
+func (r *Roster) trackDeficits() {
+	for _, id := range r.Active() {
+		if deficit := -r.Lead(id); deficit > r.MaxDeficit[id] {
+			if r.MaxDeficit == nil {
+				r.MaxDeficit = make(map[string]int)
+			}
+			r.MaxDeficit[id] = deficit
+		}
+	}
+}
+
+// CountClick records an accepted click of a player
+func (r *Roster) CountClick(userID string) {
+	if r.Clicks == nil {
+		r.Clicks = make(map[string]int)
+	}
+	r.Clicks[userID]++
+}
+
+// CountGoldenCookie records a golden cookie claimed by a player
+func (r *Roster) CountGoldenCookie(userID string) {
+	if r.GoldenCookies == nil {
+		r.GoldenCookies = make(map[string]int)
+	}
+	r.GoldenCookies[userID]++
+}
+
+// Eliminate removes a player from an FFA match; their score is kept for the results
+func (r *Roster) Eliminate(userID, reason string) {
+	if r.Eliminated == nil {
+		r.Eliminated = make(map[string]string)
+	}
+	r.Eliminated[userID] = reason
+}
+
+// Active returns the players still taking part in the match
+func (r *Roster) Active() []string {
+	active := make([]string, 0, len(r.Players))
+	for _, p := range r.Players {
+		if _, out := r.Eliminated[p.UserID]; !out {
+			active = append(active, p.UserID)
+		}
+	}
+	return active
+}
+
+// LeadingOpponent returns the active opponent with the highest score, the target of
+// offensive power-ups. In a duel this is simply the opponent.
+func (r *Roster) LeadingOpponent(userID string) string {
+	best := ""
+	for _, id := range r.Active() {
+		if id == userID {
+			continue
+		}
+		if best == "" || r.Scores[id] > r.Scores[best] {
+			best = id
+		}
+	}
+	return best
+}
+
+// Results ranks all players: active players by score, followed by eliminated ones.
+// In a team match players are ranked by team score and teammates share a placement.
+func (r *Roster) Results() []PlayerResult {
+	teamScores := r.TeamScores()
+	results := make([]PlayerResult, len(r.Players))
+	for i, p := range r.Players {
+		reason := "normal"
+		if left, ok := r.Eliminated[p.UserID]; ok {
+			reason = left
+		}
+		team := r.Team(p.UserID)
+		results[i] = PlayerResult{
+			GamePlayer: p, Score: r.Scores[p.UserID], Reason: reason, Team: team, TeamScore: teamScores[team],
+			Clicks: r.Clicks[p.UserID], GoldenCookies: r.GoldenCookies[p.UserID],
+		}
+	}
+
+	// rank is what placements are decided by
+	rank := func(res PlayerResult) int {
+		if res.Team != "" {
+			return res.TeamScore
+		}
+		return res.Score
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		iOut, jOut := results[i].Reason != "normal", results[j].Reason != "normal"
+		if iOut != jOut {
+			return !iOut
+		}
+		if rank(results[i]) != rank(results[j]) {
+			return rank(results[i]) > rank(results[j])
+		}
+		if results[i].Team != results[j].Team {
+			return results[i].Team < results[j].Team // Keep teammates together on a tie
+		}
+		return results[i].Score > results[j].Score
+	})
+
+	for i := range results {
+		results[i].Placement = i + 1
+		if i > 0 && rank(results[i]) == rank(results[i-1]) && results[i].Reason == "normal" && results[i-1].Reason == "normal" {
+			results[i].Placement = results[i-1].Placement
+		}
+	}
+
+	if r.Mode == ModeTeam {
+		// Placements count teams, not players
+		for i := 1; i < len(results); i++ {
+			if results[i].Placement != results[i-1].Placement {
+				results[i].Placement = results[i-1].Placement + 1
+			}
+		}
+	}
+	return results
+}
+
+// Winner returns the user ID of the single best player (the team ID in a team
+// match), or "draw" if the top is tied
+func (r *Roster) Winner() string {
+	results := r.Results()
+	if len(results) == 0 {
+		return "draw"
+	}
+	if r.Mode == ModeTeam {
+		teamScores := r.TeamScores()
+		switch {
+		case teamScores[Team1] > teamScores[Team2]:
+			return Team1
+		case teamScores[Team2] > teamScores[Team1]:
+			return Team2
+		}
+		return "draw"
+	}
+	if len(results) > 1 && results[1].Placement == 1 {
+		return "draw"
+	}
+	return results[0].UserID
+}
+
+// ScoresCopy returns a snapshot of the scoreboard that is safe to hand to other goroutines
+func (r *Roster) ScoresCopy() map[string]int {
+	scores := make(map[string]int, len(r.Scores))
+	for id, score := range r.Scores {
+		scores[id] = score
+	}
+	return scores
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+// GameRules holds the tunable parameters of a single match
+type GameRules struct {
+	Preset             string `json:"preset"`
+	DurationSeconds    int    `json:"durationSeconds"`    // Match length
+	CountdownSeconds   int    `json:"countdownSeconds"`   // Countdown before the first click counts
+	GoldenMinSeconds   int    `json:"goldenMinSeconds"`   // Golden cookie spawn interval lower bound
+	GoldenMaxSeconds   int    `json:"goldenMaxSeconds"`   // Golden cookie spawn interval upper bound
+	DoubleClickSeconds int    `json:"doubleClickSeconds"` // Duration of the golden cookie double-click bonus
+}
+
+const defaultRulesPreset = "classic"
+
+// Built-in rule presets, extendable via GAME_RULES_FILE
+var rulePresets = map[string]GameRules{
+	"classic": {
+		Preset: "classic", DurationSeconds: 60, CountdownSeconds: 5,
+		GoldenMinSeconds: 5, GoldenMaxSeconds: 10, DoubleClickSeconds: 3,
+	},
+	"blitz": {
+		Preset: "blitz", DurationSeconds: 30, CountdownSeconds: 3,
+		GoldenMinSeconds: 3, GoldenMaxSeconds: 6, DoubleClickSeconds: 3,
+	},
+	"marathon": {
+		Preset: "marathon", DurationSeconds: 180, CountdownSeconds: 5,
+		GoldenMinSeconds: 8, GoldenMaxSeconds: 15, DoubleClickSeconds: 4,
+	},
+}
+
+// matchmakingPreset is the preset used for public matchmaking (GAME_RULES)
+var matchmakingPreset = defaultRulesPreset
+
+// initGameRules loads custom presets from GAME_RULES_FILE (a JSON object of preset
+// name -> rules) and selects the matchmaking preset from GAME_RULES
+func initGameRules() {
+	if path := os.Getenv("GAME_RULES_FILE"); path != "" {
+		if err := loadRulePresets(path); err != nil {
+			log.Printf("[RULES] Failed to load %s, using built-in presets: %v", path, err)
+		}
+	}
+
+	if name := strings.ToLower(os.Getenv("GAME_RULES")); name != "" {
+		if _, ok := rulePresets[name]; ok {
+			matchmakingPreset = name
+		} else {
+			log.Printf("[RULES] Unknown preset %q, falling back to %s", name, defaultRulesPreset)
+		}
+	}
+
+	rules := matchmakingRules()
+	log.Printf("[RULES] Matchmaking preset %s: %ds matches, %ds countdown, golden cookie every %d-%ds (%ds bonus)",
+		rules.Preset, rules.DurationSeconds, rules.CountdownSeconds,
+		rules.GoldenMinSeconds, rules.GoldenMaxSeconds, rules.DoubleClickSeconds)
+}
+
+// loadRulePresets adds (or overrides) presets from a JSON file
+func loadRulePresets(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var presets map[string]GameRules
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return err
+	}
+
+	for name, rules := range presets {
+		name = strings.ToLower(name)
+		rules.Preset = name
+		if !rules.valid() {
+			log.Printf("[RULES] Ignoring invalid preset %q", name)
+			continue
+		}
+		rulePresets[name] = rules
+	}
+	return nil
+}
+
+func (r GameRules) valid() bool {
+	return r.DurationSeconds > 0 && r.CountdownSeconds >= 0 &&
+		r.GoldenMinSeconds > 0 && r.GoldenMaxSeconds >= r.GoldenMinSeconds &&
+		r.DoubleClickSeconds > 0
+}
+
+// rulesForPreset returns the named preset and whether it exists
+func rulesForPreset(name string) (GameRules, bool) {
+	rules, ok := rulePresets[strings.ToLower(name)]
+	return rules, ok
+}
+
+// matchmakingRules returns the rules used for public matchmaking games
+func matchmakingRules() GameRules {
+	return rulePresets[matchmakingPreset]
+}
+
+// Countdown returns the time between match creation and the first tick
+func (r GameRules) Countdown() time.Duration {
+	return time.Duration(r.CountdownSeconds) * time.Second
+}
+
+// DoubleClick returns how long a claimed golden cookie doubles clicks
+func (r GameRules) DoubleClick() time.Duration {
+	return time.Duration(r.DoubleClickSeconds) * time.Second
+}
+
+// NextGoldenCookie returns a random delay until the next golden cookie spawns
+func (r GameRules) NextGoldenCookie() time.Duration {
+	return time.Duration(r.GoldenMinSeconds+rand.Intn(r.GoldenMaxSeconds-r.GoldenMinSeconds+1)) * time.Second
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+)
+
+// Season configuration (overridable via environment variables)
+var seasonLength = 30 * 24 * time.Hour // SEASON_LENGTH_DAYS: length of a ranked season
+
+const seasonCheckInterval = time.Minute
+
+var (
+	activeSeason      *db.CookieSeason // Cached season games are currently recorded for
+	activeSeasonMutex sync.Mutex
+)
+
+// initSeasonConfig loads the season configuration from the environment
+func initSeasonConfig() {
+	if v, err := strconv.Atoi(os.Getenv("SEASON_LENGTH_DAYS")); err == nil && v > 0 {
+		seasonLength = time.Duration(v) * 24 * time.Hour
+	}
+	log.Printf("[SEASON] Seasons last %s", seasonLength)
+}
+
+// RunSeasonLoop starts the first season if needed and rolls over to the next season
+// whenever the current one has ended
+func RunSeasonLoop() {
+	rolloverSeasons(time.Now())
+
+	ticker := time.NewTicker(seasonCheckInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		rolloverSeasons(now)
+	}
+}
+
+// rolloverSeasons makes sure a season covering now exists and archives the final
+// standings of every season that has ended. Safe to run on several pods at once:
+// season creation is conditional and archiving always writes the same ranks.
+func rolloverSeasons(now time.Time) *db.CookieSeason {
+	activeSeasonMutex.Lock()
+	defer activeSeasonMutex.Unlock()
+
+	seasons, err := db.ListSeasonsWithMock()
+	if err != nil {
+		log.Printf("[SEASON] Failed to list seasons: %v", err)
+		return nil
+	}
+
+	var current *db.CookieSeason
+	if len(seasons) > 0 {
+		current = &seasons[len(seasons)-1]
+	}
+
+	if current == nil || now.Unix() >= current.EndsAt {
+		next := db.CookieSeason{SeasonID: 1, StartsAt: now.Unix()}
+		if current != nil {
+			next.SeasonID = current.SeasonID + 1
+			// Seasons follow each other without gaps, unless the server was down for a whole season
+			if now.Unix()-current.EndsAt < int64(seasonLength.Seconds()) {
+				next.StartsAt = current.EndsAt
+			}
+		}
+		next.Name = fmt.Sprintf("Season %d", next.SeasonID)
+		next.EndsAt = next.StartsAt + int64(seasonLength.Seconds())
+
+		created, err := db.CreateSeasonWithMock(next)
+		if err != nil {
+			log.Printf("[SEASON] Failed to start %s: %v", next.Name, err)
+			return nil
+		}
+		if created {
+			log.Printf("[SEASON] %s started", next.Name)
+		}
+
+		// Pick up the season as stored, another pod might have created it first
+		if seasons, err = db.ListSeasonsWithMock(); err != nil || len(seasons) == 0 {
+			return nil
+		}
+		current = &seasons[len(seasons)-1]
+	}
+
+	for _, season := range seasons {
+		if !season.Archived && now.Unix() >= season.EndsAt {
+			archiveSeason(season)
+		}
+	}
+
+	activeSeason = current
+	return current
+}
+
+// archiveSeason stores the final rank of every player of an ended season
+func archiveSeason(season db.CookieSeason) {
+	stats, err := db.GetSeasonStatsWithMock(season.SeasonID)
+	if err != nil {
+		log.Printf("[SEASON] Failed to load standings of %s: %v", season.Name, err)
+		return
+	}
+
+	ranks := make(map[string]int, len(stats))
+	for i, rank := range seasonRanks(stats) {
+		ranks[stats[i].UserID] = rank
+	}
+	if err := db.ArchiveSeasonWithMock(season.SeasonID, ranks); err != nil {
+		log.Printf("[SEASON] Failed to archive %s: %v", season.Name, err)
+		return
+	}
+	log.Printf("[SEASON] %s ended, final standings of %d players archived", season.Name, len(stats))
+}
+
+// seasonRanks returns the rank of every entry of standings ordered by score; tied
+// scores share a rank
+func seasonRanks(stats []db.CookieSeasonStats) []int {
+	ranks := make([]int, len(stats))
+	for i := range stats {
+		ranks[i] = i + 1
+		if i > 0 && stats[i].Score == stats[i-1].Score {
+			ranks[i] = ranks[i-1]
+		}
+	}
+	return ranks
+}
+
+// currentSeason returns the season games are recorded for right now, starting the
+// next season if the cached one has just ended
+func currentSeason() *db.CookieSeason {
+	now := time.Now()
+	activeSeasonMutex.Lock()
+	season := activeSeason
+	activeSeasonMutex.Unlock()
+
+	if season != nil && now.Unix() < season.EndsAt {
+		return season
+	}
+	return rolloverSeasons(now)
+}
+
+// recordSeasonResults adds a finished game to the season stats of its players
+func recordSeasonResults(winnerID string, results []PlayerResult, ratings map[string]int) {
+	season := currentSeason()
+	if season == nil {
+		return
+	}
+
+	for _, res := range results {
+		rating, ok := ratings[res.UserID]
+		if !ok {
+			rating = lookupRating(res.UserID)
+		}
+		won := winnerID != "draw" && res.Placement == 1
+		db.UpdateSeasonStatsWithMock(season.SeasonID, res.UserID, res.Name, res.Picture, res.Score, won, rating)
+	}
+}
+
+// findSeason resolves the season query parameter: "current" or a season number
+func findSeason(param string) (*db.CookieSeason, error) {
+	if param == "current" {
+		return currentSeason(), nil
+	}
+
+	seasonID, err := strconv.Atoi(param)
+	if err != nil {
+		return nil, nil
+	}
+	seasons, err := db.ListSeasonsWithMock()
+	if err != nil {
+		return nil, err
+	}
+	for i := range seasons {
+		if seasons[i].SeasonID == seasonID {
+			return &seasons[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// seasonLeaderboard returns the standings of a season. Archived seasons are ranked
+// by their final standings; sorted by rating, players are ranked by their rating.
+func seasonLeaderboard(season *db.CookieSeason, sortBy string) (rankedList, error) {
+	stats, err := db.GetSeasonStatsWithMock(season.SeasonID)
+	if err != nil {
+		return rankedList{}, err
+	}
+
+	ranks := make(map[string]int, len(stats))
+	for i, rank := range seasonRanks(stats) {
+		if season.Archived && stats[i].FinalRank > 0 {
+			rank = stats[i].FinalRank
+		}
+		ranks[stats[i].UserID] = rank
+	}
+
+	if sortBy == "rating" {
+		sort.SliceStable(stats, func(i, j int) bool {
+			return stats[i].Rating > stats[j].Rating
+		})
+		for i, s := range stats {
+			ranks[s.UserID] = i + 1
+			if i > 0 && s.Rating == stats[i-1].Rating {
+				ranks[s.UserID] = ranks[stats[i-1].UserID]
+			}
+		}
+	} else {
+		sort.SliceStable(stats, func(i, j int) bool {
+			return ranks[stats[i].UserID] < ranks[stats[j].UserID]
+		})
+	}
+
+	list := rankedList{
+		entries: make([]PublicLeaderboardEntry, len(stats)),
+		userIDs: make([]string, len(stats)),
+	}
+	for i, s := range stats {
+		list.entries[i] = PublicLeaderboardEntry{
+			Name:    s.Name,
+			Picture: s.Picture,
+			Score:   s.Score,
+			Rating:  s.Rating,
+			Rank:    ranks[s.UserID],
+			Games:   s.Games,
+			Wins:    s.Wins,
+		}
+		list.userIDs[i] = s.UserID
+	}
+	return list, nil
+}
+
+// handleSeasons lists all seasons, the current one last
+func handleSeasons(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	seasons, err := db.ListSeasonsWithMock()
+	if err != nil {
+		log.Printf("[API] Error listing seasons: %v", err)
+		http.Error(w, "Failed to fetch seasons", http.StatusInternalServerError)
+		return
+	}
+	if seasons == nil {
+		seasons = []db.CookieSeason{}
+	}
+	json.NewEncoder(w).Encode(seasons)
+}
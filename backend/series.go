@@ -0,0 +1,348 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+)
+
+// Series is a best-of-N duel: rounds are regular distributed games, played until a
+// player has won the majority of rounds, with an intermission in between. The
+// running series lives in Redis; the finished series is stored as a CookieSeries.
+type Series struct {
+	ID        string         `json:"id"`
+	BestOf    int            `json:"bestOf"`
+	Players   []QueueEntry   `json:"players"` // Seat order
+	Rules     GameRules      `json:"rules"`
+	Round     int            `json:"round"`  // Current round, 1-based
+	RoomID    string         `json:"roomId"` // Room of the current round
+	Wins      map[string]int `json:"wins"`   // UserID -> rounds won
+	Draws     int            `json:"draws"`
+	GameIDs   []string       `json:"gameIds"`            // Finished rounds
+	WinnerID  string         `json:"winnerId,omitempty"` // Set once the series is decided, "draw" if it ended level
+	StartedAt int64          `json:"startedAt"`
+}
+
+const (
+	seriesKeyPrefix    = "overcookied:series:"
+	seriesTTL          = 2 * time.Hour
+	seriesIntermission = 10 * time.Second
+)
+
+var (
+	errSeriesNotFound = errors.New("series not found")
+	errSeriesOver     = errors.New("series already decided")
+)
+
+// validBestOf reports whether a series length is supported; 0 and 1 mean a single game
+func validBestOf(bestOf int) bool {
+	return bestOf == 0 || bestOf == 1 || bestOf == 3 || bestOf == 5
+}
+
+// userIDs returns the IDs of the series players in seat order
+func (s *Series) userIDs() []string {
+	ids := make([]string, len(s.Players))
+	for i, p := range s.Players {
+		ids[i] = p.UserID
+	}
+	return ids
+}
+
+// outcome decides the series: a player who won the majority of rounds takes it,
+// otherwise once all rounds are played the player with more wins does
+func (s *Series) outcome() (winnerID string, over bool) {
+	for _, id := range s.userIDs() {
+		if s.Wins[id] > s.BestOf/2 {
+			return id, true
+		}
+	}
+	if len(s.GameIDs) < s.BestOf {
+		return "", false
+	}
+	a, b := s.Players[0].UserID, s.Players[1].UserID
+	switch {
+	case s.Wins[a] > s.Wins[b]:
+		return a, true
+	case s.Wins[b] > s.Wins[a]:
+		return b, true
+	default:
+		return "draw", true
+	}
+}
+
+// view is the client-facing description of the series
+func (s *Series) view() map[string]interface{} {
+	return map[string]interface{}{
+		"id":     s.ID,
+		"bestOf": s.BestOf,
+		"round":  s.Round,
+		"score":  s.Wins,
+		"draws":  s.Draws,
+	}
+}
+
+// CreateSeries stores a new series between players, whose rounds are played under rules
+func CreateSeries(players []QueueEntry, rules GameRules, bestOf int) (*Series, error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	series := Series{
+		ID:        hex.EncodeToString(idBytes),
+		BestOf:    bestOf,
+		Players:   players,
+		Rules:     rules,
+		Wins:      make(map[string]int),
+		StartedAt: time.Now().Unix(),
+	}
+
+	seriesJSON, err := json.Marshal(series)
+	if err != nil {
+		return nil, err
+	}
+	if err := kvSet(seriesKeyPrefix+series.ID, string(seriesJSON), seriesTTL); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Series %s created: best of %d between %s and %s", series.ID, bestOf, players[0].UserID, players[1].UserID)
+	return &series, nil
+}
+
+// GetSeries loads a running series by ID
+func GetSeries(seriesID string) (*Series, error) {
+	seriesJSON, found, err := kvGet(seriesKeyPrefix + seriesID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errSeriesNotFound
+	}
+
+	var series Series
+	if err := json.Unmarshal([]byte(seriesJSON), &series); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// updateSeries atomically applies fn to a running series and returns the result.
+// A decided series cannot change anymore.
+func updateSeries(seriesID string, fn func(series *Series) error) (*Series, error) {
+	var series Series
+	err := kvUpdate(seriesKeyPrefix+seriesID, seriesTTL, func(current string, found bool) (string, error) {
+		if !found {
+			return "", errSeriesNotFound
+		}
+		series = Series{}
+		if err := json.Unmarshal([]byte(current), &series); err != nil {
+			return "", err
+		}
+		if series.WinnerID != "" {
+			return "", errSeriesOver
+		}
+		if err := fn(&series); err != nil {
+			return "", err
+		}
+
+		updated, err := json.Marshal(series)
+		return string(updated), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// startSeries creates a series and starts its first round
+func (gm *GameManager) startSeries(players []QueueEntry, rules GameRules, bestOf int) error {
+	series, err := CreateSeries(players, rules, bestOf)
+	if err != nil {
+		return err
+	}
+	gm.startSeriesRound(series.ID)
+	return nil
+}
+
+// startSeriesRound starts the next round of a series through the regular match
+// flow. A player who is gone or playing elsewhere forfeits the series.
+func (gm *GameManager) startSeriesRound(seriesID string) {
+	series, err := GetSeries(seriesID)
+	if err != nil {
+		log.Printf("Failed to load series %s: %v", seriesID, err)
+		return
+	}
+
+	userIDs := series.userIDs()
+	presence := gm.presenceOf(userIDs)
+	for _, id := range userIDs {
+		switch presence[id] {
+		case PresenceOffline, PresenceInGame:
+			log.Printf("Player %s is not available for round %d of series %s", id, series.Round+1, seriesID)
+			gm.forfeitSeries(seriesID, id)
+			return
+		case PresenceQueued:
+			RemoveFromQueue(id)
+		}
+	}
+
+	roomID := newRoomID(ModeDuel, userIDs)
+	linkSeries := func(state *DistributedGameState) { state.SeriesID = seriesID }
+	if err := CreateDistributedGame(roomID, ModeDuel, series.Players, series.Rules, linkSeries); err != nil {
+		log.Printf("Failed to create round %d of series %s: %v", series.Round+1, seriesID, err)
+		gm.abortSeries(seriesID)
+		return
+	}
+	series, err = updateSeries(seriesID, func(series *Series) error {
+		series.Round++
+		series.RoomID = roomID
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to start the next round of series %s: %v", seriesID, err)
+		return
+	}
+
+	log.Printf("Series %s: round %d starting in room %s", seriesID, series.Round, roomID)
+	if err := PublishMatchNotification(newMatchNotification(roomID, ModeDuel, userIDs)); err != nil {
+		log.Printf("Failed to publish match notification for series %s: %v", seriesID, err)
+	}
+}
+
+// finishSeriesRound counts a finished round and either ends the series or starts
+// the next round after the intermission. Runs on the timer pod of the round.
+func (gm *GameManager) finishSeriesRound(state *DistributedGameState) {
+	series, err := updateSeries(state.SeriesID, func(series *Series) error {
+		if series.RoomID != state.RoomID || len(series.GameIDs) == series.Round {
+			return errSeriesOver // Round already counted
+		}
+		series.GameIDs = append(series.GameIDs, state.RoomID)
+		if state.WinnerID == "draw" {
+			series.Draws++
+		} else {
+			series.Wins[state.WinnerID]++
+		}
+		series.WinnerID, _ = series.outcome()
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, errSeriesOver) {
+			log.Printf("Failed to count round %s of series %s: %v", state.RoomID, state.SeriesID, err)
+		}
+		return
+	}
+
+	if series.WinnerID != "" {
+		gm.endSeries(series, "normal")
+		return
+	}
+
+	payload := series.view()
+	payload["roundWinner"] = state.WinnerID
+	payload["nextRound"] = series.Round + 1
+	payload["intermission"] = seriesIntermission.Seconds()
+	for _, id := range series.userIDs() {
+		gm.sendToUser(id, GameMessage{Type: MsgTypeSeriesUpdate, Payload: payload})
+	}
+	time.AfterFunc(seriesIntermission, func() { gm.startSeriesRound(series.ID) })
+}
+
+// forfeitSeries ends a series in favour of the opponent of a player who quit or
+// could not play on
+func (gm *GameManager) forfeitSeries(seriesID, loserID string) {
+	series, err := updateSeries(seriesID, func(series *Series) error {
+		for _, id := range series.userIDs() {
+			if id != loserID {
+				series.WinnerID = id
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, errSeriesOver) {
+			log.Printf("Failed to forfeit series %s: %v", seriesID, err)
+		}
+		return
+	}
+	gm.endSeries(series, "forfeit")
+}
+
+// abortSeries ends a series whose next round could not be started, without a winner
+func (gm *GameManager) abortSeries(seriesID string) {
+	series, err := updateSeries(seriesID, func(series *Series) error {
+		series.WinnerID = "draw"
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, errSeriesOver) {
+			log.Printf("Failed to abort series %s: %v", seriesID, err)
+		}
+		return
+	}
+	gm.endSeries(series, "aborted")
+}
+
+// endSeries persists a decided series and announces the result to its players
+func (gm *GameManager) endSeries(series *Series, reason string) {
+	log.Printf("Series %s over: winner %s (%s)", series.ID, series.WinnerID, reason)
+
+	names := make([]string, len(series.Players))
+	for i, p := range series.Players {
+		names[i] = p.Name
+	}
+	db.SaveSeriesWithMock(db.CookieSeries{
+		SeriesID: series.ID, BestOf: series.BestOf, Mode: ModeDuel,
+		PlayerIDs: series.userIDs(), Names: names,
+		Wins: series.Wins, Draws: series.Draws, GameIDs: series.GameIDs,
+		WinnerID: series.WinnerID, Reason: reason,
+		StartedAt: series.StartedAt, EndedAt: time.Now().Unix(),
+	})
+
+	payload := series.view()
+	payload["winner"] = series.WinnerID
+	payload["reason"] = reason
+	for _, id := range series.userIDs() {
+		gm.sendToUser(id, GameMessage{Type: MsgTypeSeriesOver, Payload: payload})
+	}
+}
+
+// seriesPayload describes the series a room belongs to for GAME_START, nil if none
+func seriesPayload(seriesID string) map[string]interface{} {
+	if seriesID == "" {
+		return nil
+	}
+	series, err := GetSeries(seriesID)
+	if err != nil {
+		log.Printf("Failed to load series %s: %v", seriesID, err)
+		return nil
+	}
+	return series.view()
+}
+
+// handleSeries returns the record of a finished series
+func handleSeries(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	seriesID := r.PathValue("seriesId")
+	series, err := db.GetSeriesWithMock(seriesID)
+	if err != nil {
+		log.Printf("[API] Error fetching series %s: %v", seriesID, err)
+		http.Error(w, "Failed to fetch series", http.StatusInternalServerError)
+		return
+	}
+	if series == nil {
+		http.Error(w, "Series not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(series)
+}
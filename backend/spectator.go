@@ -0,0 +1,142 @@
+package main
+
+import (
+	"log"
+)
+
+// payloadString extracts a string field from a generic message payload
+func payloadString(payload interface{}, key string) string {
+	fields, ok := payload.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	value, _ := fields[key].(string)
+	return value
+}
+
+// payloadInt extracts a numeric field from a generic message payload, 0 if missing
+func payloadInt(payload interface{}, key string) int {
+	fields, ok := payload.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	return toInt(fields[key])
+}
+
+// sendError reports a failed request back to the client
+func sendError(client *Client, code, message string) {
+	sendToClient(client, GameMessage{
+		Type:    MsgTypeError,
+		Payload: map[string]string{"code": code, "message": message},
+	})
+}
+
+// handleSpectate attaches the client to a running distributed game as a read-only
+// spectator. Game events reach spectators through the same Redis event stream
+// that feeds the players, so the game may be hosted on any pod.
+func (gm *GameManager) handleSpectate(client *Client, roomID string) {
+	if roomID == "" {
+		sendError(client, "invalid_request", "roomId is required")
+		return
+	}
+
+	if !IsRedisAvailable() {
+		sendError(client, "unavailable", "Spectating is not available right now")
+		return
+	}
+
+	if gm.inActiveGame(client) {
+		sendError(client, "already_in_game", "You cannot spectate while playing")
+		return
+	}
+
+	state, err := GetGameState(roomID)
+	if err != nil || state == nil || state.GameEnded {
+		sendError(client, "game_not_found", "This game is not live anymore")
+		return
+	}
+
+	gm.mutex.Lock()
+	gm.spectators[client] = roomID
+	gm.mutex.Unlock()
+
+	log.Printf("Client %s is now spectating room %s", client.userID, roomID)
+
+	sendToClient(client, GameMessage{
+		Type: MsgTypeSpectateStart,
+		Payload: withTeamScores(map[string]interface{}{
+			"roomId":        roomID,
+			"timeRemaining": state.TimeRemaining,
+			"p1Score":       state.P1Score,
+			"p2Score":       state.P2Score,
+			"p1Name":        state.Player1Name,
+			"p2Name":        state.Player2Name,
+			"p1Picture":     state.Player1Picture,
+			"p2Picture":     state.Player2Picture,
+			"mode":          state.Mode,
+			"players":       state.Players,
+			"scores":        state.ScoresCopy(),
+			"started":       state.GameStarted,
+		}, state.TeamScores()),
+	})
+}
+
+// stopSpectating detaches a spectator from its room
+func (gm *GameManager) stopSpectating(client *Client) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+
+	if roomID, ok := gm.spectators[client]; ok {
+		delete(gm.spectators, client)
+		log.Printf("Client %s stopped spectating room %s", client.userID, roomID)
+	}
+}
+
+// LiveGame is the public summary of a running game that spectators can join
+type LiveGame struct {
+	RoomID        string         `json:"roomId"`
+	Mode          string         `json:"mode"`
+	Players       []GamePlayer   `json:"players"`
+	Scores        map[string]int `json:"scores"`
+	TeamScores    map[string]int `json:"teamScores,omitempty"`
+	P1Name        string         `json:"p1Name"`
+	P2Name        string         `json:"p2Name"`
+	P1Picture     string         `json:"p1Picture"`
+	P2Picture     string         `json:"p2Picture"`
+	P1Score       int            `json:"p1Score"`
+	P2Score       int            `json:"p2Score"`
+	TimeRemaining int            `json:"timeRemaining"`
+	Started       bool           `json:"started"`
+}
+
+// ListLiveGames returns all games that are still running
+func ListLiveGames() ([]LiveGame, error) {
+	roomIDs, err := ListActiveGames()
+	if err != nil {
+		return nil, err
+	}
+
+	games := make([]LiveGame, 0, len(roomIDs))
+	for _, roomID := range roomIDs {
+		state, err := GetGameState(roomID)
+		if err != nil || state == nil || state.GameEnded {
+			continue
+		}
+		games = append(games, LiveGame{
+			RoomID:        state.RoomID,
+			Mode:          state.Mode,
+			Players:       state.Players,
+			Scores:        state.ScoresCopy(),
+			TeamScores:    state.TeamScores(),
+			P1Name:        state.Player1Name,
+			P2Name:        state.Player2Name,
+			P1Picture:     state.Player1Picture,
+			P2Picture:     state.Player2Picture,
+			P1Score:       state.P1Score,
+			P2Score:       state.P2Score,
+			TimeRemaining: state.TimeRemaining,
+			Started:       state.GameStarted,
+		})
+	}
+	return games, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"log"
+	"sort"
+)
+
+const (
+	teamSize         = 2
+	teamMatchPlayers = 2 * teamSize
+)
+
+// selectTeamGroup picks queue entries (players or parties) with four players in
+// total for a team match. Entries must be ordered by JoinedAt (oldest first).
+func selectTeamGroup(entries []QueueEntry, now int64) []int {
+	var picked []int
+	players := 0
+	seen := make(map[string]bool)
+	for i, entry := range entries {
+		if seen[entry.UserID] || players+entry.unitSize() > teamMatchPlayers {
+			continue
+		}
+		seen[entry.UserID] = true
+		picked = append(picked, i)
+		players += entry.unitSize()
+		if players == teamMatchPlayers {
+			return picked
+		}
+	}
+	return nil
+}
+
+// formTeams seats the players of a team match. A party of two always forms a team;
+// everyone else is balanced by rating, so the best and the worst rated player play
+// together. Seats alternate between the teams.
+func formTeams(groups [][]QueueEntry) []QueueEntry {
+	var duos [][]QueueEntry
+	var solos []QueueEntry
+	for _, group := range groups {
+		if len(group) == teamSize {
+			duos = append(duos, group)
+		} else {
+			solos = append(solos, group...)
+		}
+	}
+
+	var team1, team2 []QueueEntry
+	switch len(duos) {
+	case 2:
+		team1, team2 = duos[0], duos[1]
+	case 1:
+		team1, team2 = duos[0], solos
+	default:
+		sort.SliceStable(solos, func(a, b int) bool {
+			return solos[a].Rating > solos[b].Rating
+		})
+		team1 = []QueueEntry{solos[0], solos[3]}
+		team2 = []QueueEntry{solos[1], solos[2]}
+	}
+
+	// Seats p1..p4: team1, team2, team1, team2
+	return []QueueEntry{team1[0], team2[0], team1[1], team2[1]}
+}
+
+// TryTeamMatchmaking attempts to form a 2v2 match from the queue.
+// Returns the seated players if a match can start, nil otherwise
+func TryTeamMatchmaking() ([]QueueEntry, error) {
+	units, err := matchFromQueue(ModeTeam, selectTeamGroup)
+	if err != nil || units == nil {
+		return nil, err
+	}
+
+	groups, err := expandUnits(units)
+	if err != nil {
+		return nil, err
+	}
+	matched := formTeams(groups)
+
+	log.Printf("Matched team game: %s & %s vs %s & %s",
+		matched[0].UserID, matched[2].UserID, matched[1].UserID, matched[3].UserID)
+	return matched, nil
+}
+
+// handleLocalTeamJoin adds a client to the in-memory team queue (single-pod mode)
+// and starts a match once four players are waiting. Must be called with gm.mutex held.
+func (gm *GameManager) handleLocalTeamJoin(client *Client) {
+	for _, waiting := range gm.teamWaiting {
+		if waiting == client {
+			return
+		}
+	}
+	gm.teamWaiting = append(gm.teamWaiting, client)
+
+	if len(gm.teamWaiting) == teamMatchPlayers {
+		players := gm.teamWaiting
+		gm.teamWaiting = nil
+		gm.StartGame(ModeTeam, players...)
+	}
+}
@@ -0,0 +1,882 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/bits"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mauricedolibois/overcookied/backend/db"
+	"github.com/redis/go-redis/v9"
+)
+
+// Tournament is a single- or double-elimination bracket of duels. Players register
+// while it is open; once the organizer starts it they are seeded by score or
+// rating, and every match whose two players are known is played as a regular
+// distributed game. The tournament is stored as a whole in DynamoDB, so any pod
+// may advance it and it survives pod restarts.
+type Tournament struct {
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Format    string             `json:"format"` // FormatSingleElim or FormatDoubleElim
+	SeedBy    string             `json:"seedBy"` // SeedByScore or SeedByRating
+	Rules     GameRules          `json:"rules"`
+	Status    string             `json:"status"`
+	CreatedBy string             `json:"createdBy"`
+	Players   []TournamentPlayer `json:"players"` // Registration order, seed order once started
+	Matches   []*TournamentMatch `json:"matches,omitempty"`
+	WinnerID  string             `json:"winnerId,omitempty"`
+	CreatedAt int64              `json:"createdAt"`
+	StartedAt int64              `json:"startedAt,omitempty"`
+	EndedAt   int64              `json:"endedAt,omitempty"`
+
+	version int      // Stored version the tournament was read at
+	readied []string // Matches that got both their players during the last update
+}
+
+// TournamentPlayer is a registered player of a tournament
+type TournamentPlayer struct {
+	UserID    string `json:"userId"`
+	Name      string `json:"name"`
+	Picture   string `json:"picture"`
+	Seed      int    `json:"seed,omitempty"`      // 1 is the best seed
+	SeedValue int    `json:"seedValue,omitempty"` // Score or rating at the start
+}
+
+// TournamentMatch is one duel of the bracket. Its players are filled in by the
+// seeding or by the matches feeding into it; a slot that stays empty is a bye.
+type TournamentMatch struct {
+	ID         string    `json:"id"`      // "W2-1" is match 1 of winners round 2, "L1-2", "GF", "GF2"
+	Bracket    string    `json:"bracket"` // "winners", "losers" or "final"
+	Round      int       `json:"round"`
+	Players    [2]string `json:"players"` // UserIDs, "" while unknown or for a bye
+	Filled     [2]bool   `json:"filled"`  // Slot decided, possibly as a bye
+	Status     string    `json:"status"`
+	RoomID     string    `json:"roomId,omitempty"` // Current or last game
+	Games      []string  `json:"games,omitempty"`  // Rooms played, a draw is replayed
+	WinnerID   string    `json:"winnerId,omitempty"`
+	LoserID    string    `json:"loserId,omitempty"`
+	Result     string    `json:"result,omitempty"`     // "normal", "forfeit", "no_show" or "bye"
+	ReadySince int64     `json:"readySince,omitempty"` // Unix seconds both players are known, starts the no-show clock
+	StartedAt  int64     `json:"startedAt,omitempty"`
+	WinnerTo   string    `json:"winnerTo,omitempty"` // Match the winner advances to, none for the final
+	WinnerSlot int       `json:"winnerSlot"`
+	LoserTo    string    `json:"loserTo,omitempty"` // Double elimination: match the loser drops to
+	LoserSlot  int       `json:"loserSlot"`
+}
+
+// Tournament states
+const (
+	TournamentRegistration = "registration"
+	TournamentRunning      = "running"
+	TournamentFinished     = "finished"
+)
+
+// Tournament formats and seeding criteria
+const (
+	FormatSingleElim = "single"
+	FormatDoubleElim = "double"
+
+	SeedByScore  = "score"
+	SeedByRating = "rating"
+)
+
+// Match states
+const (
+	matchPending = "pending" // Waiting for its players
+	matchReady   = "ready"   // Both players known, waiting for them to be available
+	matchPlaying = "playing"
+	matchDone    = "done"
+)
+
+const (
+	minTournamentPlayers    = 2
+	maxTournamentPlayers    = 64
+	tournamentNoShowTimeout = 3 * time.Minute  // A player who stays unavailable this long forfeits the match
+	tournamentCheckInterval = 15 * time.Second // Pace of RunTournamentLoop
+	tournamentIntermission  = 10 * time.Second // Break before a newly ready match is started
+	tournamentLostGameGrace = time.Minute      // A playing match whose game is gone for longer is replayed
+	tournamentUpdateRetries = 5
+)
+
+var (
+	errTournamentNotFound  = errors.New("tournament not found")
+	errTournamentBusy      = errors.New("tournament is updated concurrently")
+	errTournamentUnchanged = errors.New("tournament already up to date")
+	errTournamentClosed    = errors.New("tournament registration closed")
+	errTournamentFull      = errors.New("tournament full")
+	errTournamentNotReady  = errors.New("tournament cannot start")
+)
+
+// matchID names match i (0-based) of a round of the "W" or "L" bracket
+func matchID(bracket string, round, i int) string {
+	return fmt.Sprintf("%s%d-%d", bracket, round, i+1)
+}
+
+// seedOrder returns the seeds in bracket order for a bracket of size players, so
+// that the best seeds meet as late as possible: 1, 8, 4, 5, 2, 7, 3, 6 for 8
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, 2*len(order))
+		for _, seed := range order {
+			next = append(next, seed, 2*len(order)+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// match returns a match of the bracket by ID, nil if unknown
+func (t *Tournament) match(id string) *TournamentMatch {
+	for _, m := range t.Matches {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// player returns a registered player, nil if unknown
+func (t *Tournament) player(userID string) *TournamentPlayer {
+	for i := range t.Players {
+		if t.Players[i].UserID == userID {
+			return &t.Players[i]
+		}
+	}
+	return nil
+}
+
+// seed sorts the players by their seeding value, best first; ties keep the
+// registration order
+func (t *Tournament) seed(values map[string]int) {
+	for i := range t.Players {
+		t.Players[i].SeedValue = values[t.Players[i].UserID]
+	}
+	sort.SliceStable(t.Players, func(i, j int) bool {
+		return t.Players[i].SeedValue > t.Players[j].SeedValue
+	})
+	for i := range t.Players {
+		t.Players[i].Seed = i + 1
+	}
+}
+
+// buildBracket lays out all matches of the bracket and places the seeded players
+// into the first round. Missing seeds are byes for the best seeds.
+//
+// In double elimination the losers of winners round 1 meet in losers round 1,
+// the losers of every later winners round r drop into losers round 2r-2, and the
+// winners of both brackets meet in the grand final. If the losers bracket winner
+// wins it, both players have lost once and the reset match "GF2" decides.
+func (t *Tournament) buildBracket() {
+	size := 2
+	for size < len(t.Players) {
+		size *= 2
+	}
+	rounds := bits.Len(uint(size)) - 1
+	double := t.Format == FormatDoubleElim
+
+	t.Matches = nil
+	for r := 1; r <= rounds; r++ {
+		for i := 0; i < size>>r; i++ {
+			m := &TournamentMatch{ID: matchID("W", r, i), Bracket: "winners", Round: r, Status: matchPending}
+			switch {
+			case r < rounds:
+				m.WinnerTo, m.WinnerSlot = matchID("W", r+1, i/2), i%2
+			case double:
+				m.WinnerTo, m.WinnerSlot = "GF", 0
+			}
+			if double {
+				switch {
+				case rounds == 1:
+					m.LoserTo, m.LoserSlot = "GF", 1
+				case r == 1:
+					m.LoserTo, m.LoserSlot = matchID("L", 1, i/2), i%2
+				default:
+					m.LoserTo, m.LoserSlot = matchID("L", 2*r-2, i), 1
+				}
+			}
+			t.Matches = append(t.Matches, m)
+		}
+	}
+
+	if double {
+		lastRound := 2*rounds - 2
+		for r := 1; r <= lastRound; r++ {
+			// Rounds come in pairs of equal size: odd rounds pair up survivors,
+			// even rounds take in the losers of the winners bracket
+			for i := 0; i < size>>((r+1)/2+1); i++ {
+				m := &TournamentMatch{ID: matchID("L", r, i), Bracket: "losers", Round: r, Status: matchPending}
+				switch {
+				case r == lastRound:
+					m.WinnerTo, m.WinnerSlot = "GF", 1
+				case r%2 == 1:
+					m.WinnerTo, m.WinnerSlot = matchID("L", r+1, i), 0
+				default:
+					m.WinnerTo, m.WinnerSlot = matchID("L", r+1, i/2), i%2
+				}
+				t.Matches = append(t.Matches, m)
+			}
+		}
+		t.Matches = append(t.Matches, &TournamentMatch{ID: "GF", Bracket: "final", Round: 1, Status: matchPending})
+	}
+
+	order := seedOrder(size)
+	for i := 0; i < size/2; i++ {
+		for slot := 0; slot < 2; slot++ {
+			userID := ""
+			if seed := order[2*i+slot]; seed <= len(t.Players) {
+				userID = t.Players[seed-1].UserID
+			}
+			t.place(matchID("W", 1, i), slot, userID)
+		}
+	}
+}
+
+// place puts a player, or a bye if userID is empty, into a slot of a match. Once
+// both slots are decided the match is ready, or resolved right away for a bye.
+func (t *Tournament) place(id string, slot int, userID string) {
+	m := t.match(id)
+	m.Players[slot] = userID
+	m.Filled[slot] = true
+	if !m.Filled[0] || !m.Filled[1] {
+		return
+	}
+
+	switch {
+	case m.Players[0] != "" && m.Players[1] != "":
+		m.Status = matchReady
+		m.ReadySince = time.Now().Unix()
+		t.readied = append(t.readied, m.ID)
+	case m.Players[0] != "":
+		t.decide(m, m.Players[0], "bye")
+	default:
+		t.decide(m, m.Players[1], "bye") // Possibly nobody, the bye moves on
+	}
+}
+
+// decide records the winner of a match and moves both players on through the
+// bracket; the winner of the last match wins the tournament
+func (t *Tournament) decide(m *TournamentMatch, winnerID, result string) {
+	m.Status = matchDone
+	m.WinnerID = winnerID
+	m.Result = result
+	for _, id := range m.Players {
+		if id != "" && id != winnerID {
+			m.LoserID = id
+		}
+	}
+
+	if m.LoserTo != "" {
+		t.place(m.LoserTo, m.LoserSlot, m.LoserID)
+	}
+	if m.ID == "GF" && result == "normal" && winnerID == m.Players[1] {
+		// The winners bracket finalist suffered their first loss
+		t.Matches = append(t.Matches, &TournamentMatch{ID: "GF2", Bracket: "final", Round: 2, Status: matchPending})
+		t.place("GF2", 0, m.Players[0])
+		t.place("GF2", 1, winnerID)
+		return
+	}
+	if m.WinnerTo != "" {
+		t.place(m.WinnerTo, m.WinnerSlot, winnerID)
+		return
+	}
+	t.Status = TournamentFinished
+	t.WinnerID = winnerID
+	t.EndedAt = time.Now().Unix()
+}
+
+// record encodes the tournament for DynamoDB at the next version
+func (t *Tournament) record() (db.CookieTournament, error) {
+	bracket, err := json.Marshal(t)
+	if err != nil {
+		return db.CookieTournament{}, err
+	}
+	return db.CookieTournament{
+		TournamentID: t.ID,
+		Name:         t.Name,
+		Status:       t.Status,
+		CreatedAt:    t.CreatedAt,
+		Version:      t.version + 1,
+		Bracket:      string(bracket),
+	}, nil
+}
+
+// decodeTournament restores a tournament from its DynamoDB record
+func decodeTournament(record db.CookieTournament) (*Tournament, error) {
+	var t Tournament
+	if err := json.Unmarshal([]byte(record.Bracket), &t); err != nil {
+		return nil, err
+	}
+	t.version = record.Version
+	return &t, nil
+}
+
+// saveTournament stores the tournament unless it changed since it was read
+func saveTournament(t *Tournament) (bool, error) {
+	record, err := t.record()
+	if err != nil {
+		return false, err
+	}
+	saved, err := db.SaveTournamentWithMock(record)
+	if saved {
+		t.version = record.Version
+	}
+	return saved, err
+}
+
+// CreateTournament opens the registration of a new tournament
+func CreateTournament(name, format, seedBy string, rules GameRules, createdBy string) (*Tournament, error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	t := &Tournament{
+		ID:        hex.EncodeToString(idBytes),
+		Name:      name,
+		Format:    format,
+		SeedBy:    seedBy,
+		Rules:     rules,
+		Status:    TournamentRegistration,
+		CreatedBy: createdBy,
+		Players:   []TournamentPlayer{},
+		CreatedAt: time.Now().Unix(),
+	}
+	if _, err := saveTournament(t); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Tournament %s created by %s: %s (%s elimination)", t.ID, createdBy, name, format)
+	return t, nil
+}
+
+// GetTournament loads a tournament by ID
+func GetTournament(tournamentID string) (*Tournament, error) {
+	record, err := db.GetTournamentWithMock(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, errTournamentNotFound
+	}
+	return decodeTournament(*record)
+}
+
+// ListTournaments returns all tournaments, newest first
+func ListTournaments() ([]*Tournament, error) {
+	records, err := db.ListTournamentsWithMock()
+	if err != nil {
+		return nil, err
+	}
+	tournaments := make([]*Tournament, 0, len(records))
+	for _, record := range records {
+		t, err := decodeTournament(record)
+		if err != nil {
+			log.Printf("Skipping unreadable tournament %s: %v", record.TournamentID, err)
+			continue
+		}
+		tournaments = append(tournaments, t)
+	}
+	return tournaments, nil
+}
+
+// updateTournament applies fn to the latest version of a tournament and stores
+// the result, starting over if another pod saved it in between
+func updateTournament(tournamentID string, fn func(t *Tournament) error) (*Tournament, error) {
+	for range tournamentUpdateRetries {
+		t, err := GetTournament(tournamentID)
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(t); err != nil {
+			return nil, err
+		}
+		saved, err := saveTournament(t)
+		if err != nil {
+			return nil, err
+		}
+		if saved {
+			return t, nil
+		}
+	}
+	return nil, errTournamentBusy
+}
+
+// seedValues looks up the seeding value of every player
+func seedValues(players []TournamentPlayer, seedBy string) map[string]int {
+	values := make(map[string]int, len(players))
+	for _, p := range players {
+		if seedBy == SeedByRating {
+			values[p.UserID] = lookupRating(p.UserID)
+			continue
+		}
+		if user, err := db.GetUserWithMock(p.UserID); err == nil && user != nil {
+			values[p.UserID] = user.Score
+		}
+	}
+	return values
+}
+
+// RunTournamentLoop regularly starts the ready matches of running tournaments and
+// settles no-shows. Every pod runs it; versioned updates make sure each match is
+// started and decided only once.
+func (gm *GameManager) RunTournamentLoop() {
+	ticker := time.NewTicker(tournamentCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		tournaments, err := ListTournaments()
+		if err != nil {
+			log.Printf("Failed to list tournaments: %v", err)
+			continue
+		}
+		for _, t := range tournaments {
+			if t.Status == TournamentRunning {
+				gm.advanceTournament(t)
+			}
+		}
+	}
+}
+
+// checkTournament reloads a tournament and advances it
+func (gm *GameManager) checkTournament(tournamentID string) {
+	t, err := GetTournament(tournamentID)
+	if err != nil {
+		log.Printf("Failed to load tournament %s: %v", tournamentID, err)
+		return
+	}
+	if t.Status == TournamentRunning {
+		gm.advanceTournament(t)
+	}
+}
+
+// advanceTournament starts the ready matches of a running tournament and replays
+// matches whose game was lost
+func (gm *GameManager) advanceTournament(t *Tournament) {
+	for _, m := range t.Matches {
+		switch m.Status {
+		case matchReady:
+			gm.playTournamentMatch(t, m)
+		case matchPlaying:
+			gm.recoverTournamentMatch(t, m)
+		}
+	}
+}
+
+// playTournamentMatch starts the game of a ready match through the regular match
+// flow once both players are available. A player who is offline or playing
+// elsewhere for longer than the no-show timeout forfeits.
+func (gm *GameManager) playTournamentMatch(t *Tournament, m *TournamentMatch) {
+	userIDs := m.Players[:]
+	presence := gm.presenceOf(userIDs)
+	var absent []string
+	for _, id := range userIDs {
+		switch presence[id] {
+		case PresenceOffline, PresenceInGame:
+			absent = append(absent, id)
+		}
+	}
+	if len(absent) > 0 {
+		if time.Since(time.Unix(m.ReadySince, 0)) >= tournamentNoShowTimeout {
+			gm.forfeitTournamentNoShow(t.ID, m.ID, absent)
+		}
+		return
+	}
+
+	roomID := newRoomID(ModeDuel, userIDs)
+	_, err := updateTournament(t.ID, func(t *Tournament) error {
+		current := t.match(m.ID)
+		if t.Status != TournamentRunning || current == nil || current.Status != matchReady {
+			return errTournamentUnchanged // Started by another pod
+		}
+		current.Status = matchPlaying
+		current.RoomID = roomID
+		current.StartedAt = time.Now().Unix()
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, errTournamentUnchanged) {
+			log.Printf("Failed to start match %s of tournament %s: %v", m.ID, t.ID, err)
+		}
+		return
+	}
+
+	entries := make([]QueueEntry, len(userIDs))
+	for i, id := range userIDs {
+		if presence[id] == PresenceQueued {
+			RemoveFromQueue(id)
+		}
+		p := t.player(id)
+		entries[i] = QueueEntry{UserID: id, Name: p.Name, Picture: p.Picture, Mode: ModeDuel, JoinedAt: time.Now().Unix()}
+	}
+	linkMatch := func(state *DistributedGameState) {
+		state.TournamentID = t.ID
+		state.TournamentMatch = m.ID
+	}
+	if err := CreateDistributedGame(roomID, ModeDuel, entries, t.Rules, linkMatch); err != nil {
+		log.Printf("Failed to create match %s of tournament %s: %v", m.ID, t.ID, err)
+		gm.replayTournamentMatch(t.ID, m.ID, roomID)
+		return
+	}
+
+	log.Printf("Tournament %s: match %s starting in room %s", t.ID, m.ID, roomID)
+	if err := PublishMatchNotification(newMatchNotification(roomID, ModeDuel, userIDs)); err != nil {
+		log.Printf("Failed to publish match notification for tournament %s: %v", t.ID, err)
+	}
+}
+
+// recoverTournamentMatch replays a playing match whose game vanished without a
+// result, e.g. because its pod died between claiming the match and creating the game
+func (gm *GameManager) recoverTournamentMatch(t *Tournament, m *TournamentMatch) {
+	if time.Since(time.Unix(m.StartedAt, 0)) < tournamentLostGameGrace {
+		return
+	}
+	state, err := GetGameState(m.RoomID)
+	if state != nil || (err != nil && !errors.Is(err, redis.Nil)) {
+		return
+	}
+	log.Printf("Tournament %s: game %s of match %s was lost, replaying", t.ID, m.RoomID, m.ID)
+	gm.replayTournamentMatch(t.ID, m.ID, m.RoomID)
+}
+
+// replayTournamentMatch puts a match that was started in roomID back to ready
+func (gm *GameManager) replayTournamentMatch(tournamentID, id, roomID string) {
+	t, err := updateTournament(tournamentID, func(t *Tournament) error {
+		m := t.match(id)
+		if m == nil || m.Status != matchPlaying || m.RoomID != roomID {
+			return errTournamentUnchanged
+		}
+		m.Status = matchReady
+		m.ReadySince = time.Now().Unix()
+		t.readied = append(t.readied, m.ID)
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, errTournamentUnchanged) {
+			log.Printf("Failed to reset match %s of tournament %s: %v", id, tournamentID, err)
+		}
+		return
+	}
+	gm.tournamentUpdated(t)
+}
+
+// finishTournamentMatch records the result of a tournament game and advances the
+// bracket. A knockout match cannot end level, so a draw is replayed. Runs on the
+// pod that ended the game; a result for a game that is not the match's current
+// one is ignored.
+func (gm *GameManager) finishTournamentMatch(tournamentID, id, roomID, winnerID, result string) {
+	t, err := updateTournament(tournamentID, func(t *Tournament) error {
+		m := t.match(id)
+		if m == nil || m.Status != matchPlaying || m.RoomID != roomID {
+			return errTournamentUnchanged // Already counted
+		}
+		m.Games = append(m.Games, roomID)
+		if !slices.Contains(m.Players[:], winnerID) {
+			m.Status = matchReady
+			m.ReadySince = time.Now().Unix()
+			t.readied = append(t.readied, m.ID)
+			return nil
+		}
+		t.decide(m, winnerID, result)
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, errTournamentUnchanged) {
+			log.Printf("Failed to record match %s of tournament %s: %v", id, tournamentID, err)
+		}
+		return
+	}
+
+	if m := t.match(id); m.Status == matchDone {
+		log.Printf("Tournament %s: match %s won by %s (%s)", tournamentID, id, winnerID, result)
+	} else {
+		log.Printf("Tournament %s: match %s ended in a draw and is replayed", tournamentID, id)
+	}
+	gm.tournamentUpdated(t)
+}
+
+// forfeitTournamentNoShow decides a ready match against the players who did not
+// show up in time. If neither did, the better seed advances.
+func (gm *GameManager) forfeitTournamentNoShow(tournamentID, id string, absent []string) {
+	t, err := updateTournament(tournamentID, func(t *Tournament) error {
+		m := t.match(id)
+		if m == nil || m.Status != matchReady {
+			return errTournamentUnchanged
+		}
+		winnerID := m.Players[0]
+		switch {
+		case len(absent) == 2:
+			if t.player(m.Players[1]).Seed < t.player(m.Players[0]).Seed {
+				winnerID = m.Players[1]
+			}
+		case absent[0] == m.Players[0]:
+			winnerID = m.Players[1]
+		}
+		t.decide(m, winnerID, "no_show")
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, errTournamentUnchanged) {
+			log.Printf("Failed to forfeit match %s of tournament %s: %v", id, tournamentID, err)
+		}
+		return
+	}
+
+	log.Printf("Tournament %s: %v did not show up for match %s", tournamentID, absent, id)
+	gm.tournamentUpdated(t)
+}
+
+// tournamentUpdated tells the players of newly ready matches about their next
+// match, which is started after the intermission, and announces the champion
+func (gm *GameManager) tournamentUpdated(t *Tournament) {
+	for _, id := range t.readied {
+		m := t.match(id)
+		for slot, userID := range m.Players {
+			gm.sendToUser(userID, GameMessage{
+				Type: MsgTypeTournamentMatch,
+				Payload: map[string]interface{}{
+					"tournamentId":  t.ID,
+					"name":          t.Name,
+					"matchId":       m.ID,
+					"bracket":       m.Bracket,
+					"round":         m.Round,
+					"opponent":      t.player(m.Players[1-slot]),
+					"startsIn":      tournamentIntermission.Seconds(),
+					"noShowTimeout": tournamentNoShowTimeout.Seconds(),
+				},
+			})
+		}
+	}
+	if len(t.readied) > 0 {
+		time.AfterFunc(tournamentIntermission, func() { gm.checkTournament(t.ID) })
+	}
+
+	if t.Status == TournamentFinished {
+		log.Printf("Tournament %s over: winner %s", t.ID, t.WinnerID)
+		payload := map[string]interface{}{"tournamentId": t.ID, "name": t.Name, "winner": t.player(t.WinnerID)}
+		for _, p := range t.Players {
+			gm.sendToUser(p.UserID, GameMessage{Type: MsgTypeTournamentOver, Payload: payload})
+		}
+	}
+}
+
+// handleTournaments lists all tournaments (GET) or creates one organized by the
+// caller (POST {name, format, seedBy, preset})
+func handleTournaments(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		tournaments, err := ListTournaments()
+		if err != nil {
+			log.Printf("[API] Error listing tournaments: %v", err)
+			http.Error(w, "Failed to fetch tournaments", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(tournaments)
+
+	case http.MethodPost:
+		var req struct {
+			Name   string `json:"name"`
+			Format string `json:"format"`
+			SeedBy string `json:"seedBy"`
+			Preset string `json:"preset"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || len(req.Name) > 64 {
+			http.Error(w, "name must be 1 to 64 characters", http.StatusBadRequest)
+			return
+		}
+		if req.Format == "" {
+			req.Format = FormatSingleElim
+		}
+		if req.Format != FormatSingleElim && req.Format != FormatDoubleElim {
+			http.Error(w, "format must be single or double", http.StatusBadRequest)
+			return
+		}
+		if req.SeedBy == "" {
+			req.SeedBy = SeedByRating
+		}
+		if req.SeedBy != SeedByScore && req.SeedBy != SeedByRating {
+			http.Error(w, "seedBy must be score or rating", http.StatusBadRequest)
+			return
+		}
+		rules := matchmakingRules()
+		if req.Preset != "" {
+			var ok bool
+			if rules, ok = rulesForPreset(req.Preset); !ok {
+				http.Error(w, "Unknown game rules preset", http.StatusBadRequest)
+				return
+			}
+		}
+
+		t, err := CreateTournament(req.Name, req.Format, req.SeedBy, rules, requestClaims(r).UserID)
+		if err != nil {
+			log.Printf("[API] Error creating tournament: %v", err)
+			http.Error(w, "Failed to create tournament", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(t)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTournament returns the bracket state of a tournament
+func handleTournament(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	tournamentID := r.PathValue("tournamentId")
+	t, err := GetTournament(tournamentID)
+	if errors.Is(err, errTournamentNotFound) {
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[API] Error fetching tournament %s: %v", tournamentID, err)
+		http.Error(w, "Failed to fetch tournament", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(t)
+}
+
+// handleTournamentRegistration registers the caller for a tournament (POST) or
+// withdraws them (DELETE) while the registration is open
+func handleTournamentRegistration(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	claims := requestClaims(r)
+	tournamentID := r.PathValue("tournamentId")
+	t, err := updateTournament(tournamentID, func(t *Tournament) error {
+		if t.Status != TournamentRegistration {
+			return errTournamentClosed
+		}
+		registered := t.player(claims.UserID) != nil
+		switch {
+		case r.Method == http.MethodDelete && registered:
+			t.Players = slices.DeleteFunc(t.Players, func(p TournamentPlayer) bool { return p.UserID == claims.UserID })
+		case r.Method == http.MethodPost && !registered:
+			if len(t.Players) >= maxTournamentPlayers {
+				return errTournamentFull
+			}
+			t.Players = append(t.Players, TournamentPlayer{UserID: claims.UserID, Name: claims.Name, Picture: claims.Picture})
+		default:
+			return errTournamentUnchanged
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errTournamentUnchanged):
+		t, err = GetTournament(tournamentID)
+	case errors.Is(err, errTournamentClosed):
+		http.Error(w, "Registration is closed", http.StatusConflict)
+		return
+	case errors.Is(err, errTournamentFull):
+		http.Error(w, "Tournament is full", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, errTournamentNotFound) {
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[API] Error updating registration for tournament %s: %v", tournamentID, err)
+		http.Error(w, "Failed to update registration", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(t)
+}
+
+// handleTournamentStart closes the registration, seeds the players and starts the
+// first round. Only the organizer may start a tournament.
+func (gm *GameManager) handleTournamentStart(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if !IsRedisAvailable() {
+		http.Error(w, "Tournaments are not available right now", http.StatusServiceUnavailable)
+		return
+	}
+
+	tournamentID := r.PathValue("tournamentId")
+	current, err := GetTournament(tournamentID)
+	if errors.Is(err, errTournamentNotFound) {
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[API] Error fetching tournament %s: %v", tournamentID, err)
+		http.Error(w, "Failed to start tournament", http.StatusInternalServerError)
+		return
+	}
+	if current.CreatedBy != requestClaims(r).UserID {
+		http.Error(w, "Only the organizer can start the tournament", http.StatusForbidden)
+		return
+	}
+
+	values := seedValues(current.Players, current.SeedBy)
+	t, err := updateTournament(tournamentID, func(t *Tournament) error {
+		if t.Status != TournamentRegistration || len(t.Players) < minTournamentPlayers {
+			return errTournamentNotReady
+		}
+		t.Status = TournamentRunning
+		t.StartedAt = time.Now().Unix()
+		t.seed(values)
+		t.buildBracket()
+		return nil
+	})
+	if errors.Is(err, errTournamentNotReady) {
+		http.Error(w, fmt.Sprintf("Tournament needs at least %d registered players and must not be started yet", minTournamentPlayers), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("[API] Error starting tournament %s: %v", tournamentID, err)
+		http.Error(w, "Failed to start tournament", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Tournament %s started with %d players", t.ID, len(t.Players))
+	gm.tournamentUpdated(t)
+	json.NewEncoder(w).Encode(t)
+}
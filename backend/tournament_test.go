@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// newTestTournament returns a seeded tournament of n players with its bracket laid out
+func newTestTournament(format string, n int) *Tournament {
+	t := &Tournament{ID: "t-1", Format: format, Status: TournamentRunning}
+	for i := 1; i <= n; i++ {
+		t.Players = append(t.Players, TournamentPlayer{UserID: fmt.Sprintf("user-%d", i), Seed: i})
+	}
+	t.buildBracket()
+	return t
+}
+
+func TestTournamentBracket(t *testing.T) {
+	tests := []struct {
+		format  string
+		players int
+		byes    int // Players with a bye in winners round 1
+		winner  int // Slot that wins every match, -1 to alternate
+		final   string
+	}{
+		{FormatSingleElim, 2, 0, -1, "W1-1"},
+		{FormatSingleElim, 3, 1, -1, "W2-1"},
+		{FormatSingleElim, 5, 3, -1, "W3-1"},
+		{FormatSingleElim, 8, 0, -1, "W3-1"},
+		{FormatSingleElim, 8, 0, 1, "W3-1"},
+		{FormatDoubleElim, 2, 0, -1, "GF2"},
+		{FormatDoubleElim, 3, 1, -1, "GF2"},
+		{FormatDoubleElim, 5, 3, -1, "GF2"},
+		{FormatDoubleElim, 8, 0, -1, "GF2"},
+		{FormatDoubleElim, 2, 0, 0, "GF"},
+		{FormatDoubleElim, 5, 3, 0, "GF"},
+		{FormatDoubleElim, 8, 0, 0, "GF"},
+		{FormatDoubleElim, 8, 0, 1, "GF2"},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s/%d/%d", tc.format, tc.players, tc.winner), func(t *testing.T) {
+			tour := newTestTournament(tc.format, tc.players)
+
+			// Every route leads to an existing match, and no slot is fed twice
+			fed := make(map[string]string)
+			route := func(from, to string, slot int) {
+				if tour.match(to) == nil {
+					t.Fatalf("%s routes to unknown match %s", from, to)
+				}
+				if slot != 0 && slot != 1 {
+					t.Fatalf("%s routes to invalid slot %d of %s", from, slot, to)
+				}
+				key := fmt.Sprintf("%s/%d", to, slot)
+				if other, ok := fed[key]; ok {
+					t.Fatalf("%s and %s both feed %s", other, from, key)
+				}
+				fed[key] = from
+			}
+			for _, m := range tour.Matches {
+				if m.WinnerTo != "" {
+					route(m.ID, m.WinnerTo, m.WinnerSlot)
+				} else if m.ID != tc.final && m.Bracket != "final" {
+					t.Fatalf("Only the final may have no next match, %s has none", m.ID)
+				}
+				if m.LoserTo != "" {
+					if tc.format == FormatSingleElim {
+						t.Fatalf("Single elimination match %s drops its loser to %s", m.ID, m.LoserTo)
+					}
+					route(m.ID, m.LoserTo, m.LoserSlot)
+				}
+			}
+
+			// Byes are resolved right away and move their player on
+			byes := 0
+			for _, m := range tour.Matches {
+				if m.Result != "bye" || m.WinnerID == "" {
+					continue
+				}
+				if m.Bracket == "winners" && m.Round == 1 {
+					byes++
+				}
+				if next := tour.match(m.WinnerTo); next.Players[m.WinnerSlot] != m.WinnerID {
+					t.Errorf("Bye of %s in %s did not advance to %s", m.WinnerID, m.ID, m.WinnerTo)
+				}
+			}
+			if byes != tc.byes {
+				t.Errorf("Expected %d byes in the first round, got %d", tc.byes, byes)
+			}
+
+			// Play every ready match until the final
+			played := 0
+			for tour.Status != TournamentFinished {
+				var ready *TournamentMatch
+				for _, m := range tour.Matches {
+					if m.Status == matchReady {
+						ready = m
+						break
+					}
+				}
+				if ready == nil {
+					t.Fatalf("Bracket stuck before the final after %d matches", played)
+				}
+				slot := tc.winner
+				if slot < 0 {
+					slot = played % 2
+				}
+				tour.decide(ready, ready.Players[slot], "normal")
+				played++
+			}
+
+			final := tour.match(tc.final)
+			if final == nil {
+				t.Fatalf("Expected the tournament to end in %s, it was never played", tc.final)
+			}
+			if final.Status != matchDone || tour.WinnerID == "" || final.WinnerID != tour.WinnerID {
+				t.Fatalf("Expected the final %s to decide the tournament, got %+v (winner %q)", tc.final, final, tour.WinnerID)
+			}
+			for _, m := range tour.Matches {
+				if m.Status != matchDone {
+					t.Errorf("Match %s left %s", m.ID, m.Status)
+				}
+			}
+
+			// Everyone but the champion is knocked out: once in single elimination,
+			// twice in double elimination. A double elimination champion from the
+			// losers bracket won the reset match with a single loss.
+			losses := make(map[string]int)
+			for _, m := range tour.Matches {
+				if m.LoserID != "" {
+					losses[m.LoserID]++
+				}
+			}
+			for _, p := range tour.Players {
+				switch {
+				case tc.format == FormatSingleElim && p.UserID == tour.WinnerID:
+					if losses[p.UserID] != 0 {
+						t.Errorf("Expected champion %s to be unbeaten, lost %d times", p.UserID, losses[p.UserID])
+					}
+				case tc.format == FormatSingleElim:
+					if losses[p.UserID] != 1 {
+						t.Errorf("Expected %s to lose once, got %d", p.UserID, losses[p.UserID])
+					}
+				case p.UserID == tour.WinnerID:
+					if losses[p.UserID] > 1 {
+						t.Errorf("Expected champion %s to lose at most once, lost %d times", p.UserID, losses[p.UserID])
+					}
+				default:
+					if losses[p.UserID] != 2 {
+						t.Errorf("Expected %s to lose twice, got %d", p.UserID, losses[p.UserID])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSeedOrder(t *testing.T) {
+	got := fmt.Sprint(seedOrder(8))
+	if want := "[1 8 4 5 2 7 3 6]"; got != want {
+		t.Errorf("Expected seed order %s, got %s", want, got)
+	}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -38,6 +40,16 @@ type Client struct {
 	userID  string
 	name    string
 	picture string
+	limiter *clickLimiter
+	connID  string // Unique across pods, identifies the connection owning a game seat
+}
+
+// connCounter numbers the connections of this pod
+var connCounter atomic.Uint64
+
+// newConnID returns an ID for a new connection that no other pod hands out
+func newConnID() string {
+	return fmt.Sprintf("%s#%d", GetPodID(), connCounter.Add(1))
 }
 
 // readPump pumps messages from the websocket connection to the hub.
@@ -120,7 +132,7 @@ func serveWs(manager *GameManager, w http.ResponseWriter, r *http.Request) {
 	}
 
 	userID := claims.UserID
-	client := &Client{manager: manager, conn: conn, send: make(chan []byte, 256), userID: userID}
+	client := &Client{manager: manager, conn: conn, send: make(chan []byte, 256), userID: userID, limiter: newClickLimiter(), connID: newConnID()}
 
 	client.name = claims.Name
 	client.picture = claims.Picture
@@ -1,7 +1,7 @@
 'use client';
 
 import { useState, useEffect } from 'react';
-import { getApiUrl } from '@/lib/auth';
+import { getApiUrl, authHeaders } from '@/lib/auth';
 
 interface GameRecord {
   id: string;
@@ -25,39 +25,44 @@ export default function GameHistory({ userId }: GameHistoryProps) {
   const [games, setGames] = useState<GameRecord[]>([]);
   const [totalCount, setTotalCount] = useState<number>(0);
   const [isLoading, setIsLoading] = useState(true);
+  const [nextCursor, setNextCursor] = useState<string>('');
 
-  useEffect(() => {
-    async function fetchHistory() {
-      if (!userId) return;
-      try {
-        const res = await fetch(`${getApiUrl()}/api/history?userId=${userId}`);
-        if (res.ok) {
-          const data = await res.json();
-          // API now returns { games: [...], totalCount: number }
-          const gamesData = data.games || data;
-          const mappedData = gamesData.map((g: any) => ({
-            id: g.gameId,
-            score: g.score,
-            opponentScore: g.opponentScore,
-            won: g.won,
-            winnerId: g.winnerId,
-            opponentId: g.opponent,
-            playerName: g.playerName,
-            playerPicture: g.playerPicture,
-            opponentName: g.opponentName,
-            opponentPicture: g.opponentPicture,
-            timestamp: g.timestamp,
-          }));
-          setGames(mappedData);
-          setTotalCount(data.totalCount || mappedData.length);
-        }
-      } catch (error) {
-        console.error("Failed to fetch history", error);
-      } finally {
-        setIsLoading(false);
+  // Loads the caller's history page by page; an empty cursor loads the newest games
+  async function fetchHistory(cursor: string) {
+    try {
+      const query = cursor ? `?cursor=${encodeURIComponent(cursor)}` : '';
+      const res = await fetch(`${getApiUrl()}/api/history${query}`, { headers: authHeaders() });
+      if (res.ok) {
+        const data = await res.json();
+        // API returns { games: [...], totalCount: number, nextCursor: string }
+        const gamesData = data.games || [];
+        const mappedData = gamesData.map((g: any) => ({
+          id: g.gameId,
+          score: g.score,
+          opponentScore: g.opponentScore,
+          won: g.won,
+          winnerId: g.winnerId,
+          opponentId: g.opponent,
+          playerName: g.playerName,
+          playerPicture: g.playerPicture,
+          opponentName: g.opponentName,
+          opponentPicture: g.opponentPicture,
+          timestamp: g.timestamp,
+        }));
+        setGames((prev) => (cursor ? [...prev, ...mappedData] : mappedData));
+        setTotalCount(data.totalCount || mappedData.length);
+        setNextCursor(data.nextCursor || '');
       }
+    } catch (error) {
+      console.error("Failed to fetch history", error);
+    } finally {
+      setIsLoading(false);
     }
-    fetchHistory();
+  }
+
+  useEffect(() => {
+    if (!userId) return;
+    fetchHistory('');
   }, [userId]);
 
   return (
@@ -139,6 +144,14 @@ export default function GameHistory({ userId }: GameHistoryProps) {
               </div>
             </div>
           ))}
+          {nextCursor && (
+            <button
+              onClick={() => fetchHistory(nextCursor)}
+              className="w-full py-2 rounded-[16px] text-sm font-bold bg-[#FFF4E6] border-2 border-[#FFD93D] hover:bg-[#FFEB99] text-gray-800"
+            >
+              Load more
+            </button>
+          )}
         </div>
       )}
 
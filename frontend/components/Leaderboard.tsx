@@ -1,7 +1,7 @@
 'use client';
 
 import { useState, useEffect } from 'react';
-import { getApiUrl } from '@/lib/auth';
+import { getApiUrl, authHeaders } from '@/lib/auth';
 
 interface LeaderboardEntry {
   rank: number;
@@ -13,18 +13,21 @@ interface LeaderboardEntry {
 export default function Leaderboard() {
   const [entries, setEntries] = useState<LeaderboardEntry[]>([]);
   const [isLoading, setIsLoading] = useState(true);
+  const [seasonal, setSeasonal] = useState(false);
 
   useEffect(() => {
     async function fetchLeaderboard() {
+      setIsLoading(true);
       try {
-        const res = await fetch(`${getApiUrl()}/api/leaderboard`);
+        const query = seasonal ? '?season=current' : '';
+        const res = await fetch(`${getApiUrl()}/api/leaderboard${query}`, { headers: authHeaders() });
         if (res.ok) {
           const data = await res.json();
-          // Map API response to Component format (if needed, but structure matches closely)
-          // API returns CookieUser: { userId, email, name, picture, score, cookies }
+          // API returns a page: { entries: [{ name, picture, score, rating, rank }], total, nextCursor }
           // Component expects: { rank, username, score, avatar }
-          const mappedData = data.map((u: any, index: number) => ({
-            rank: index + 1,
+          // Season entries share their rank when scores are tied
+          const mappedData = data.entries.map((u: any, index: number) => ({
+            rank: u.rank || index + 1,
             username: u.name,
             score: u.score,
             avatar: u.picture // Assuming picture is a URL or emoji
@@ -38,7 +41,7 @@ export default function Leaderboard() {
       }
     }
     fetchLeaderboard();
-  }, []);
+  }, [seasonal]);
 
   const getMedalEmoji = (rank: number) => {
     switch (rank) {
@@ -51,10 +54,16 @@ export default function Leaderboard() {
 
   return (
     <div>
-      <div className="flex items-center mb-6">
+      <div className="flex items-center justify-between mb-6">
         <h2 className="text-2xl font-extrabold text-gray-800 flex items-center">
-          🏆 Global Leaderboard
+          🏆 {seasonal ? 'Season Leaderboard' : 'Global Leaderboard'}
         </h2>
+        <button
+          onClick={() => setSeasonal(!seasonal)}
+          className="px-3 py-1 rounded-full text-sm font-bold bg-[#FFF4E6] border-2 border-[#FFD93D] hover:bg-[#FFEB99] text-gray-800"
+        >
+          {seasonal ? 'All-time' : 'This season'}
+        </button>
       </div>
 
       {isLoading ? (
@@ -65,7 +74,7 @@ export default function Leaderboard() {
         <div className="space-y-2 h-[600px] overflow-y-auto pr-2 custom-scrollbar">
           {entries.map((entry) => (
             <div
-              key={entry.rank}
+              key={`${entry.rank}-${entry.username}`}
               className={`flex items-center justify-between p-4 rounded-[16px] transition-all ${entry.rank <= 3
                 ? 'bg-gradient-to-r from-[#FFD93D] to-[#FFEB99] border-2 border-[#FF6B4A] shadow-[0px_4px_8px_rgba(255,107,74,0.2)]'
                 : 'bg-[#FFF4E6] hover:bg-[#FFEB99] border-2 border-[#FFD93D]'
@@ -115,7 +124,7 @@ export default function Leaderboard() {
                     {entry.score.toLocaleString()}
                   </div>
                   <div className="text-xs text-gray-600 font-bold">
-                    Total Cookies
+                    {seasonal ? 'Season Cookies' : 'Total Cookies'}
                   </div>
                 </div>
               </div>
@@ -12,6 +12,12 @@ export function getApiUrl(): string {
   return process.env.NEXT_PUBLIC_API_URL || '';
 }
 
+// Authorization header for /api/* requests of the signed-in user
+export function authHeaders(): HeadersInit {
+  const token = authService.getCurrentUser()?.token;
+  return token ? { 'Authorization': `Bearer ${token}` } : {};
+}
+
 export const authService = {
   getCurrentUser(): UserSession | null {
     if (typeof window === 'undefined') return null;
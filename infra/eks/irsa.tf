@@ -38,6 +38,7 @@ resource "aws_iam_policy" "backend_dynamodb" {
         "dynamodb:GetItem",
         "dynamodb:PutItem",
         "dynamodb:UpdateItem",
+        "dynamodb:DeleteItem",
         "dynamodb:Query",
         "dynamodb:Scan",
         "dynamodb:BatchGetItem"
@@ -45,7 +46,16 @@ resource "aws_iam_policy" "backend_dynamodb" {
       Resource = [
         "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_users}",
         "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_games}",
-        "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_games}/index/*"
+        "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_games}/index/*",
+        "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_replays}",
+        "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_seasons}",
+        "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_season_stats}",
+        "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_player_stats}",
+        "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_head_to_head}",
+        "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_achievements}",
+        "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_friends}",
+        "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_series}",
+        "arn:aws:dynamodb:${var.aws_region}:${local.account_id}:table/${var.dynamodb_table_tournaments}"
       ]
     }]
   })
@@ -58,6 +58,60 @@ variable "dynamodb_table_games" {
   default     = "CookieGames"
 }
 
+variable "dynamodb_table_replays" {
+  description = "DynamoDB table name for game replays"
+  type        = string
+  default     = "CookieReplays"
+}
+
+variable "dynamodb_table_seasons" {
+  description = "DynamoDB table name for ranked seasons"
+  type        = string
+  default     = "CookieSeasons"
+}
+
+variable "dynamodb_table_season_stats" {
+  description = "DynamoDB table name for per-season player stats"
+  type        = string
+  default     = "CookieSeasonStats"
+}
+
+variable "dynamodb_table_player_stats" {
+  description = "DynamoDB table name for lifetime player stats"
+  type        = string
+  default     = "CookiePlayerStats"
+}
+
+variable "dynamodb_table_head_to_head" {
+  description = "DynamoDB table name for head-to-head records"
+  type        = string
+  default     = "CookieHeadToHead"
+}
+
+variable "dynamodb_table_achievements" {
+  description = "DynamoDB table name for unlocked achievements"
+  type        = string
+  default     = "CookieAchievements"
+}
+
+variable "dynamodb_table_friends" {
+  description = "DynamoDB table name for friendships and friend requests"
+  type        = string
+  default     = "CookieFriends"
+}
+
+variable "dynamodb_table_series" {
+  description = "DynamoDB table name for best-of-N series results"
+  type        = string
+  default     = "CookieSeries"
+}
+
+variable "dynamodb_table_tournaments" {
+  description = "DynamoDB table name for tournament brackets"
+  type        = string
+  default     = "CookieTournaments"
+}
+
 variable "valkey_node_type" {
   description = "ElastiCache Valkey node type"
   type        = string